@@ -0,0 +1,22 @@
+#!/bin/bash
+#
+# Generates a self-signed certificate for localhost that SQL Server presents
+# for TLS. Acceptance tests pin it via the provider's `tls.certificate`.
+
+set -e
+
+CERT_DIR="$(dirname "$0")/certs"
+mkdir -p "${CERT_DIR}"
+
+if [ -f "${CERT_DIR}/mssql.pem" ] && [ -f "${CERT_DIR}/mssql.key" ]; then
+  exit 0
+fi
+
+openssl req -x509 -nodes -newkey rsa:2048 -days 365 \
+  -subj "/CN=localhost" \
+  -addext "subjectAltName=DNS:localhost,IP:127.0.0.1" \
+  -keyout "${CERT_DIR}/mssql.key" \
+  -out "${CERT_DIR}/mssql.pem"
+
+# The server runs as the unprivileged mssql user inside the container.
+chmod 644 "${CERT_DIR}/mssql.key" "${CERT_DIR}/mssql.pem"
@@ -1,6 +1,7 @@
 #!/bin/bash
 
 source .env
+./gen_cert.sh
 docker compose up -d --wait
 CONTAINER_ID=$(docker compose ps -q)
 docker exec ${CONTAINER_ID} \
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"strings"
+	"sync"
+)
+
+// Locks serializes DDL that SQL Server does not allow to run concurrently,
+// such as ALTER DATABASE or ALTER ROLE on the same database. Locks are keyed
+// by server and database, so operations on unrelated databases proceed in
+// parallel. Server-scoped DDL like CREATE DATABASE, CREATE LOGIN or ALTER
+// SERVER ROLE takes the server lock.
+//
+// To avoid deadlocks, an operation that needs both takes the database lock
+// first. A nil *Locks does not lock.
+type Locks struct {
+	mu    sync.Mutex
+	locks map[string]*lockEntry
+}
+
+type lockEntry struct {
+	mu sync.Mutex
+	// refs counts holders and waiters, so that unused entries are dropped.
+	refs int
+}
+
+func NewLocks() *Locks {
+	return &Locks{locks: map[string]*lockEntry{}}
+}
+
+// Database locks a database on a server and returns the func that unlocks it.
+// Database names are compared case-insensitively.
+func (l *Locks) Database(serverID string, database string) func() {
+	return l.lock(serverID + "/" + strings.ToLower(database))
+}
+
+// Server locks a server for server-scoped DDL and returns the func that
+// unlocks it.
+func (l *Locks) Server(serverID string) func() {
+	return l.lock(serverID)
+}
+
+func (l *Locks) lock(key string) func() {
+	if l == nil {
+		return func() {}
+	}
+
+	l.mu.Lock()
+	e := l.locks[key]
+	if e == nil {
+		e = &lockEntry{}
+		l.locks[key] = e
+	}
+	e.refs++
+	l.mu.Unlock()
+
+	e.mu.Lock()
+	return func() {
+		e.mu.Unlock()
+
+		l.mu.Lock()
+		e.refs--
+		if e.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+// acquired reports whether lock returns within a short time.
+func acquired(lock func() func()) (func(), bool) {
+	done := make(chan func(), 1)
+	go func() { done <- lock() }()
+	select {
+	case unlock := <-done:
+		return unlock, true
+	case <-time.After(50 * time.Millisecond):
+		go func() { (<-done)() }()
+		return nil, false
+	}
+}
+
+func Test_Locks_Database(t *testing.T) {
+	l := NewLocks()
+	unlock := l.Database("sql:1433", "app")
+
+	if _, ok := acquired(func() func() { return l.Database("sql:1433", "APP") }); ok {
+		t.Fatalf("locked the same database twice")
+	}
+	other, ok := acquired(func() func() { return l.Database("sql:1433", "reporting") })
+	if !ok {
+		t.Fatalf("an unrelated database was blocked")
+	}
+	other()
+	server, ok := acquired(func() func() { return l.Server("sql:1433") })
+	if !ok {
+		t.Fatalf("the server lock was blocked by a database lock")
+	}
+	server()
+
+	unlock()
+	again, ok := acquired(func() func() { return l.Database("sql:1433", "app") })
+	if !ok {
+		t.Fatalf("the database stayed locked after unlock")
+	}
+	again()
+}
+
+func Test_Locks_DropsUnusedEntries(t *testing.T) {
+	l := NewLocks()
+	l.Server("sql:1433")()
+	l.Database("sql:1433", "app")()
+
+	if n := len(l.locks); n != 0 {
+		t.Fatalf("%d lock entries left, want 0", n)
+	}
+}
+
+func Test_Locks_Nil(t *testing.T) {
+	var l *Locks
+	l.Database("sql:1433", "app")()
+	l.Server("sql:1433")()
+}
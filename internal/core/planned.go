@@ -0,0 +1,97 @@
+package core
+
+import (
+	"strings"
+	"sync"
+)
+
+// Planned records the databases, logins and database principals that
+// resources plan to create or change, and the databases in which scripts
+// run. Terraform plans a resource after the resources it refers to, so plan
+// time checks that a referenced object exists can skip the objects recorded
+// here, which may not exist until apply. Names are compared
+// case-insensitively. A nil *Planned records nothing.
+type Planned struct {
+	mu      sync.Mutex
+	objects map[string]bool
+}
+
+func NewPlanned() *Planned {
+	return &Planned{objects: map[string]bool{}}
+}
+
+// AddDatabase records a planned database.
+func (p *Planned) AddDatabase(name string) {
+	p.add("database", name)
+}
+
+// AddLogin records a planned login.
+func (p *Planned) AddLogin(name string) {
+	p.add("login", name)
+}
+
+// AddPrincipal records a planned user or role in a database.
+func (p *Planned) AddPrincipal(database string, name string) {
+	p.add("principal", database, name)
+}
+
+// AddScript records a planned script in a database. Scripts can create any
+// object: missing server principals are not reported while any script is
+// planned, and missing principals and securables of a database while a
+// script is planned in it.
+func (p *Planned) AddScript(database string) {
+	p.add("script", database)
+	p.add("script", "*")
+}
+
+// Database reports whether a database is planned.
+func (p *Planned) Database(name string) bool {
+	return p.has("database", name)
+}
+
+// Login reports whether a login is planned.
+func (p *Planned) Login(name string) bool {
+	return p.has("login", name)
+}
+
+// Principal reports whether a user or role is planned in a database.
+func (p *Planned) Principal(database string, name string) bool {
+	return p.has("principal", database, name)
+}
+
+// Script reports whether a script is planned in a database.
+func (p *Planned) Script(database string) bool {
+	return p.has("script", database)
+}
+
+// AnyScript reports whether a script is planned in any database.
+func (p *Planned) AnyScript() bool {
+	return p.has("script", "*")
+}
+
+func plannedKey(kind string, names []string) string {
+	return kind + "\x00" + strings.ToLower(strings.Join(names, "\x00"))
+}
+
+func (p *Planned) add(kind string, names ...string) {
+	if p == nil {
+		return
+	}
+	for _, name := range names {
+		if name == "" {
+			return
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.objects[plannedKey(kind, names)] = true
+}
+
+func (p *Planned) has(kind string, names ...string) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.objects[plannedKey(kind, names)]
+}
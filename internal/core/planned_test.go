@@ -0,0 +1,25 @@
+package core
+
+import "testing"
+
+func Test_Planned(t *testing.T) {
+	p := NewPlanned()
+	p.AddDatabase("App")
+	p.AddLogin("svc")
+	p.AddPrincipal("app", "Readers")
+	p.AddScript("app")
+	p.AddPrincipal("", "ignored")
+
+	if !p.Database("app") || !p.Login("SVC") || !p.Principal("APP", "readers") || !p.Script("App") || !p.AnyScript() {
+		t.Fatalf("planned objects not found")
+	}
+	if p.Database("svc") || p.Principal("other", "readers") || p.Script("other") || p.Principal("", "ignored") {
+		t.Fatalf("unplanned objects found")
+	}
+
+	var nilPlanned *Planned
+	nilPlanned.AddLogin("svc")
+	if nilPlanned.Login("svc") {
+		t.Fatalf("nil Planned recorded a login")
+	}
+}
@@ -1,9 +1,87 @@
 package core
 
-import "github.com/vsabella/terraform-provider-mssql/internal/mssql"
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
+)
 
 type ProviderData struct {
-	Client   mssql.SqlClient
+	Client mssql.SqlClient
+
+	// ServerID identifies the server in resource IDs. It is empty only when
+	// the provider is Unconfigured and its server_id, host or port is unknown.
 	ServerID string
 	Database string
+
+	// Server describes the connected server. It is read once when the
+	// provider is configured and is nil when it could not be determined;
+	// resources then skip their capability checks.
+	Server *mssql.ServerInfo
+
+	// Locks serializes DDL per database and per server. It is shared by
+	// every provider instance in the process.
+	Locks *Locks
+
+	// Planned records the objects resources plan to create, so that checks
+	// of the objects a resource refers to skip them during plan.
+	Planned *Planned
+
+	// DryRun is set when the client records the statements that would change
+	// the server instead of executing them.
+	DryRun bool
+
+	// Unconfigured is set when the provider configuration depends on values
+	// that are unknown until apply, e.g. the host of a server created in the
+	// same run. Client then fails every call with mssql.ErrNotConfigured.
+	Unconfigured bool
+}
+
+// RequireServer reports whether the provider can reach the server, adding an
+// error diagnostic when it cannot. Use it in operations that must talk to the
+// server; reads should keep their prior state instead.
+func (p ProviderData) RequireServer(diags *diag.Diagnostics) bool {
+	if !p.Unconfigured {
+		return true
+	}
+	diags.AddError(
+		"Provider Not Yet Configured",
+		"The mssql provider configuration depends on values that are unknown until apply, so this operation cannot reach the server yet. "+
+			"Apply the resources the provider configuration depends on first, or use -target.",
+	)
+	return false
+}
+
+// LockDatabase locks a database on the provider's server for DDL and returns
+// the func that unlocks it. An empty database is the provider's database.
+func (p ProviderData) LockDatabase(database string) func() {
+	if database == "" {
+		database = p.Database
+	}
+	return p.Locks.Database(p.ServerID, database)
+}
+
+// LockServer locks the provider's server for server-scoped DDL and returns
+// the func that unlocks it.
+func (p ProviderData) LockServer() func() {
+	return p.Locks.Server(p.ServerID)
+}
+
+// ReportDryRun adds the statements a dry run recorded with ctx as a warning.
+// Defer it in resource operations that change the server, after labeling ctx
+// with mssql.WithResource.
+func (p ProviderData) ReportDryRun(ctx context.Context, diags *diag.Diagnostics) {
+	if !p.DryRun {
+		return
+	}
+	statements := mssql.RecordedStatements(ctx)
+	if len(statements) == 0 {
+		return
+	}
+	diags.AddWarning(
+		"Dry Run: SQL Not Executed",
+		"The provider is in dry run mode, so the following statements were recorded instead of executed:\n\n"+strings.Join(statements, "\n"),
+	)
 }
@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
+)
+
+// addUnsupported reports an attribute the connected server cannot apply.
+func addUnsupported(diags *diag.Diagnostics, attr path.Path, server mssql.ServerInfo, detail string) {
+	diags.AddAttributeError(
+		attr,
+		"Unsupported on This Edition",
+		fmt.Sprintf("%s The provider is connected to %s.", detail, server),
+	)
+}
+
+// validateDatabaseCapabilities checks configured database options against the
+// server version and edition. Null and unknown values are skipped.
+func validateDatabaseCapabilities(data MssqlDatabaseResourceModel, server mssql.ServerInfo, diags *diag.Diagnostics) {
+	if v := data.AcceleratedDatabaseRecovery; !v.IsNull() && !v.IsUnknown() {
+		switch {
+		case server.IsAzure() && !v.ValueBool():
+			addUnsupported(diags, path.Root("accelerated_database_recovery"), server,
+				"Accelerated Database Recovery is always enabled on Azure SQL and cannot be turned off.")
+		case !server.AtLeast(15):
+			addUnsupported(diags, path.Root("accelerated_database_recovery"), server,
+				"Accelerated Database Recovery requires SQL Server 2019 or later.")
+		}
+	}
+
+	if v := data.RecoveryModel; !v.IsNull() && !v.IsUnknown() && server.IsAzureSQLDatabase() &&
+		!strings.EqualFold(v.ValueString(), "FULL") {
+		addUnsupported(diags, path.Root("recovery_model"), server,
+			"Azure SQL Database always uses the FULL recovery model.")
+	}
+
+	if v := data.CompatibilityLevel; !v.IsNull() && !v.IsUnknown() {
+		if max := server.MaxCompatibilityLevel(); max > 0 && v.ValueInt64() > max {
+			addUnsupported(diags, path.Root("compatibility_level"), server,
+				fmt.Sprintf("Compatibility level %d is not available; the highest supported level is %d.", v.ValueInt64(), max))
+		}
+	}
+
+	if v := data.ScopedConfigurations; !v.IsNull() && !v.IsUnknown() && len(v.Elements()) > 0 && !server.AtLeast(13) {
+		addUnsupported(diags, path.Root("scoped_configuration"), server,
+			"Database scoped configurations require SQL Server 2016 or later.")
+	}
+}
+
+// validateLoginCapabilities checks configured login options against the
+// server edition.
+func validateLoginCapabilities(data MssqlLoginResourceModel, server mssql.ServerInfo, diags *diag.Diagnostics) {
+	if !server.IsAzureSQLDatabase() {
+		return
+	}
+	// CREATE LOGIN on Azure SQL Database only accepts PASSWORD and SID.
+	if v := data.DefaultDatabase; !v.IsNull() && !v.IsUnknown() {
+		addUnsupported(diags, path.Root("default_database"), server,
+			"Azure SQL Database logins do not support a default database.")
+	}
+	if v := data.DefaultLanguage; !v.IsNull() && !v.IsUnknown() {
+		addUnsupported(diags, path.Root("default_language"), server,
+			"Azure SQL Database logins do not support a default language.")
+	}
+}
+
+// validateUserCapabilities checks configured user options against the server
+// version and edition.
+func validateUserCapabilities(data MssqlUserResourceModel, server mssql.ServerInfo, diags *diag.Diagnostics) {
+	if v := data.External; !v.IsNull() && !v.IsUnknown() && v.ValueBool() && !server.AtLeast(16) {
+		addUnsupported(diags, path.Root("external"), server,
+			"Microsoft Entra ID users require Azure SQL or SQL Server 2022 or later.")
+	}
+}
@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
+)
+
+var (
+	testSQL2016  = mssql.ServerInfo{ProductMajorVersion: 13, EngineEdition: mssql.EngineEditionEnterprise, Edition: "Enterprise Edition (64-bit)"}
+	testSQL2022  = mssql.ServerInfo{ProductMajorVersion: 16, EngineEdition: mssql.EngineEditionEnterprise, Edition: "Enterprise Edition (64-bit)"}
+	testAzureSQL = mssql.ServerInfo{ProductMajorVersion: 12, EngineEdition: mssql.EngineEditionAzureSQLDatabase, Edition: "SQL Azure"}
+)
+
+func nullDatabaseModel() MssqlDatabaseResourceModel {
+	return MssqlDatabaseResourceModel{
+		Name:                        types.StringValue("app"),
+		CompatibilityLevel:          types.Int64Null(),
+		RecoveryModel:               types.StringNull(),
+		AcceleratedDatabaseRecovery: types.BoolNull(),
+		ScopedConfigurations:        types.SetNull(types.ObjectType{}),
+	}
+}
+
+func Test_validateDatabaseCapabilities(t *testing.T) {
+	tests := []struct {
+		name    string
+		server  mssql.ServerInfo
+		modify  func(*MssqlDatabaseResourceModel)
+		wantErr bool
+	}{
+		{name: "defaults", server: testSQL2016, modify: func(*MssqlDatabaseResourceModel) {}},
+		{
+			name:    "adr before 2019",
+			server:  testSQL2016,
+			modify:  func(d *MssqlDatabaseResourceModel) { d.AcceleratedDatabaseRecovery = types.BoolValue(true) },
+			wantErr: true,
+		},
+		{
+			name:   "adr on 2022",
+			server: testSQL2022,
+			modify: func(d *MssqlDatabaseResourceModel) { d.AcceleratedDatabaseRecovery = types.BoolValue(true) },
+		},
+		{
+			name:    "adr off on azure",
+			server:  testAzureSQL,
+			modify:  func(d *MssqlDatabaseResourceModel) { d.AcceleratedDatabaseRecovery = types.BoolValue(false) },
+			wantErr: true,
+		},
+		{
+			name:    "simple recovery on azure",
+			server:  testAzureSQL,
+			modify:  func(d *MssqlDatabaseResourceModel) { d.RecoveryModel = types.StringValue("SIMPLE") },
+			wantErr: true,
+		},
+		{
+			name:   "simple recovery on box",
+			server: testSQL2016,
+			modify: func(d *MssqlDatabaseResourceModel) { d.RecoveryModel = types.StringValue("SIMPLE") },
+		},
+		{
+			name:    "compatibility level too new",
+			server:  testSQL2016,
+			modify:  func(d *MssqlDatabaseResourceModel) { d.CompatibilityLevel = types.Int64Value(150) },
+			wantErr: true,
+		},
+		{
+			name:   "unknown compatibility level",
+			server: testSQL2016,
+			modify: func(d *MssqlDatabaseResourceModel) { d.CompatibilityLevel = types.Int64Unknown() },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := nullDatabaseModel()
+			tt.modify(&data)
+
+			var diags diag.Diagnostics
+			validateDatabaseCapabilities(data, tt.server, &diags)
+			if diags.HasError() != tt.wantErr {
+				t.Fatalf("HasError() = %t, want %t: %v", diags.HasError(), tt.wantErr, diags)
+			}
+		})
+	}
+}
+
+func Test_validateLoginAndUserCapabilities(t *testing.T) {
+	login := MssqlLoginResourceModel{
+		Name:            types.StringValue("app"),
+		DefaultDatabase: types.StringValue("app"),
+		DefaultLanguage: types.StringNull(),
+	}
+
+	var diags diag.Diagnostics
+	validateLoginCapabilities(login, testSQL2016, &diags)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics for SQL Server: %v", diags)
+	}
+	validateLoginCapabilities(login, testAzureSQL, &diags)
+	if !diags.HasError() {
+		t.Fatalf("expected default_database to be rejected on Azure SQL Database")
+	}
+
+	user := MssqlUserResourceModel{Username: types.StringValue("app"), External: types.BoolValue(true)}
+	diags = nil
+	validateUserCapabilities(user, testAzureSQL, &diags)
+	validateUserCapabilities(user, testSQL2022, &diags)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics for external user: %v", diags)
+	}
+	validateUserCapabilities(user, testSQL2016, &diags)
+	if !diags.HasError() {
+		t.Fatalf("expected external user to be rejected before SQL Server 2022")
+	}
+}
@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/vsabella/terraform-provider-mssql/internal/core"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
+)
+
+// Test_MssqlGrantResource_State flips a grant to a deny and its grant option
+// in place, and checks that changes made outside Terraform show up as drift
+// on refresh.
+func Test_MssqlGrantResource_State(t *testing.T) {
+	c, data := newMoveTestServer(t)
+	data.Locks = core.NewLocks()
+	r := &MssqlGrantResource{ctx: data}
+	ctx := context.Background()
+
+	current := createResource(t, r, MssqlGrantResourceModel{
+		Id:              types.StringUnknown(),
+		Database:        types.StringValue("app"),
+		Permission:      types.StringValue("DELETE"),
+		Principal:       types.StringValue("readers"),
+		ObjectType:      types.StringValue("SCHEMA"),
+		ObjectName:      types.StringValue("sales"),
+		State:           types.StringValue("GRANT"),
+		WithGrantOption: types.BoolValue(true),
+		Columns:         types.SetNull(types.StringType),
+	})
+
+	grant := mssql.GrantPermission{Database: "app", Principal: "readers", Permission: "DELETE", ObjectType: "SCHEMA", ObjectName: "sales"}
+	if got, err := c.ReadPermission(ctx, grant); err != nil || !got.WithGrantOption {
+		t.Fatalf("ReadPermission() after Create() = %+v, %v, want the grant option", got, err)
+	}
+
+	current = updateResource(t, r, current, func(m *MssqlGrantResourceModel) { m.WithGrantOption = types.BoolValue(false) })
+	if got, err := c.ReadPermission(ctx, grant); err != nil || got.State != "GRANT" || got.WithGrantOption {
+		t.Fatalf("ReadPermission() after revoking the grant option = %+v, %v, want GRANT", got, err)
+	}
+
+	current = updateResource(t, r, current, func(m *MssqlGrantResourceModel) { m.State = types.StringValue("DENY") })
+	if got, err := c.ReadPermission(ctx, grant); err != nil || got.State != "DENY" {
+		t.Fatalf("ReadPermission() after Update() = %+v, %v, want DENY", got, err)
+	}
+
+	// Someone grants the permission again outside Terraform.
+	grant.WithGrantOption = true
+	if _, err := c.GrantPermission(ctx, grant); err != nil {
+		t.Fatalf("GrantPermission() error = %v", err)
+	}
+	if read, _ := readResource[MssqlGrantResourceModel](t, r, current); read.State.ValueString() != "GRANT" || !read.WithGrantOption.ValueBool() {
+		t.Fatalf("Read() = %+v, want GRANT with the grant option", read)
+	}
+}
+
+// Test_MssqlGrantResource_Columns changes the columns of a column grant in
+// place and checks that a column granted outside Terraform shows up as drift.
+func Test_MssqlGrantResource_Columns(t *testing.T) {
+	c, data := newMoveTestServer(t)
+	data.Locks = core.NewLocks()
+	r := &MssqlGrantResource{ctx: data}
+	ctx := context.Background()
+
+	if err := c.AddObject("app", "sales", "Customers"); err != nil {
+		t.Fatalf("AddObject() error = %v", err)
+	}
+	if err := c.AddColumns("app", "sales", "Customers", "Id", "Region", "Email"); err != nil {
+		t.Fatalf("AddColumns() error = %v", err)
+	}
+	columns := func(names ...string) types.Set {
+		set, _ := types.SetValueFrom(ctx, types.StringType, names)
+		return set
+	}
+
+	current := createResource(t, r, MssqlGrantResourceModel{
+		Id:              types.StringUnknown(),
+		Database:        types.StringValue("app"),
+		Permission:      types.StringValue("SELECT"),
+		Principal:       types.StringValue("app_user"),
+		ObjectType:      types.StringValue("TABLE"),
+		ObjectName:      types.StringValue("sales.customers"),
+		State:           types.StringValue("GRANT"),
+		WithGrantOption: types.BoolValue(false),
+		Columns:         columns("id", "region"),
+	})
+
+	grant := mssql.GrantPermission{Database: "app", Principal: "app_user", Permission: "SELECT", ObjectType: "TABLE", ObjectName: "sales.Customers", Columns: []string{"Id"}}
+	readColumns := func() string {
+		t.Helper()
+		got, err := c.ReadPermission(ctx, grant)
+		if err != nil {
+			t.Fatalf("ReadPermission() error = %v", err)
+		}
+		return strings.Join(got.Columns, ",")
+	}
+	if got := readColumns(); got != "Id,Region" {
+		t.Fatalf("columns after Create() = %s, want Id,Region", got)
+	}
+
+	// Columns are spelled as configured, not as on the server.
+	if read, _ := readResource[MssqlGrantResourceModel](t, r, current); !read.Columns.Equal(columns("id", "region")) {
+		t.Fatalf("Read() columns = %s, want the configured spelling", read.Columns)
+	}
+
+	current = updateResource(t, r, current, func(m *MssqlGrantResourceModel) { m.Columns = columns("region", "email") })
+	grant.Columns = []string{"Region"}
+	if got := readColumns(); got != "Email,Region" {
+		t.Fatalf("columns after Update() = %s, want Email,Region", got)
+	}
+
+	// Someone grants another column outside Terraform.
+	if _, err := c.GrantPermission(ctx, mssql.GrantPermission{Database: "app", Principal: "app_user", Permission: "SELECT", ObjectType: "TABLE", ObjectName: "sales.Customers", Columns: []string{"Id"}}); err != nil {
+		t.Fatalf("GrantPermission() error = %v", err)
+	}
+	if read, _ := readResource[MssqlGrantResourceModel](t, r, current); !read.Columns.Equal(columns("Id", "region", "email")) {
+		t.Fatalf("Read() columns = %s, want Id, region and email", read.Columns)
+	}
+
+	// Delete revokes the columns in state, leaving the one granted outside
+	// Terraform.
+	if diags := deleteResource(t, r, current); diags.HasError() {
+		t.Fatalf("Delete() diagnostics = %v", diags)
+	}
+	grant.Columns = []string{"Id"}
+	if got := readColumns(); got != "Id" {
+		t.Fatalf("columns after Delete() = %s, want Id", got)
+	}
+
+	// Errors of the revoke are reported.
+	if err := c.DeleteUser(ctx, "app", "app_user"); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	diags := deleteResource(t, r, current)
+	if !diags.HasError() || !strings.Contains(diags.Errors()[0].Detail(), "app_user") || !strings.Contains(diags.Errors()[0].Detail(), "got error:") {
+		t.Fatalf("Delete() of a grant to a dropped user diagnostics = %v, want the revoke error", diags)
+	}
+}
+
+func Test_decodeGrantId(t *testing.T) {
+	grant, err := decodeGrantId("localhost:1433/app/app_user/SELECT/TABLE/sales.customers/id,region")
+	if err != nil {
+		t.Fatalf("decodeGrantId() error = %v", err)
+	}
+	if grant.ObjectName != "sales.customers" || strings.Join(grant.Columns, ",") != "id,region" {
+		t.Fatalf("decodeGrantId() = %+v, want columns id and region of sales.customers", grant)
+	}
+	if grant, err := decodeGrantId("localhost:1433/app/app_user/SELECT/TABLE/sales.customers"); err != nil || grant.Columns != nil {
+		t.Fatalf("decodeGrantId() = %+v, %v, want no columns", grant, err)
+	}
+	if _, err := decodeGrantId("localhost:1433/app/app_user/SELECT/TABLE"); err == nil {
+		t.Fatal("decodeGrantId() of 5 segments error = nil")
+	}
+}
@@ -0,0 +1,166 @@
+package provider
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/vsabella/terraform-provider-mssql/internal/core"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
+)
+
+// Resources of other community providers can be moved into this provider with
+// a `moved` block (Terraform 1.8 or later):
+//
+//   - betr-io/mssql names objects in its state and identifies the server with
+//     a sqlserver://<host>:<port> URL in each ID.
+//   - PGSSoft/mssql identifies databases, principals and schemas by their
+//     catalog ids, so moving its state looks the names up on the server.
+//
+// Movers only set the attributes that identify the object, plus what cannot
+// be read back such as passwords. Terraform refreshes moved resources, and
+// Read fills in the rest.
+const (
+	betrProvider    = "betr-io/mssql"
+	pgssoftProvider = "pgssoft/mssql"
+)
+
+// movingFrom reports whether a move request is for a resource type of the
+// given source provider. The registry hostname is ignored so that mirrors
+// and private registries match too.
+func movingFrom(req resource.MoveStateRequest, source string, typeName string) bool {
+	return req.SourceTypeName == typeName &&
+		strings.HasSuffix(strings.ToLower(req.SourceProviderAddress), "/"+source)
+}
+
+// decodeMovedState decodes the raw state of the source resource into v.
+func decodeMovedState(req resource.MoveStateRequest, v any, diags *diag.Diagnostics) bool {
+	if req.SourceRawState == nil || len(req.SourceRawState.JSON) == 0 {
+		diags.AddError("Unable to Move Resource State",
+			fmt.Sprintf("The state of %s from %s has no JSON representation.", req.SourceTypeName, req.SourceProviderAddress))
+		return false
+	}
+	if err := json.Unmarshal(req.SourceRawState.JSON, v); err != nil {
+		diags.AddError("Unable to Move Resource State",
+			fmt.Sprintf("Unable to decode the state of %s from %s: %s", req.SourceTypeName, req.SourceProviderAddress, err))
+		return false
+	}
+	return true
+}
+
+// requireMoveServer reports whether the provider can build IDs and look up
+// names for moved state. Unlike other operations, moves are not preceded by
+// a resource Configure call, so the resource may have no provider data.
+func requireMoveServer(data core.ProviderData, diags *diag.Diagnostics) bool {
+	if data.Client == nil {
+		diags.AddError("Provider Not Configured",
+			"Moving state into mssql resources requires a configured mssql provider to connect to the server.")
+		return false
+	}
+	return data.RequireServer(diags)
+}
+
+// stringOrNull returns a null string for "", which other providers store for
+// unset optional attributes.
+func stringOrNull(s string) types.String {
+	if s == "" {
+		return types.StringNull()
+	}
+	return types.StringValue(s)
+}
+
+// checkBetrServer warns when a betr-io/mssql ID, sqlserver://<host>:<port>/...,
+// names a different server than the one the provider connects to.
+func checkBetrServer(id string, serverID string, diags *diag.Diagnostics) {
+	u, err := url.Parse(id)
+	if err != nil || u.Scheme != "sqlserver" || u.Host == "" {
+		diags.AddError("Unable to Move Resource State",
+			fmt.Sprintf("expected betr-io/mssql id in format sqlserver://<host>:<port>/..., got %q", id))
+		return
+	}
+	if !strings.EqualFold(u.Host, serverID) {
+		diags.AddWarning("Moved Resource From Another Server",
+			fmt.Sprintf("The moved resource was managed on %s, but the provider connects to %s. "+
+				"The object is read from %s after the move; if it does not exist there, Terraform plans to create it.", u.Host, serverID, serverID))
+	}
+}
+
+// parseCatalogIds splits a PGSSoft/mssql ID, such as <database_id>/<principal_id>,
+// into its n catalog ids.
+func parseCatalogIds(id string, n int, format string) ([]int64, error) {
+	parts := strings.Split(id, "/")
+	if len(parts) != n {
+		return nil, fmt.Errorf("expected id in format %s, got %q", format, id)
+	}
+	ids := make([]int64, n)
+	for i, part := range parts {
+		v, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected id in format %s, got %q", format, id)
+		}
+		ids[i] = v
+	}
+	return ids, nil
+}
+
+// lookupError describes a failed lookup of a catalog id from moved state.
+func lookupError(diags *diag.Diagnostics, what string, id int64, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		diags.AddError("Unable to Move Resource State",
+			fmt.Sprintf("The %s with id %d referenced by the moved state does not exist on the server.", what, id))
+		return
+	}
+	diags.AddError("Unable to Move Resource State",
+		fmt.Sprintf("Unable to look up the %s with id %d, got error: %s", what, id, err))
+}
+
+// lookupDatabase returns the name of the database with a catalog id.
+func lookupDatabase(ctx context.Context, client mssql.SqlClient, id int64, diags *diag.Diagnostics) string {
+	db, err := client.GetDatabaseById(ctx, id)
+	if err != nil {
+		lookupError(diags, "database", id, err)
+		return ""
+	}
+	return db.Name
+}
+
+// lookupDatabasePrincipal returns the name of the user or role with a
+// catalog id in a database.
+func lookupDatabasePrincipal(ctx context.Context, client mssql.SqlClient, database string, id int64, diags *diag.Diagnostics) string {
+	name, err := client.GetDatabasePrincipalName(ctx, database, id)
+	if err != nil {
+		lookupError(diags, fmt.Sprintf("principal in database %s", database), id, err)
+		return ""
+	}
+	return name
+}
+
+// lookupSchema returns the name of the schema with a catalog id in a
+// database.
+func lookupSchema(ctx context.Context, client mssql.SqlClient, database string, id int64, diags *diag.Diagnostics) string {
+	name, err := client.GetSchemaName(ctx, database, id)
+	if err != nil {
+		lookupError(diags, fmt.Sprintf("schema in database %s", database), id, err)
+		return ""
+	}
+	return name
+}
+
+// lookupServerPrincipal returns the name of the login or server role with a
+// catalog id.
+func lookupServerPrincipal(ctx context.Context, client mssql.SqlClient, id int64, diags *diag.Diagnostics) string {
+	name, err := client.GetServerPrincipalName(ctx, id)
+	if err != nil {
+		lookupError(diags, "server principal", id, err)
+		return ""
+	}
+	return name
+}
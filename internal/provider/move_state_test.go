@@ -0,0 +1,300 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/vsabella/terraform-provider-mssql/internal/core"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
+	"github.com/vsabella/terraform-provider-mssql/mssql/mssqlfake"
+)
+
+const (
+	testBetrAddress    = "registry.terraform.io/betr-io/mssql"
+	testPgssoftAddress = "registry.terraform.io/PGSSoft/mssql"
+)
+
+// newMoveTestServer returns a fake server with an app database holding an
+// app login, its user, a readers role with the user as member and a sales
+// schema.
+func newMoveTestServer(t *testing.T) (*mssqlfake.Client, core.ProviderData) {
+	t.Helper()
+	ctx := context.Background()
+	c := mssqlfake.New(mssqlfake.Config{})
+	if _, err := c.CreateDatabase(ctx, "app"); err != nil {
+		t.Fatalf("CreateDatabase() error = %v", err)
+	}
+	if _, err := c.CreateLogin(ctx, mssql.CreateLogin{Name: "app", Password: "s3cret!Pass", DefaultDatabase: "app"}); err != nil {
+		t.Fatalf("CreateLogin() error = %v", err)
+	}
+	if _, err := c.CreateUser(ctx, "app", mssql.CreateUser{Username: "app_user", LoginName: "app", DefaultSchema: "dbo"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if _, err := c.CreateRole(ctx, "app", "readers"); err != nil {
+		t.Fatalf("CreateRole() error = %v", err)
+	}
+	if _, err := c.AssignRole(ctx, "app", "readers", "app_user"); err != nil {
+		t.Fatalf("AssignRole() error = %v", err)
+	}
+	if err := c.AddSchema("app", "sales"); err != nil {
+		t.Fatalf("AddSchema() error = %v", err)
+	}
+	return c, core.ProviderData{Client: c, ServerID: "localhost:1433", Database: "master"}
+}
+
+// moveState runs the state movers of a resource like the framework does and
+// returns the moved state as the resource model.
+func moveState[M any](t *testing.T, r resource.ResourceWithMoveState, address string, typeName string, state string) (M, diag.Diagnostics) {
+	t.Helper()
+	ctx := context.Background()
+
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+
+	var model M
+	var diags diag.Diagnostics
+	for _, mover := range r.MoveState(ctx) {
+		req := resource.MoveStateRequest{
+			SourceProviderAddress: address,
+			SourceTypeName:        typeName,
+			SourceRawState:        &tfprotov6.RawState{JSON: []byte(state)},
+		}
+		resp := resource.MoveStateResponse{
+			TargetState: tfsdk.State{
+				Schema: schemaResp.Schema,
+				Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+			},
+		}
+		mover.StateMover(ctx, req, &resp)
+		diags.Append(resp.Diagnostics...)
+		if resp.Diagnostics.HasError() {
+			return model, diags
+		}
+		if !resp.TargetState.Raw.IsNull() {
+			diags.Append(resp.TargetState.Get(ctx, &model)...)
+			return model, diags
+		}
+	}
+	diags.AddError("No State Mover", fmt.Sprintf("no state mover handled %s from %s", typeName, address))
+	return model, diags
+}
+
+func Test_MoveState_Login(t *testing.T) {
+	c, data := newMoveTestServer(t)
+	r := &MssqlLoginResource{ctx: data}
+
+	got, diags := moveState[MssqlLoginResourceModel](t, r, testBetrAddress, "mssql_login",
+		`{"id":"sqlserver://localhost:1433/app","server":[{"host":"localhost","port":"1433"}],"login_name":"app","password":"s3cret!Pass","sid":"0x01AB","default_database":"app","default_language":"","principal_id":267}`)
+	if diags.HasError() {
+		t.Fatalf("moving betr-io mssql_login: %v", diags)
+	}
+	if got.Id.ValueString() != "localhost:1433/app" || got.Name.ValueString() != "app" || got.Password.ValueString() != "s3cret!Pass" ||
+		got.DefaultDatabase.ValueString() != "app" || !got.DefaultLanguage.IsNull() || got.AutoImport.ValueBool() {
+		t.Fatalf("moved betr-io mssql_login = %+v", got)
+	}
+
+	_, diags = moveState[MssqlLoginResourceModel](t, r, testBetrAddress, "mssql_login",
+		`{"id":"sqlserver://other:1433/app","login_name":"app","password":"x"}`)
+	if diags.HasError() || diags.WarningsCount() != 1 {
+		t.Fatalf("moving betr-io mssql_login from another server: %v, want one warning", diags)
+	}
+
+	appDb, err := c.GetDatabase(context.Background(), "app")
+	if err != nil {
+		t.Fatalf("GetDatabase() error = %v", err)
+	}
+	got, diags = moveState[MssqlLoginResourceModel](t, r, testPgssoftAddress, "mssql_sql_login",
+		fmt.Sprintf(`{"id":"0x01AB","name":"app","password":"s3cret!Pass","default_database_id":"%d","default_language":"us_english"}`, appDb.Id))
+	if diags.HasError() {
+		t.Fatalf("moving PGSSoft mssql_sql_login: %v", diags)
+	}
+	if got.Id.ValueString() != "localhost:1433/app" || got.DefaultDatabase.ValueString() != "app" || got.Sid.ValueString() != "0x01AB" {
+		t.Fatalf("moved PGSSoft mssql_sql_login = %+v", got)
+	}
+
+	_, diags = moveState[MssqlLoginResourceModel](t, r, testPgssoftAddress, "mssql_sql_login",
+		`{"id":"0x01AB","name":"app","password":"x","default_database_id":"9999"}`)
+	if !diags.HasError() {
+		t.Fatal("moving PGSSoft mssql_sql_login with a missing default database succeeded, want error")
+	}
+
+	_, diags = moveState[MssqlLoginResourceModel](t, r, "registry.terraform.io/hashicorp/azurerm", "mssql_login", `{}`)
+	if !diags.HasError() {
+		t.Fatal("moving from an unsupported provider succeeded, want error")
+	}
+}
+
+func Test_MoveState_User(t *testing.T) {
+	c, data := newMoveTestServer(t)
+	r := &MssqlUserResource{ctx: data}
+
+	got, diags := moveState[MssqlUserResourceModel](t, r, testBetrAddress, "mssql_user",
+		`{"id":"sqlserver://localhost:1433/app/app_user","database":"app","username":"app_user","login_name":"app","password":"","sid":"","authentication_type":"INSTANCE","default_schema":"dbo","roles":["readers"]}`)
+	if diags.HasError() {
+		t.Fatalf("moving betr-io mssql_user: %v", diags)
+	}
+	if diags.WarningsCount() != 1 {
+		t.Fatalf("moving betr-io mssql_user with roles: %v, want a warning about the roles", diags)
+	}
+	if got.Id.ValueString() != "localhost:1433/app/app_user" || got.LoginName.ValueString() != "app" || !got.Password.IsNull() || got.External.ValueBool() {
+		t.Fatalf("moved betr-io mssql_user = %+v", got)
+	}
+
+	appDb, err := c.GetDatabase(context.Background(), "app")
+	if err != nil {
+		t.Fatalf("GetDatabase() error = %v", err)
+	}
+	got, diags = moveState[MssqlUserResourceModel](t, r, testPgssoftAddress, "mssql_sql_user",
+		fmt.Sprintf(`{"id":"%d/5","name":"app_user","database_id":"%d","login_id":"0x01AB"}`, appDb.Id, appDb.Id))
+	if diags.HasError() {
+		t.Fatalf("moving PGSSoft mssql_sql_user: %v", diags)
+	}
+	if got.Id.ValueString() != "localhost:1433/app/app_user" || got.Database.ValueString() != "app" || got.External.ValueBool() {
+		t.Fatalf("moved PGSSoft mssql_sql_user = %+v", got)
+	}
+
+	got, diags = moveState[MssqlUserResourceModel](t, r, testPgssoftAddress, "mssql_azuread_user",
+		fmt.Sprintf(`{"id":"%d/6","name":"someone@example.com","database_id":"%d","user_object_id":"00000000-0000-0000-0000-000000000000"}`, appDb.Id, appDb.Id))
+	if diags.HasError() {
+		t.Fatalf("moving PGSSoft mssql_azuread_user: %v", diags)
+	}
+	if !got.External.ValueBool() {
+		t.Fatalf("moved PGSSoft mssql_azuread_user = %+v, want external", got)
+	}
+}
+
+func Test_MoveState_Role(t *testing.T) {
+	c, data := newMoveTestServer(t)
+	r := &MssqlRoleResource{ctx: data}
+
+	appDb, err := c.GetDatabase(context.Background(), "app")
+	if err != nil {
+		t.Fatalf("GetDatabase() error = %v", err)
+	}
+	got, diags := moveState[MssqlRoleResourceModel](t, r, testPgssoftAddress, "mssql_database_role",
+		fmt.Sprintf(`{"id":"%d/7","name":"readers","database_id":"%d","owner_id":"%d/1"}`, appDb.Id, appDb.Id, appDb.Id))
+	if diags.HasError() {
+		t.Fatalf("moving PGSSoft mssql_database_role: %v", diags)
+	}
+	if got.Id.ValueString() != "localhost:1433/app/readers" || got.Database.ValueString() != "app" || got.Name.ValueString() != "readers" {
+		t.Fatalf("moved PGSSoft mssql_database_role = %+v", got)
+	}
+}
+
+func Test_MoveState_RoleAssignment(t *testing.T) {
+	c, data := newMoveTestServer(t)
+	r := &MssqlRoleAssignmentResource{ctx: data}
+
+	ctx := context.Background()
+	appDb, err := c.GetDatabase(ctx, "app")
+	if err != nil {
+		t.Fatalf("GetDatabase() error = %v", err)
+	}
+	roleId, err := c.GetDatabasePrincipalId(ctx, "app", "readers")
+	if err != nil {
+		t.Fatalf("GetDatabasePrincipalId() error = %v", err)
+	}
+	memberId, err := c.GetDatabasePrincipalId(ctx, "app", "app_user")
+	if err != nil {
+		t.Fatalf("GetDatabasePrincipalId() error = %v", err)
+	}
+	got, diags := moveState[MssqlRoleAssignmentResourceModel](t, r, testPgssoftAddress, "mssql_database_role_member",
+		fmt.Sprintf(`{"id":"%[1]d/%[2]d/%[3]d","role_id":"%[1]d/%[2]d","member_id":"%[1]d/%[3]d"}`, appDb.Id, roleId, memberId))
+	if diags.HasError() {
+		t.Fatalf("moving PGSSoft mssql_database_role_member: %v", diags)
+	}
+	if got.Id.ValueString() != "localhost:1433/db/app/readers/app_user" || got.ServerRole.ValueBool() {
+		t.Fatalf("moved PGSSoft mssql_database_role_member = %+v", got)
+	}
+
+	_, diags = moveState[MssqlRoleAssignmentResourceModel](t, r, testPgssoftAddress, "mssql_database_role_member",
+		fmt.Sprintf(`{"role_id":"%[1]d/%[2]d","member_id":"%[1]d/9999"}`, appDb.Id, roleId))
+	if !diags.HasError() {
+		t.Fatal("moving PGSSoft mssql_database_role_member with a missing member succeeded, want error")
+	}
+
+	serverRoleId, err := c.GetServerPrincipalId(ctx, "sysadmin")
+	if err != nil {
+		t.Fatalf("GetServerPrincipalId() error = %v", err)
+	}
+	loginId, err := c.GetServerPrincipalId(ctx, "app")
+	if err != nil {
+		t.Fatalf("GetServerPrincipalId() error = %v", err)
+	}
+	got, diags = moveState[MssqlRoleAssignmentResourceModel](t, r, testPgssoftAddress, "mssql_server_role_member",
+		fmt.Sprintf(`{"id":"%[1]d/%[2]d","role_id":"%[1]d","member_id":"%[2]d"}`, serverRoleId, loginId))
+	if diags.HasError() {
+		t.Fatalf("moving PGSSoft mssql_server_role_member: %v", diags)
+	}
+	if got.Id.ValueString() != "localhost:1433/server/sysadmin/app" || !got.ServerRole.ValueBool() || !got.Database.IsNull() {
+		t.Fatalf("moved PGSSoft mssql_server_role_member = %+v", got)
+	}
+}
+
+func Test_MoveState_Grant(t *testing.T) {
+	c, data := newMoveTestServer(t)
+	r := &MssqlGrantResource{ctx: data}
+
+	ctx := context.Background()
+	appDb, err := c.GetDatabase(ctx, "app")
+	if err != nil {
+		t.Fatalf("GetDatabase() error = %v", err)
+	}
+	principalId, err := c.GetDatabasePrincipalId(ctx, "app", "app_user")
+	if err != nil {
+		t.Fatalf("GetDatabasePrincipalId() error = %v", err)
+	}
+	schemaId, err := c.GetSecurableId(ctx, "app", "SCHEMA", "sales")
+	if err != nil {
+		t.Fatalf("GetSecurableId() error = %v", err)
+	}
+
+	got, diags := moveState[MssqlGrantResourceModel](t, r, testPgssoftAddress, "mssql_database_permission",
+		fmt.Sprintf(`{"id":"%[1]d/%[2]d/CONNECT","principal_id":"%[1]d/%[2]d","permission":"CONNECT","with_grant_option":false}`, appDb.Id, principalId))
+	if diags.HasError() {
+		t.Fatalf("moving PGSSoft mssql_database_permission: %v", diags)
+	}
+	if got.Id.ValueString() != "localhost:1433/app/app_user/CONNECT" || !got.ObjectType.IsNull() || !got.ObjectName.IsNull() {
+		t.Fatalf("moved PGSSoft mssql_database_permission = %+v", got)
+	}
+
+	got, diags = moveState[MssqlGrantResourceModel](t, r, testPgssoftAddress, "mssql_schema_permission",
+		fmt.Sprintf(`{"schema_id":"%[1]d/%[2]d","principal_id":"%[1]d/%[3]d","permission":"select","with_grant_option":true}`, appDb.Id, schemaId, principalId))
+	if diags.HasError() {
+		t.Fatalf("moving PGSSoft mssql_schema_permission: %v", diags)
+	}
+	if got.Permission.ValueString() != "SELECT" || got.ObjectType.ValueString() != "SCHEMA" || got.ObjectName.ValueString() != "sales" || !got.WithGrantOption.ValueBool() {
+		t.Fatalf("moved PGSSoft mssql_schema_permission = %+v", got)
+	}
+}
+
+func Test_MoveState_RequiresConfiguredProvider(t *testing.T) {
+	r := &MssqlLoginResource{}
+	_, diags := moveState[MssqlLoginResourceModel](t, r, testBetrAddress, "mssql_login",
+		`{"id":"sqlserver://localhost:1433/app","login_name":"app","password":"x"}`)
+	if !diags.HasError() {
+		t.Fatal("moving state without a configured provider succeeded, want error")
+	}
+}
+
+func Test_MssqlProvider_ResourcesConfiguredForMoveState(t *testing.T) {
+	_, data := newMoveTestServer(t)
+	p := &MssqlProvider{}
+	p.setData(&data)
+
+	for _, factory := range p.Resources(context.Background()) {
+		if r, ok := factory().(*MssqlLoginResource); ok {
+			if r.ctx.ServerID != data.ServerID {
+				t.Fatalf("login resource ServerID = %q, want %q", r.ctx.ServerID, data.ServerID)
+			}
+			return
+		}
+	}
+	t.Fatal("Resources() has no mssql_login resource")
+}
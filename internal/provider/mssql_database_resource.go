@@ -5,9 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"net/url"
 	"strings"
-	"sync"
 
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/path"
@@ -18,13 +16,14 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/vsabella/terraform-provider-mssql/internal/core"
-	"github.com/vsabella/terraform-provider-mssql/internal/mssql"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &MssqlDatabaseResource{}
 var _ resource.ResourceWithImportState = &MssqlDatabaseResource{}
-var resLock sync.Mutex
+var _ resource.ResourceWithUpgradeState = &MssqlDatabaseResource{}
+var _ resource.ResourceWithModifyPlan = &MssqlDatabaseResource{}
 
 func NewMssqlDatabaseResource() resource.Resource {
 	return &MssqlDatabaseResource{}
@@ -63,11 +62,13 @@ func (r *MssqlDatabaseResource) Metadata(ctx context.Context, req resource.Metad
 
 func (r *MssqlDatabaseResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Version: schemaVersion,
+
 		MarkdownDescription: "Manages a SQL Server database including engine options and scoped configurations. **Note:** Destroy removes the resource from Terraform state but does not drop the database from the server.",
 
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
-				MarkdownDescription: "Resource identifier in format `<server_id>/<database>` where `server_id` is `host:port`.",
+				MarkdownDescription: "Resource identifier in format `<server_id>/<database>` where `server_id` is the provider's `server_id`, by default `host:port`. Segments are URL path-escaped.",
 				Computed:            true,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
@@ -179,9 +180,33 @@ func (r *MssqlDatabaseResource) Configure(ctx context.Context, req resource.Conf
 	r.ctx = *client
 }
 
+func (r *MssqlDatabaseResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to check on destroy.
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data MssqlDatabaseResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Resources in the database are checked after this one is planned, and
+	// must not fail because it does not exist yet.
+	if name, ok := known(data.Name); ok {
+		r.ctx.Planned.AddDatabase(name)
+	}
+
+	if r.ctx.Server != nil {
+		validateDatabaseCapabilities(data, *r.ctx.Server, &resp.Diagnostics)
+	}
+}
+
 func (r *MssqlDatabaseResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	resLock.Lock()
-	defer resLock.Unlock()
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
 
 	var data MssqlDatabaseResourceModel
 
@@ -191,13 +216,22 @@ func (r *MssqlDatabaseResource) Create(ctx context.Context, req resource.CreateR
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_database", formatId(r.ctx.ServerID, data.Name.ValueString()))
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
+	defer r.ctx.LockDatabase(data.Name.ValueString())()
+
+	// Concurrent CREATE DATABASE statements contend for the model database,
+	// so creation is serialized per server; the options below are not.
+	unlockServer := r.ctx.LockServer()
 	_, err := r.ctx.Client.CreateDatabase(ctx, data.Name.ValueString())
+	unlockServer()
 	if err != nil {
 		resp.Diagnostics.AddError(fmt.Sprintf("Error creating database %s", data.Name.ValueString()), err.Error())
 		return
 	}
 
-	data.Id = types.StringValue(fmt.Sprintf("%s/%s", r.ctx.ServerID, data.Name.ValueString()))
+	data.Id = types.StringValue(formatId(r.ctx.ServerID, data.Name.ValueString()))
 	tflog.Debug(ctx, fmt.Sprintf("Created database %s", data.Name.ValueString()))
 
 	// Apply database options if any are set
@@ -221,6 +255,11 @@ func (r *MssqlDatabaseResource) Create(ctx context.Context, req resource.CreateR
 }
 
 func (r *MssqlDatabaseResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Without a reachable server there is nothing to refresh; keep the prior state.
+	if r.ctx.Unconfigured {
+		return
+	}
+
 	var state MssqlDatabaseResourceModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
@@ -228,6 +267,8 @@ func (r *MssqlDatabaseResource) Read(ctx context.Context, req resource.ReadReque
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_database", state.Id.ValueString())
+
 	databaseName := state.Name.ValueString()
 	if state.Name.IsUnknown() || state.Name.IsNull() || databaseName == "" {
 		dbName, err := parseDatabaseId(state.Id.ValueString())
@@ -253,7 +294,7 @@ func (r *MssqlDatabaseResource) Read(ctx context.Context, req resource.ReadReque
 		return
 	}
 
-	state.Id = types.StringValue(fmt.Sprintf("%s/%s", r.ctx.ServerID, db.Name))
+	state.Id = types.StringValue(formatId(r.ctx.ServerID, db.Name))
 	state.Name = types.StringValue(db.Name)
 
 	if err := r.refreshDatabaseState(ctx, &state); err != nil {
@@ -264,8 +305,9 @@ func (r *MssqlDatabaseResource) Read(ctx context.Context, req resource.ReadReque
 }
 
 func (r *MssqlDatabaseResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	resLock.Lock()
-	defer resLock.Unlock()
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
 
 	var plan, state MssqlDatabaseResourceModel
 
@@ -275,6 +317,11 @@ func (r *MssqlDatabaseResource) Update(ctx context.Context, req resource.UpdateR
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_database", state.Id.ValueString())
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
+	defer r.ctx.LockDatabase(state.Name.ValueString())()
+
 	// we don't support updating database name as there should not be any reason to do so.
 	if plan.Name.ValueString() != state.Name.ValueString() {
 		resp.Diagnostics.AddError("Unable to update database", fmt.Sprintf("Updating database name is not supported. Database name cannot be changed from %s to %s.", state.Name.ValueString(), plan.Name.ValueString()))
@@ -321,8 +368,9 @@ func (r *MssqlDatabaseResource) Update(ctx context.Context, req resource.UpdateR
 }
 
 func (r *MssqlDatabaseResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
-	resLock.Lock()
-	defer resLock.Unlock()
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
 
 	var data MssqlDatabaseResourceModel
 
@@ -340,6 +388,12 @@ func (r *MssqlDatabaseResource) Delete(ctx context.Context, req resource.DeleteR
 }
 
 func (r *MssqlDatabaseResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
+	ctx = mssql.WithResource(ctx, "mssql_database", req.ID)
+
 	// Import ID must be <server_id>/<database>
 	dbName, err := parseDatabaseId(req.ID)
 	if err != nil {
@@ -358,7 +412,7 @@ func (r *MssqlDatabaseResource) ImportState(ctx context.Context, req resource.Im
 	// Importing the full server view here can cause Terraform to plan clearing unrelated settings.
 
 	// Set basic attributes
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), fmt.Sprintf("%s/%s", r.ctx.ServerID, db.Name))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), formatId(r.ctx.ServerID, db.Name))...)
 	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), db.Name)...)
 
 	// Get database options
@@ -418,16 +472,26 @@ func (r *MssqlDatabaseResource) ImportState(ctx context.Context, req resource.Im
 	}
 }
 
-func parseDatabaseId(id string) (string, error) {
-	parts := strings.Split(id, "/")
-	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-		return "", fmt.Errorf("expected id in format <server_id>/<database>, got %q", id)
+func (r *MssqlDatabaseResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: upgradeFromV0(currentSchema(ctx, r), func(data *MssqlDatabaseResourceModel) error {
+			server, err := upgradeServerId(r.ctx, data.Id.ValueString(), false)
+			if err != nil {
+				return err
+			}
+			data.Id = types.StringValue(formatId(server, data.Name.ValueString()))
+			return nil
+		}),
+		1: upgradeFromV1(currentSchema(ctx, r), r.ctx),
 	}
-	db, err := url.QueryUnescape(parts[1])
+}
+
+func parseDatabaseId(id string) (string, error) {
+	parts, err := parseId(id, 2, "<server_id>/<database>")
 	if err != nil {
 		return "", err
 	}
-	return db, nil
+	return parts[1], nil
 }
 
 func (r *MssqlDatabaseResource) applyDatabaseOptions(ctx context.Context, data *MssqlDatabaseResourceModel) error {
@@ -5,24 +5,33 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"net/url"
+	"slices"
 	"strings"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/setplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/vsabella/terraform-provider-mssql/internal/core"
-	"github.com/vsabella/terraform-provider-mssql/internal/mssql"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &MssqlGrantResource{}
+var _ resource.ResourceWithModifyPlan = &MssqlGrantResource{}
+var _ resource.ResourceWithValidateConfig = &MssqlGrantResource{}
 var _ resource.ResourceWithImportState = &MssqlGrantResource{}
+var _ resource.ResourceWithUpgradeState = &MssqlGrantResource{}
+var _ resource.ResourceWithMoveState = &MssqlGrantResource{}
 
 func NewMssqlGrantResource() resource.Resource {
 	return &MssqlGrantResource{}
@@ -33,88 +42,87 @@ type MssqlGrantResource struct {
 }
 
 type MssqlGrantResourceModel struct {
-	Id         types.String `tfsdk:"id"`
-	Database   types.String `tfsdk:"database"`
-	Permission types.String `tfsdk:"permission"`
-	Principal  types.String `tfsdk:"principal"`
-	ObjectType types.String `tfsdk:"object_type"`
-	ObjectName types.String `tfsdk:"object_name"`
+	Id              types.String `tfsdk:"id"`
+	Database        types.String `tfsdk:"database"`
+	Permission      types.String `tfsdk:"permission"`
+	Principal       types.String `tfsdk:"principal"`
+	ObjectType      types.String `tfsdk:"object_type"`
+	ObjectName      types.String `tfsdk:"object_name"`
+	State           types.String `tfsdk:"state"`
+	WithGrantOption types.Bool   `tfsdk:"with_grant_option"`
+	Columns         types.Set    `tfsdk:"columns"`
 }
 
 func grantToId(serverID string, grant mssql.GrantPermission) string {
-	parts := []string{
-		url.QueryEscape(serverID),
-		url.QueryEscape(grant.Database),
-		url.QueryEscape(grant.Principal),
-		url.QueryEscape(strings.ToUpper(grant.Permission)),
-	}
+	segments := []string{serverID, grant.Database, grant.Principal, strings.ToUpper(grant.Permission)}
 	if grant.ObjectType != "" && grant.ObjectName != "" {
-		parts = append(parts,
-			url.QueryEscape(strings.ToUpper(grant.ObjectType)),
-			url.QueryEscape(grant.ObjectName),
-		)
+		segments = append(segments, strings.ToUpper(grant.ObjectType), grant.ObjectName)
 	}
-	return strings.Join(parts, "/")
+	return formatId(segments...)
 }
 
+// decodeGrantId parses a grant ID. Import IDs may add the columns of a
+// column grant as a last segment, e.g. .../TABLE/dbo.customers/id,region.
 func decodeGrantId(id string) (mssql.GrantPermission, error) {
-	parts := strings.Split(id, "/")
-	if len(parts) < 4 {
-		return mssql.GrantPermission{}, fmt.Errorf("expected id in format <server_id>/<database>/<principal>/<permission>[/object_type/object_name], got %q", id)
-	}
-
-	switch len(parts) {
-	case 4, 6:
-		// ok
-	default:
-		return mssql.GrantPermission{}, fmt.Errorf("expected id in format <server_id>/<database>/<principal>/<permission>[/object_type/object_name], got %q", id)
-	}
-
-	decode := func(s string) (string, error) {
-		return url.QueryUnescape(s)
+	const format = "<server_id>/<database>/<principal>/<permission>[/object_type/object_name[/columns]]"
+	n := strings.Count(id, "/") + 1
+	if n != 4 && n != 6 && n != 7 {
+		return mssql.GrantPermission{}, fmt.Errorf("expected id in format %s, got %q", format, id)
 	}
-
-	serverID, err := decode(parts[0])
+	parts, err := parseId(id, n, format)
 	if err != nil {
 		return mssql.GrantPermission{}, err
 	}
-	if serverID == "" {
-		return mssql.GrantPermission{}, fmt.Errorf("expected id in format <server_id>/<database>/<principal>/<permission>[/object_type/object_name], got %q", id)
-	}
 
-	db, err := decode(parts[1])
-	if err != nil {
-		return mssql.GrantPermission{}, err
+	grant := mssql.GrantPermission{
+		Database:   parts[1],
+		Principal:  parts[2],
+		Permission: parts[3],
 	}
-	principal, err := decode(parts[2])
-	if err != nil {
-		return mssql.GrantPermission{}, err
+	if n >= 6 {
+		grant.ObjectType = parts[4]
+		grant.ObjectName = parts[5]
 	}
-	permission, err := decode(parts[3])
-	if err != nil {
-		return mssql.GrantPermission{}, err
+	if n == 7 {
+		grant.Columns = strings.Split(parts[6], ",")
 	}
+	return grant, nil
+}
 
-	grant := mssql.GrantPermission{
-		Database:   db,
-		Principal:  principal,
-		Permission: permission,
+// grantColumns returns the columns of a grant, or nil for a grant on the
+// whole object.
+func grantColumns(ctx context.Context, columns types.Set, diags *diag.Diagnostics) []string {
+	if columns.IsNull() || columns.IsUnknown() {
+		return nil
 	}
+	var names []string
+	diags.Append(columns.ElementsAs(ctx, &names, false)...)
+	return names
+}
 
-	if len(parts) == 6 {
-		objectType, err := decode(parts[4])
-		if err != nil {
-			return mssql.GrantPermission{}, err
+// readColumns returns the columns read from the server, spelled like the
+// matching prior columns so that a difference in case is not reported as
+// drift.
+func readColumns(prior []string, read []string) types.Set {
+	elements := make([]attr.Value, len(read))
+	for i, column := range read {
+		if j := slices.IndexFunc(prior, func(p string) bool { return strings.EqualFold(p, column) }); j >= 0 {
+			column = prior[j]
 		}
-		objectName, err := decode(parts[5])
-		if err != nil {
-			return mssql.GrantPermission{}, err
-		}
-		grant.ObjectType = objectType
-		grant.ObjectName = objectName
+		elements[i] = types.StringValue(column)
 	}
+	return types.SetValueMust(types.StringType, elements)
+}
 
-	return grant, nil
+// columnsExcept returns the columns of a that are not in b.
+func columnsExcept(a []string, b []string) []string {
+	var except []string
+	for _, column := range a {
+		if !slices.ContainsFunc(b, func(c string) bool { return strings.EqualFold(c, column) }) {
+			except = append(except, column)
+		}
+	}
+	return except
 }
 
 func (r *MssqlGrantResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -123,10 +131,12 @@ func (r *MssqlGrantResource) Metadata(ctx context.Context, req resource.Metadata
 
 func (r *MssqlGrantResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Version: schemaVersion,
+
 		// This description is used by the documentation generator and the language server.
-		MarkdownDescription: `Grants permissions to a database principal.
+		MarkdownDescription: `Grants or denies permissions to a database principal.
 
-Supports both database-level permissions (e.g., CREATE PROCEDURE) and object-level permissions (e.g., CONTROL on a SCHEMA).
+Supports both database-level permissions (e.g., CREATE PROCEDURE) and permissions on a securable of the database (e.g., CONTROL on a SCHEMA or VIEW DEFINITION on a CERTIFICATE).
 
 **Examples:**
 
@@ -148,11 +158,46 @@ resource "mssql_grant" "schema_control" {
   object_type = "SCHEMA"
   object_name = "tools"
 }
+` + "```" + `
+
+Column-level grant:
+` + "```hcl" + `
+resource "mssql_grant" "customer_columns" {
+  database    = "mydb"
+  permission  = "SELECT"
+  principal   = "reporting"
+  object_type = "TABLE"
+  object_name = "dbo.customers"
+  columns     = ["id", "region", "created_at"]
+}
+` + "```" + `
+
+Grant on a certificate:
+` + "```hcl" + `
+resource "mssql_grant" "signing_cert" {
+  database    = "mydb"
+  permission  = "CONTROL"
+  principal   = "signer"
+  object_type = "CERTIFICATE"
+  object_name = "CodeSigning"
+}
+` + "```" + `
+
+Deny on a table:
+` + "```hcl" + `
+resource "mssql_grant" "deny_delete" {
+  database    = "mydb"
+  permission  = "DELETE"
+  principal   = "app_role"
+  object_type = "TABLE"
+  object_name = "audit.Log"
+  state       = "DENY"
+}
 ` + "```",
 
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
-				MarkdownDescription: "Resource identifier in format `<server_id>/<database>/<principal>/<permission>[/object_type/object_name]` where `server_id` is `host:port`.",
+				MarkdownDescription: "Resource identifier in format `<server_id>/<database>/<principal>/<permission>[/object_type/object_name]` where `server_id` is the provider's `server_id`, by default `host:port`. Segments are URL path-escaped.",
 				Computed:            true,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
@@ -188,22 +233,48 @@ resource "mssql_grant" "schema_control" {
 				},
 			},
 			"object_type": schema.StringAttribute{
-				MarkdownDescription: "Type of object to grant permission on (e.g., SCHEMA, TABLE, VIEW, PROCEDURE). If not specified, grants a database-level permission.",
+				MarkdownDescription: "Type of securable to grant permission on: SCHEMA, OBJECT or one of its types (TABLE, VIEW, PROCEDURE, PROC, FUNCTION, SEQUENCE, SYNONYM), TYPE, XML SCHEMA COLLECTION, ASSEMBLY, CERTIFICATE, ASYMMETRIC KEY, SYMMETRIC KEY, ROLE, USER, APPLICATION ROLE, FULLTEXT CATALOG or SERVICE. If not specified, grants a database-level permission.",
 				Optional:            true,
 				Validators: []validator.String{
-					objectTypeValidator{},
+					objectTypeValidator{types: mssql.ObjectTypes},
 				},
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.RequiresReplace(),
 				},
 			},
 			"object_name": schema.StringAttribute{
-				MarkdownDescription: "Name of the object to grant permission on. Required if `object_type` is specified.",
+				MarkdownDescription: "Name of the securable to grant permission on, qualified by its schema (`schema.name`) for objects, types and XML schema collections. Required if `object_type` is specified.",
 				Optional:            true,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.RequiresReplace(),
 				},
 			},
+			"state": schema.StringAttribute{
+				MarkdownDescription: "`GRANT` or `DENY`. Changing it grants or denies the permission in place. Default: `GRANT`",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("GRANT"),
+				Validators: []validator.String{
+					stringOneOfValidator{values: []string{"GRANT", "DENY"}},
+				},
+			},
+			"with_grant_option": schema.BoolAttribute{
+				MarkdownDescription: "Let the principal grant the permission to others (`WITH GRANT OPTION`). Only valid when `state` is `GRANT`. Turning it off revokes the grant option in place, together with the permissions the principal granted through it. Default: `false`",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"columns": schema.SetAttribute{
+				MarkdownDescription: "Columns of a table or view to grant the permission on, e.g. `GRANT SELECT (id, region) ON dbo.customers`. If not specified, the permission is on the whole object. Columns are added and removed in place; manage the columns of a permission, principal and object in one resource. To import a column grant, end the import ID with the columns separated by commas, e.g. `.../TABLE/dbo.customers/id,region`.",
+				ElementType:         types.StringType,
+				Optional:            true,
+				PlanModifiers: []planmodifier.Set{
+					setplanmodifier.RequiresReplaceIf(func(ctx context.Context, req planmodifier.SetRequest, resp *setplanmodifier.RequiresReplaceIfFuncResponse) {
+						// Column and object permissions are different permissions.
+						resp.RequiresReplace = req.StateValue.IsNull() != req.PlanValue.IsNull()
+					}, "Switching between column and object permissions requires replacement.", "Switching between column and object permissions requires replacement."),
+				},
+			},
 		},
 	}
 }
@@ -227,7 +298,54 @@ func (r *MssqlGrantResource) Configure(ctx context.Context, req resource.Configu
 	r.ctx = *client
 }
 
+func (r *MssqlGrantResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data MssqlGrantResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if data.WithGrantOption.ValueBool() && data.State.ValueString() == "DENY" {
+		resp.Diagnostics.AddAttributeError(path.Root("with_grant_option"), "Invalid Grant Option",
+			"with_grant_option cannot be set when state is DENY.")
+	}
+	if !data.Columns.IsNull() && !data.Columns.IsUnknown() {
+		if len(data.Columns.Elements()) == 0 {
+			resp.Diagnostics.AddAttributeError(path.Root("columns"), "Invalid Columns",
+				"columns must not be empty. Omit it to grant the permission on the whole object.")
+		}
+		if data.ObjectType.IsNull() || !data.ObjectType.IsUnknown() &&
+			!slices.Contains(mssql.ColumnObjectTypes, strings.ToUpper(strings.TrimSpace(data.ObjectType.ValueString()))) {
+			resp.Diagnostics.AddAttributeError(path.Root("columns"), "Invalid Columns",
+				"columns require an object_type of TABLE, VIEW or OBJECT.")
+		}
+	}
+}
+
+func (r *MssqlGrantResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	refs, ok := checkReferences(ctx, r.ctx, req, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+
+	var data MssqlGrantResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	database, ok := plannedDatabase(r.ctx, data.Database)
+	if !ok || !refs.database(path.Root("database"), database) {
+		return
+	}
+	refs.databasePrincipal(path.Root("principal"), database, data.Principal)
+	refs.securable(path.Root("object_name"), database, data.ObjectType, data.ObjectName)
+}
+
 func (r *MssqlGrantResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	var data MssqlGrantResourceModel
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -250,13 +368,23 @@ func (r *MssqlGrantResource) Create(ctx context.Context, req resource.CreateRequ
 	}
 
 	grant := mssql.GrantPermission{
-		Database:   database,
-		Principal:  data.Principal.ValueString(),
-		Permission: strings.ToUpper(data.Permission.ValueString()),
-		ObjectType: strings.ToUpper(data.ObjectType.ValueString()),
-		ObjectName: data.ObjectName.ValueString(),
+		Database:        database,
+		Principal:       data.Principal.ValueString(),
+		Permission:      strings.ToUpper(data.Permission.ValueString()),
+		ObjectType:      strings.ToUpper(data.ObjectType.ValueString()),
+		ObjectName:      data.ObjectName.ValueString(),
+		State:           data.State.ValueString(),
+		WithGrantOption: data.WithGrantOption.ValueBool(),
+		Columns:         grantColumns(ctx, data.Columns, &resp.Diagnostics),
+	}
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_grant", grantToId(r.ctx.ServerID, grant))
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
+	defer r.ctx.LockDatabase(grant.Database)()
 	result, err := r.ctx.Client.GrantPermission(ctx, grant)
 	if err != nil {
 		resp.Diagnostics.AddError(
@@ -269,6 +397,8 @@ func (r *MssqlGrantResource) Create(ctx context.Context, req resource.CreateRequ
 	data.Database = types.StringValue(database)
 	data.Principal = types.StringValue(result.Principal)
 	data.Permission = types.StringValue(result.Permission)
+	data.State = types.StringValue(result.State)
+	data.WithGrantOption = types.BoolValue(result.WithGrantOption)
 	if result.ObjectType != "" {
 		data.ObjectType = types.StringValue(result.ObjectType)
 	}
@@ -281,6 +411,11 @@ func (r *MssqlGrantResource) Create(ctx context.Context, req resource.CreateRequ
 }
 
 func (r *MssqlGrantResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Without a reachable server there is nothing to refresh; keep the prior state.
+	if r.ctx.Unconfigured {
+		return
+	}
+
 	var data MssqlGrantResourceModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -288,6 +423,8 @@ func (r *MssqlGrantResource) Read(ctx context.Context, req resource.ReadRequest,
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_grant", data.Id.ValueString())
+
 	database := data.Database.ValueString()
 	if data.Database.IsUnknown() || data.Database.IsNull() || database == "" {
 		decoded, err := decodeGrantId(data.Id.ValueString())
@@ -324,6 +461,10 @@ func (r *MssqlGrantResource) Read(ctx context.Context, req resource.ReadRequest,
 		Permission: strings.ToUpper(data.Permission.ValueString()),
 		ObjectType: strings.ToUpper(data.ObjectType.ValueString()),
 		ObjectName: data.ObjectName.ValueString(),
+		Columns:    grantColumns(ctx, data.Columns, &resp.Diagnostics),
+	}
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
 	perm, err := r.ctx.Client.ReadPermission(ctx, lookupGrant)
@@ -339,6 +480,11 @@ func (r *MssqlGrantResource) Read(ctx context.Context, req resource.ReadRequest,
 	data.Database = types.StringValue(database)
 	data.Principal = types.StringValue(perm.Principal)
 	data.Permission = types.StringValue(perm.Permission)
+	data.State = types.StringValue(perm.State)
+	data.WithGrantOption = types.BoolValue(perm.WithGrantOption)
+	if len(perm.Columns) > 0 {
+		data.Columns = readColumns(lookupGrant.Columns, perm.Columns)
+	}
 	if perm.ObjectType != "" {
 		data.ObjectType = types.StringValue(perm.ObjectType)
 	}
@@ -349,18 +495,80 @@ func (r *MssqlGrantResource) Read(ctx context.Context, req resource.ReadRequest,
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
+// Update changes the state, grant option and columns of the permission, the
+// only attributes that do not force replacement. GRANT and DENY each replace
+// the other, so the permission is never left revoked in between.
 func (r *MssqlGrantResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	var data MssqlGrantResourceModel
+	var data, state MssqlGrantResourceModel
 
 	// Read Terraform plan data into the model
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	columns := grantColumns(ctx, data.Columns, &resp.Diagnostics)
+	priorColumns := grantColumns(ctx, state.Columns, &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
 		return
 	}
+	added, removed := columnsExcept(columns, priorColumns), columnsExcept(priorColumns, columns)
+	changed := !data.State.Equal(state.State) || !data.WithGrantOption.Equal(state.WithGrantOption) || len(added) > 0
+
+	if changed || len(removed) > 0 {
+		if !r.ctx.RequireServer(&resp.Diagnostics) {
+			return
+		}
+		ctx = mssql.WithResource(ctx, "mssql_grant", data.Id.ValueString())
+		defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
+		grant := mssql.GrantPermission{
+			Database:        data.Database.ValueString(),
+			Principal:       data.Principal.ValueString(),
+			Permission:      strings.ToUpper(data.Permission.ValueString()),
+			ObjectType:      strings.ToUpper(data.ObjectType.ValueString()),
+			ObjectName:      data.ObjectName.ValueString(),
+			State:           data.State.ValueString(),
+			WithGrantOption: data.WithGrantOption.ValueBool(),
+			Columns:         columns,
+		}
+
+		defer r.ctx.LockDatabase(grant.Database)()
+		var err error
+		if len(removed) > 0 {
+			revoke := grant
+			revoke.Columns = removed
+			err = r.ctx.Client.RevokePermission(ctx, revoke)
+		}
+		switch {
+		case err != nil || !changed:
+		case grant.State == "GRANT" && !grant.WithGrantOption && state.WithGrantOption.ValueBool():
+			// GRANT keeps an existing grant option; only revoking it drops it.
+			err = r.ctx.Client.RevokeGrantOption(ctx, grant)
+			if err == nil && len(added) > 0 {
+				grant.Columns = added
+				_, err = r.ctx.Client.GrantPermission(ctx, grant)
+			}
+		default:
+			_, err = r.ctx.Client.GrantPermission(ctx, grant)
+		}
+		if err != nil {
+			resp.Diagnostics.AddError(
+				fmt.Sprintf("Error changing permission %s of principal %s to %s", grant.Permission, grant.Principal, grant.State),
+				err.Error())
+			return
+		}
+	}
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *MssqlGrantResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	var data MssqlGrantResourceModel
 
 	// Read Terraform prior state data into the model
@@ -369,6 +577,9 @@ func (r *MssqlGrantResource) Delete(ctx context.Context, req resource.DeleteRequ
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_grant", data.Id.ValueString())
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
 	database := data.Database.ValueString()
 	if data.Database.IsUnknown() || data.Database.IsNull() || database == "" {
 		decoded, err := decodeGrantId(data.Id.ValueString())
@@ -391,21 +602,33 @@ func (r *MssqlGrantResource) Delete(ctx context.Context, req resource.DeleteRequ
 		}
 	}
 
+	columns := grantColumns(ctx, data.Columns, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 	grant := mssql.GrantPermission{
 		Database:   database,
 		Principal:  data.Principal.ValueString(),
 		Permission: strings.ToUpper(data.Permission.ValueString()),
 		ObjectType: strings.ToUpper(data.ObjectType.ValueString()),
 		ObjectName: data.ObjectName.ValueString(),
+		State:      data.State.ValueString(),
+		Columns:    columns,
 	}
 
+	defer r.ctx.LockDatabase(grant.Database)()
 	if err := r.ctx.Client.RevokePermission(ctx, grant); err != nil {
-		resp.Diagnostics.AddError("Unable to revoke permission", fmt.Sprintf("Unable to revoke permission %s from principal %s", data.Permission.ValueString(), data.Principal.ValueString()))
+		resp.Diagnostics.AddError("Unable to revoke permission",
+			fmt.Sprintf("Unable to revoke permission %s from principal %s, got error: %s", data.Permission.ValueString(), data.Principal.ValueString(), err))
 		return
 	}
 }
 
 func (r *MssqlGrantResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	grant, err := decodeGrantId(req.ID)
 	if err != nil {
 		resp.Diagnostics.AddError("Invalid import ID", err.Error())
@@ -425,5 +648,141 @@ func (r *MssqlGrantResource) ImportState(ctx context.Context, req resource.Impor
 	if grant.ObjectName != "" {
 		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("object_name"), grant.ObjectName)...)
 	}
+	if len(grant.Columns) > 0 {
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("columns"), grant.Columns)...)
+	}
 	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), grantToId(r.ctx.ServerID, grant))...)
 }
+
+// mssqlGrantResourceModelV1 is the model of schema versions 0 and 1, before
+// state, with_grant_option and columns were added.
+type mssqlGrantResourceModelV1 struct {
+	Id         types.String `tfsdk:"id"`
+	Database   types.String `tfsdk:"database"`
+	Permission types.String `tfsdk:"permission"`
+	Principal  types.String `tfsdk:"principal"`
+	ObjectType types.String `tfsdk:"object_type"`
+	ObjectName types.String `tfsdk:"object_name"`
+}
+
+func grantSchemaV1() schema.Schema {
+	return schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id":          schema.StringAttribute{Computed: true},
+			"database":    schema.StringAttribute{Optional: true, Computed: true},
+			"permission":  schema.StringAttribute{Required: true},
+			"principal":   schema.StringAttribute{Required: true},
+			"object_type": schema.StringAttribute{Optional: true},
+			"object_name": schema.StringAttribute{Optional: true},
+		},
+	}
+}
+
+// upgrade returns the prior grant with id, granted without the grant option
+// on the whole object, as every grant was before those attributes existed.
+func (data mssqlGrantResourceModelV1) upgrade(id string) MssqlGrantResourceModel {
+	return MssqlGrantResourceModel{
+		Id:              types.StringValue(id),
+		Database:        data.Database,
+		Permission:      data.Permission,
+		Principal:       data.Principal,
+		ObjectType:      data.ObjectType,
+		ObjectName:      data.ObjectName,
+		State:           types.StringValue("GRANT"),
+		WithGrantOption: types.BoolValue(false),
+		Columns:         types.SetNull(types.StringType),
+	}
+}
+
+func (r *MssqlGrantResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: upgradeModel(grantSchemaV1(), func(data *mssqlGrantResourceModelV1) (MssqlGrantResourceModel, error) {
+			server, err := upgradeServerId(r.ctx, data.Id.ValueString(), true)
+			if err != nil {
+				return MssqlGrantResourceModel{}, err
+			}
+			return data.upgrade(grantToId(server, mssql.GrantPermission{
+				Database:   data.Database.ValueString(),
+				Principal:  data.Principal.ValueString(),
+				Permission: data.Permission.ValueString(),
+				ObjectType: data.ObjectType.ValueString(),
+				ObjectName: data.ObjectName.ValueString(),
+			})), nil
+		}),
+		1: upgradeModel(grantSchemaV1(), func(data *mssqlGrantResourceModelV1) (MssqlGrantResourceModel, error) {
+			server, err := upgradeServerId(r.ctx, data.Id.ValueString(), false)
+			if err != nil {
+				return MssqlGrantResourceModel{}, err
+			}
+			_, rest, _ := strings.Cut(data.Id.ValueString(), "/")
+			return data.upgrade(formatId(server) + "/" + rest), nil
+		}),
+	}
+}
+
+func (r *MssqlGrantResource) MoveState(ctx context.Context) []resource.StateMover {
+	return []resource.StateMover{
+		{StateMover: r.movePgssoftPermission},
+	}
+}
+
+// pgssoftPermissionState is the state of a PGSSoft/mssql
+// mssql_database_permission or mssql_schema_permission. Principal and schema
+// ids are <database_id>/<principal_id> and <database_id>/<schema_id>.
+type pgssoftPermissionState struct {
+	PrincipalId     string `json:"principal_id"`
+	SchemaId        string `json:"schema_id"`
+	Permission      string `json:"permission"`
+	WithGrantOption bool   `json:"with_grant_option"`
+}
+
+func (r *MssqlGrantResource) movePgssoftPermission(ctx context.Context, req resource.MoveStateRequest, resp *resource.MoveStateResponse) {
+	onSchema := movingFrom(req, pgssoftProvider, "mssql_schema_permission")
+	if !onSchema && !movingFrom(req, pgssoftProvider, "mssql_database_permission") {
+		return
+	}
+
+	var source pgssoftPermissionState
+	if !decodeMovedState(req, &source, &resp.Diagnostics) || !requireMoveServer(r.ctx, &resp.Diagnostics) {
+		return
+	}
+	principalIds, err := parseCatalogIds(source.PrincipalId, 2, "<database_id>/<principal_id>")
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to Move Resource State", err.Error())
+		return
+	}
+
+	grant := mssql.GrantPermission{
+		Permission: strings.ToUpper(source.Permission),
+	}
+	grant.Database = lookupDatabase(ctx, r.ctx.Client, principalIds[0], &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	grant.Principal = lookupDatabasePrincipal(ctx, r.ctx.Client, grant.Database, principalIds[1], &resp.Diagnostics)
+	if onSchema {
+		schemaIds, err := parseCatalogIds(source.SchemaId, 2, "<database_id>/<schema_id>")
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to Move Resource State", err.Error())
+			return
+		}
+		grant.ObjectType = "SCHEMA"
+		grant.ObjectName = lookupSchema(ctx, r.ctx.Client, grant.Database, schemaIds[1], &resp.Diagnostics)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data := MssqlGrantResourceModel{
+		Id:              types.StringValue(grantToId(r.ctx.ServerID, grant)),
+		Database:        types.StringValue(grant.Database),
+		Permission:      types.StringValue(grant.Permission),
+		Principal:       types.StringValue(grant.Principal),
+		ObjectType:      stringOrNull(grant.ObjectType),
+		ObjectName:      stringOrNull(grant.ObjectName),
+		State:           types.StringValue("GRANT"),
+		WithGrantOption: types.BoolValue(source.WithGrantOption),
+		Columns:         types.SetNull(types.StringType),
+	}
+	resp.Diagnostics.Append(resp.TargetState.Set(ctx, &data)...)
+}
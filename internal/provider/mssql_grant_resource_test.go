@@ -85,6 +85,50 @@ func TestAccMssqlGrantResource_SchemaQualifiedObject(t *testing.T) {
 	})
 }
 
+func TestAccMssqlGrantResource_Deny(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testAccMssqlGrantDenyConfig("DENY"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("mssql_grant.deny_delete", "permission", "DELETE"),
+					resource.TestCheckResourceAttr("mssql_grant.deny_delete", "state", "DENY"),
+				),
+			},
+			// Flip the deny to a grant in place
+			{
+				Config: providerConfig + testAccMssqlGrantDenyConfig("GRANT"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("mssql_grant.deny_delete", "state", "GRANT"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccMssqlGrantResource_Certificate(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testAccMssqlGrantCertificateConfig(),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("mssql_grant.cert_view", "object_type", "CERTIFICATE"),
+					resource.TestCheckResourceAttr("mssql_grant.cert_view", "object_name", "grant_test_cert"),
+				),
+			},
+			{
+				ResourceName:      "mssql_grant.cert_view",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
 func testAccMssqlGrantDatabaseLevelConfig() string {
 	return `
 resource "mssql_user" "grant_test" {
@@ -170,3 +214,48 @@ resource "mssql_grant" "table_select" {
 }
 `
 }
+
+func testAccMssqlGrantDenyConfig(state string) string {
+	return fmt.Sprintf(`
+resource "mssql_user" "deny_user" {
+  database = "testdb"
+  username = "deny_user"
+  password = "DenyUserPassword123!@#"
+}
+
+resource "mssql_grant" "deny_delete" {
+  database   = "testdb"
+  permission = "DELETE"
+  principal  = mssql_user.deny_user.username
+  state      = %q
+}
+`, state)
+}
+
+func testAccMssqlGrantCertificateConfig() string {
+	return `
+resource "mssql_user" "cert_user" {
+  database = "testdb"
+  username = "cert_user"
+  password = "CertUserPassword123!@#"
+}
+
+resource "mssql_script" "grant_test_cert" {
+  database_name = "testdb"
+  name          = "grant_test_cert"
+  create_script = "IF NOT EXISTS (SELECT * FROM sys.certificates WHERE name = 'grant_test_cert') CREATE CERTIFICATE [grant_test_cert] ENCRYPTION BY PASSWORD = 'CertPassword123!@#' WITH SUBJECT = 'mssql_grant test'"
+  delete_script = "DROP CERTIFICATE IF EXISTS [grant_test_cert]"
+  version       = "v1"
+}
+
+resource "mssql_grant" "cert_view" {
+  database    = "testdb"
+  permission  = "VIEW DEFINITION"
+  principal   = mssql_user.cert_user.username
+  object_type = "CERTIFICATE"
+  object_name = "grant_test_cert"
+
+  depends_on = [mssql_script.grant_test_cert]
+}
+`
+}
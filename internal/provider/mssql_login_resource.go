@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"net/url"
 	"strings"
 
 	"github.com/hashicorp/terraform-plugin-framework/path"
@@ -18,12 +17,15 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/vsabella/terraform-provider-mssql/internal/core"
-	"github.com/vsabella/terraform-provider-mssql/internal/mssql"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &MssqlLoginResource{}
 var _ resource.ResourceWithImportState = &MssqlLoginResource{}
+var _ resource.ResourceWithUpgradeState = &MssqlLoginResource{}
+var _ resource.ResourceWithModifyPlan = &MssqlLoginResource{}
+var _ resource.ResourceWithMoveState = &MssqlLoginResource{}
 
 func NewMssqlLoginResource() resource.Resource {
 	return &MssqlLoginResource{}
@@ -70,11 +72,13 @@ func (r *MssqlLoginResource) Metadata(ctx context.Context, req resource.Metadata
 
 func (r *MssqlLoginResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Version: schemaVersion,
+
 		MarkdownDescription: "Manages a SQL Server login (server-level principal). Use this resource to create or adopt SQL authentication logins that can then be mapped to database users.",
 
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
-				MarkdownDescription: "Resource identifier in format `<server_id>/<login_name>` where `server_id` is `host:port`.",
+				MarkdownDescription: "Resource identifier in format `<server_id>/<login_name>` where `server_id` is the provider's `server_id`, by default `host:port`. Segments are URL path-escaped.",
 				Computed:            true,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
@@ -141,7 +145,38 @@ func (r *MssqlLoginResource) Configure(ctx context.Context, req resource.Configu
 	r.ctx = *client
 }
 
+func (r *MssqlLoginResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to check on destroy.
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data MssqlLoginResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if name, ok := known(data.Name); ok {
+		r.ctx.Planned.AddLogin(name)
+	}
+
+	if r.ctx.Server != nil {
+		validateLoginCapabilities(data, *r.ctx.Server, &resp.Diagnostics)
+	}
+
+	if refs, ok := checkReferences(ctx, r.ctx, req, &resp.Diagnostics); ok {
+		if database, ok := known(data.DefaultDatabase); ok {
+			refs.database(path.Root("default_database"), database)
+		}
+	}
+}
+
 func (r *MssqlLoginResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	var data MssqlLoginResourceModel
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -149,6 +184,9 @@ func (r *MssqlLoginResource) Create(ctx context.Context, req resource.CreateRequ
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_login", formatId(r.ctx.ServerID, data.Name.ValueString()))
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
 	create := mssql.CreateLogin{
 		Name:            data.Name.ValueString(),
 		Password:        data.Password.ValueString(),
@@ -182,6 +220,7 @@ func (r *MssqlLoginResource) Create(ctx context.Context, req resource.CreateRequ
 		}
 	}
 
+	defer r.ctx.LockServer()()
 	login, err := r.ctx.Client.CreateLogin(ctx, create)
 	if err != nil {
 		resp.Diagnostics.AddError(fmt.Sprintf("Error creating login %s", create.Name), err.Error())
@@ -195,7 +234,7 @@ func (r *MssqlLoginResource) Create(ctx context.Context, req resource.CreateRequ
 }
 
 func loginToResourceWithServer(data *MssqlLoginResourceModel, login mssql.Login, serverID string) {
-	data.Id = types.StringValue(fmt.Sprintf("%s/%s", serverID, login.Name))
+	data.Id = types.StringValue(formatId(serverID, login.Name))
 	data.Name = types.StringValue(login.Name)
 	data.DefaultDatabase = types.StringValue(login.DefaultDatabase)
 	if login.DefaultLanguage != "" {
@@ -209,18 +248,19 @@ func loginToResourceWithServer(data *MssqlLoginResourceModel, login mssql.Login,
 }
 
 func parseLoginId(id string) (string, error) {
-	parts := strings.SplitN(id, "/", 2)
-	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-		return "", fmt.Errorf("expected id in format <server_id>/<login_name>, got %q", id)
-	}
-	loginName, err := url.QueryUnescape(parts[1])
+	parts, err := parseId(id, 2, "<server_id>/<login_name>")
 	if err != nil {
 		return "", err
 	}
-	return loginName, nil
+	return parts[1], nil
 }
 
 func (r *MssqlLoginResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Without a reachable server there is nothing to refresh; keep the prior state.
+	if r.ctx.Unconfigured {
+		return
+	}
+
 	var data MssqlLoginResourceModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -228,6 +268,8 @@ func (r *MssqlLoginResource) Read(ctx context.Context, req resource.ReadRequest,
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_login", data.Id.ValueString())
+
 	loginName, err := parseLoginId(data.Id.ValueString())
 	if err != nil {
 		resp.Diagnostics.AddError("Invalid login ID", err.Error())
@@ -250,6 +292,10 @@ func (r *MssqlLoginResource) Read(ctx context.Context, req resource.ReadRequest,
 }
 
 func (r *MssqlLoginResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	var data MssqlLoginResourceModel
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -257,6 +303,9 @@ func (r *MssqlLoginResource) Update(ctx context.Context, req resource.UpdateRequ
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_login", data.Id.ValueString())
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
 	update := mssql.UpdateLogin{
 		Name:            data.Name.ValueString(),
 		Password:        data.Password.ValueString(),
@@ -264,6 +313,7 @@ func (r *MssqlLoginResource) Update(ctx context.Context, req resource.UpdateRequ
 		DefaultLanguage: data.DefaultLanguage.ValueString(),
 	}
 
+	defer r.ctx.LockServer()()
 	login, err := r.ctx.Client.UpdateLogin(ctx, update)
 	if err != nil {
 		resp.Diagnostics.AddError("Could not update login", err.Error())
@@ -277,6 +327,10 @@ func (r *MssqlLoginResource) Update(ctx context.Context, req resource.UpdateRequ
 }
 
 func (r *MssqlLoginResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	var data MssqlLoginResourceModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -284,11 +338,15 @@ func (r *MssqlLoginResource) Delete(ctx context.Context, req resource.DeleteRequ
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_login", data.Id.ValueString())
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
 	loginName, err := parseLoginId(data.Id.ValueString())
 	if err != nil {
 		resp.Diagnostics.AddError("Invalid login ID", err.Error())
 		return
 	}
+	defer r.ctx.LockServer()()
 	if err := r.ctx.Client.DeleteLogin(ctx, loginName); err != nil {
 		resp.Diagnostics.AddError("Unable to delete login", fmt.Sprintf("Unable to delete login %s, got error: %s", data.Name.ValueString(), err))
 		return
@@ -296,6 +354,12 @@ func (r *MssqlLoginResource) Delete(ctx context.Context, req resource.DeleteRequ
 }
 
 func (r *MssqlLoginResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
+	ctx = mssql.WithResource(ctx, "mssql_login", req.ID)
+
 	// Import ID: <server_id>/<login_name>
 	loginName, err := parseLoginId(req.ID)
 	if err != nil {
@@ -309,7 +373,7 @@ func (r *MssqlLoginResource) ImportState(ctx context.Context, req resource.Impor
 		return
 	}
 
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), fmt.Sprintf("%s/%s", r.ctx.ServerID, login.Name))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), formatId(r.ctx.ServerID, login.Name))...)
 	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), login.Name)...)
 	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("default_database"), login.DefaultDatabase)...)
 	if login.DefaultLanguage != "" {
@@ -326,3 +390,103 @@ func (r *MssqlLoginResource) ImportState(ctx context.Context, req resource.Impor
 		"The login password cannot be read from the server. You must set the password attribute in your configuration. The next apply will update the password.",
 	)
 }
+
+func (r *MssqlLoginResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: upgradeFromV0(currentSchema(ctx, r), func(data *MssqlLoginResourceModel) error {
+			server, err := upgradeServerId(r.ctx, data.Id.ValueString(), false)
+			if err != nil {
+				return err
+			}
+			data.Id = types.StringValue(formatId(server, data.Name.ValueString()))
+			return nil
+		}),
+		1: upgradeFromV1(currentSchema(ctx, r), r.ctx),
+	}
+}
+
+func (r *MssqlLoginResource) MoveState(ctx context.Context) []resource.StateMover {
+	return []resource.StateMover{
+		{StateMover: r.moveBetrLogin},
+		{StateMover: r.movePgssoftLogin},
+	}
+}
+
+// betrLoginState is the state of a betr-io/mssql mssql_login.
+type betrLoginState struct {
+	Id              string `json:"id"`
+	LoginName       string `json:"login_name"`
+	Password        string `json:"password"`
+	Sid             string `json:"sid"`
+	DefaultDatabase string `json:"default_database"`
+	DefaultLanguage string `json:"default_language"`
+}
+
+func (r *MssqlLoginResource) moveBetrLogin(ctx context.Context, req resource.MoveStateRequest, resp *resource.MoveStateResponse) {
+	if !movingFrom(req, betrProvider, "mssql_login") {
+		return
+	}
+
+	var source betrLoginState
+	if !decodeMovedState(req, &source, &resp.Diagnostics) || !requireMoveServer(r.ctx, &resp.Diagnostics) {
+		return
+	}
+	checkBetrServer(source.Id, r.ctx.ServerID, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data := MssqlLoginResourceModel{
+		Id:              types.StringValue(formatId(r.ctx.ServerID, source.LoginName)),
+		Name:            types.StringValue(source.LoginName),
+		Password:        types.StringValue(source.Password),
+		DefaultDatabase: stringOrNull(source.DefaultDatabase),
+		DefaultLanguage: stringOrNull(source.DefaultLanguage),
+		Sid:             stringOrNull(source.Sid),
+		AutoImport:      types.BoolValue(false),
+	}
+	resp.Diagnostics.Append(resp.TargetState.Set(ctx, &data)...)
+}
+
+// pgssoftLoginState is the state of a PGSSoft/mssql mssql_sql_login, whose
+// id is the login SID.
+type pgssoftLoginState struct {
+	Id                string `json:"id"`
+	Name              string `json:"name"`
+	Password          string `json:"password"`
+	DefaultDatabaseId string `json:"default_database_id"`
+	DefaultLanguage   string `json:"default_language"`
+}
+
+func (r *MssqlLoginResource) movePgssoftLogin(ctx context.Context, req resource.MoveStateRequest, resp *resource.MoveStateResponse) {
+	if !movingFrom(req, pgssoftProvider, "mssql_sql_login") {
+		return
+	}
+
+	var source pgssoftLoginState
+	if !decodeMovedState(req, &source, &resp.Diagnostics) || !requireMoveServer(r.ctx, &resp.Diagnostics) {
+		return
+	}
+
+	data := MssqlLoginResourceModel{
+		Id:              types.StringValue(formatId(r.ctx.ServerID, source.Name)),
+		Name:            types.StringValue(source.Name),
+		Password:        types.StringValue(source.Password),
+		DefaultDatabase: types.StringNull(),
+		DefaultLanguage: stringOrNull(source.DefaultLanguage),
+		Sid:             stringOrNull(source.Id),
+		AutoImport:      types.BoolValue(false),
+	}
+	if source.DefaultDatabaseId != "" {
+		ids, err := parseCatalogIds(source.DefaultDatabaseId, 1, "<database_id>")
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to Move Resource State", err.Error())
+			return
+		}
+		data.DefaultDatabase = types.StringValue(lookupDatabase(ctx, r.ctx.Client, ids[0], &resp.Diagnostics))
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	resp.Diagnostics.Append(resp.TargetState.Set(ctx, &data)...)
+}
@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"net/url"
 	"strings"
 
 	"github.com/hashicorp/terraform-plugin-framework/path"
@@ -18,12 +17,15 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/vsabella/terraform-provider-mssql/internal/core"
-	"github.com/vsabella/terraform-provider-mssql/internal/mssql"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &MssqlRoleAssignmentResource{}
+var _ resource.ResourceWithModifyPlan = &MssqlRoleAssignmentResource{}
 var _ resource.ResourceWithImportState = &MssqlRoleAssignmentResource{}
+var _ resource.ResourceWithUpgradeState = &MssqlRoleAssignmentResource{}
+var _ resource.ResourceWithMoveState = &MssqlRoleAssignmentResource{}
 
 func NewMssqlRoleAssignmentResource() resource.Resource {
 	return &MssqlRoleAssignmentResource{}
@@ -47,6 +49,8 @@ func (r *MssqlRoleAssignmentResource) Metadata(ctx context.Context, req resource
 
 func (r *MssqlRoleAssignmentResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Version: schemaVersion,
+
 		// This description is used by the documentation generator and the language server.
 		MarkdownDescription: `Assigns a principal to a database role or server role.
 
@@ -70,7 +74,7 @@ resource "mssql_role_assignment" "telemetry_state_reader" {
 
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
-				MarkdownDescription: "Resource identifier in format `<server_id>/db/<database>/<role>/<principal>` or `<server_id>/server/<role>/<principal>` where `server_id` is `host:port`.",
+				MarkdownDescription: "Resource identifier in format `<server_id>/db/<database>/<role>/<principal>` or `<server_id>/server/<role>/<principal>` where `server_id` is the provider's `server_id`, by default `host:port`. Segments are URL path-escaped.",
 				Computed:            true,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
@@ -131,7 +135,40 @@ func (r *MssqlRoleAssignmentResource) Configure(ctx context.Context, req resourc
 	r.ctx = *client
 }
 
+func (r *MssqlRoleAssignmentResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	refs, ok := checkReferences(ctx, r.ctx, req, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+
+	var data MssqlRoleAssignmentResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.ServerRole.IsUnknown() {
+		return
+	}
+	if data.ServerRole.ValueBool() {
+		refs.serverPrincipal(path.Root("role"), "Server Role", data.Role)
+		refs.serverPrincipal(path.Root("principal"), "Server Principal", data.Principal)
+		return
+	}
+
+	database, ok := plannedDatabase(r.ctx, data.Database)
+	if !ok || !refs.database(path.Root("database"), database) {
+		return
+	}
+	refs.databaseRole(path.Root("role"), database, data.Role)
+	refs.databasePrincipal(path.Root("principal"), database, data.Principal)
+}
+
 func (r *MssqlRoleAssignmentResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	var data MssqlRoleAssignmentResourceModel
 
 	// Read Terraform plan data into the model
@@ -142,30 +179,39 @@ func (r *MssqlRoleAssignmentResource) Create(ctx context.Context, req resource.C
 	}
 
 	isServer := data.ServerRole.ValueBool()
+	database := data.Database.ValueString()
+	if !isServer && (data.Database.IsUnknown() || data.Database.IsNull() || database == "") {
+		database = r.ctx.Database
+		data.Database = types.StringValue(database)
+	}
+
+	id := formatId(r.ctx.ServerID, "db", database, data.Role.ValueString(), data.Principal.ValueString())
+	if isServer {
+		id = formatId(r.ctx.ServerID, "server", data.Role.ValueString(), data.Principal.ValueString())
+	}
+	ctx = mssql.WithResource(ctx, "mssql_role_assignment", id)
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
 
 	var membership mssql.RoleMembership
 	var err error
 
 	if isServer {
+		defer r.ctx.LockServer()()
 		membership, err = r.ctx.Client.AssignServerRole(ctx, data.Role.ValueString(), data.Principal.ValueString())
 		if err != nil {
 			resp.Diagnostics.AddError(fmt.Sprintf("Error assigning server role %s to principal %s", data.Role.ValueString(), data.Principal.ValueString()), err.Error())
 			return
 		}
 		data.Database = types.StringNull()
-		data.Id = types.StringValue(fmt.Sprintf("%s/server/%s/%s", r.ctx.ServerID, membership.Role, membership.Member))
+		data.Id = types.StringValue(formatId(r.ctx.ServerID, "server", membership.Role, membership.Member))
 	} else {
-		database := data.Database.ValueString()
-		if data.Database.IsUnknown() || data.Database.IsNull() || database == "" {
-			database = r.ctx.Database
-			data.Database = types.StringValue(database)
-		}
+		defer r.ctx.LockDatabase(database)()
 		membership, err = r.ctx.Client.AssignRole(ctx, database, data.Role.ValueString(), data.Principal.ValueString())
 		if err != nil {
 			resp.Diagnostics.AddError(fmt.Sprintf("Error assigning role %s to principal %s", data.Role.ValueString(), data.Principal.ValueString()), err.Error())
 			return
 		}
-		data.Id = types.StringValue(fmt.Sprintf("%s/db/%s/%s/%s", r.ctx.ServerID, database, membership.Role, membership.Member))
+		data.Id = types.StringValue(formatId(r.ctx.ServerID, "db", database, membership.Role, membership.Member))
 	}
 
 	data.Role = types.StringValue(membership.Role)
@@ -178,6 +224,11 @@ func (r *MssqlRoleAssignmentResource) Create(ctx context.Context, req resource.C
 }
 
 func (r *MssqlRoleAssignmentResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Without a reachable server there is nothing to refresh; keep the prior state.
+	if r.ctx.Unconfigured {
+		return
+	}
+
 	var data MssqlRoleAssignmentResourceModel
 
 	// Read Terraform prior state data into the model
@@ -186,6 +237,8 @@ func (r *MssqlRoleAssignmentResource) Read(ctx context.Context, req resource.Rea
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_role_assignment", data.Id.ValueString())
+
 	id := data.Id.ValueString()
 	isServer := data.ServerRole.ValueBool()
 
@@ -230,10 +283,10 @@ func (r *MssqlRoleAssignmentResource) Read(ctx context.Context, req resource.Rea
 	}
 
 	if isServer {
-		data.Id = types.StringValue(fmt.Sprintf("%s/server/%s/%s", r.ctx.ServerID, membership.Role, membership.Member))
+		data.Id = types.StringValue(formatId(r.ctx.ServerID, "server", membership.Role, membership.Member))
 		data.Database = types.StringNull()
 	} else {
-		data.Id = types.StringValue(fmt.Sprintf("%s/db/%s/%s/%s", r.ctx.ServerID, data.Database.ValueString(), membership.Role, membership.Member))
+		data.Id = types.StringValue(formatId(r.ctx.ServerID, "db", data.Database.ValueString(), membership.Role, membership.Member))
 	}
 	data.Role = types.StringValue(membership.Role)
 	data.Principal = types.StringValue(membership.Member)
@@ -254,6 +307,10 @@ func (r *MssqlRoleAssignmentResource) Update(ctx context.Context, req resource.U
 }
 
 func (r *MssqlRoleAssignmentResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	var data MssqlRoleAssignmentResourceModel
 
 	// Read Terraform prior state data into the model
@@ -262,6 +319,9 @@ func (r *MssqlRoleAssignmentResource) Delete(ctx context.Context, req resource.D
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_role_assignment", data.Id.ValueString())
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
 	id := data.Id.ValueString()
 	isServer := data.ServerRole.ValueBool()
 
@@ -282,6 +342,7 @@ func (r *MssqlRoleAssignmentResource) Delete(ctx context.Context, req resource.D
 	}
 
 	if isServer {
+		defer r.ctx.LockServer()()
 		if err := r.ctx.Client.UnassignServerRole(ctx, data.Role.ValueString(), data.Principal.ValueString()); err != nil {
 			resp.Diagnostics.AddError("unable to unassign server role", fmt.Sprintf("unable to unassign server role %s from principal %s, got error: %s", data.Role.ValueString(), data.Principal.ValueString(), err))
 			return
@@ -294,6 +355,7 @@ func (r *MssqlRoleAssignmentResource) Delete(ctx context.Context, req resource.D
 		database = r.ctx.Database
 	}
 
+	defer r.ctx.LockDatabase(database)()
 	if err := r.ctx.Client.UnassignRole(ctx, database, data.Role.ValueString(), data.Principal.ValueString()); err != nil {
 		resp.Diagnostics.AddError("unable to unassign role", fmt.Sprintf("unable to unassign role %s from principal %s, got error: %s", data.Role.ValueString(), data.Principal.ValueString(), err))
 		return
@@ -301,6 +363,10 @@ func (r *MssqlRoleAssignmentResource) Delete(ctx context.Context, req resource.D
 }
 
 func (r *MssqlRoleAssignmentResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	// Import ID must be:
 	// - <server_id>/db/<database>/<role>/<principal>
 	// - <server_id>/server/<role>/<principal>
@@ -318,10 +384,28 @@ func (r *MssqlRoleAssignmentResource) ImportState(ctx context.Context, req resou
 	}
 
 	if parsed.IsServer {
-		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), fmt.Sprintf("%s/server/%s/%s", r.ctx.ServerID, parsed.Role, parsed.Principal))...)
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), formatId(r.ctx.ServerID, "server", parsed.Role, parsed.Principal))...)
 		return
 	}
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), fmt.Sprintf("%s/db/%s/%s/%s", r.ctx.ServerID, parsed.Database, parsed.Role, parsed.Principal))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), formatId(r.ctx.ServerID, "db", parsed.Database, parsed.Role, parsed.Principal))...)
+}
+
+func (r *MssqlRoleAssignmentResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: upgradeFromV0(currentSchema(ctx, r), func(data *MssqlRoleAssignmentResourceModel) error {
+			server, err := upgradeServerId(r.ctx, data.Id.ValueString(), false)
+			if err != nil {
+				return err
+			}
+			if data.ServerRole.ValueBool() {
+				data.Id = types.StringValue(formatId(server, "server", data.Role.ValueString(), data.Principal.ValueString()))
+			} else {
+				data.Id = types.StringValue(formatId(server, "db", data.Database.ValueString(), data.Role.ValueString(), data.Principal.ValueString()))
+			}
+			return nil
+		}),
+		1: upgradeFromV1(currentSchema(ctx, r), r.ctx),
+	}
 }
 
 type roleAssignmentId struct {
@@ -332,49 +416,116 @@ type roleAssignmentId struct {
 }
 
 func parseRoleAssignmentId(id string) (roleAssignmentId, error) {
+	const format = "<server_id>/db/<database>/<role>/<principal> or <server_id>/server/<role>/<principal>"
 	parts := strings.Split(id, "/")
-	if len(parts) < 4 || parts[0] == "" {
-		return roleAssignmentId{}, fmt.Errorf("expected id in format <server_id>/db/<database>/<role>/<principal> or <server_id>/server/<role>/<principal>, got %q", id)
+	if len(parts) < 2 {
+		return roleAssignmentId{}, fmt.Errorf("expected id in format %s, got %q", format, id)
 	}
-	scope := parts[1]
-	switch scope {
+	switch parts[1] {
 	case "server":
-		if len(parts) != 4 {
-			return roleAssignmentId{}, fmt.Errorf("expected id in format <server_id>/server/<role>/<principal>, got %q", id)
-		}
-		role, err := url.QueryUnescape(parts[2])
-		if err != nil {
-			return roleAssignmentId{}, err
-		}
-		principal, err := url.QueryUnescape(parts[3])
+		parts, err := parseId(id, 4, "<server_id>/server/<role>/<principal>")
 		if err != nil {
 			return roleAssignmentId{}, err
 		}
-		if role == "" || principal == "" {
-			return roleAssignmentId{}, fmt.Errorf("expected id in format <server_id>/server/<role>/<principal>, got %q", id)
-		}
-		return roleAssignmentId{IsServer: true, Role: role, Principal: principal}, nil
+		return roleAssignmentId{IsServer: true, Role: parts[2], Principal: parts[3]}, nil
 	case "db":
-		if len(parts) != 5 {
-			return roleAssignmentId{}, fmt.Errorf("expected id in format <server_id>/db/<database>/<role>/<principal>, got %q", id)
-		}
-		db, err := url.QueryUnescape(parts[2])
+		parts, err := parseId(id, 5, "<server_id>/db/<database>/<role>/<principal>")
 		if err != nil {
 			return roleAssignmentId{}, err
 		}
-		role, err := url.QueryUnescape(parts[3])
-		if err != nil {
-			return roleAssignmentId{}, err
-		}
-		principal, err := url.QueryUnescape(parts[4])
-		if err != nil {
-			return roleAssignmentId{}, err
-		}
-		if db == "" || role == "" || principal == "" {
-			return roleAssignmentId{}, fmt.Errorf("expected id in format <server_id>/db/<database>/<role>/<principal>, got %q", id)
-		}
-		return roleAssignmentId{IsServer: false, Database: db, Role: role, Principal: principal}, nil
+		return roleAssignmentId{IsServer: false, Database: parts[2], Role: parts[3], Principal: parts[4]}, nil
 	default:
-		return roleAssignmentId{}, fmt.Errorf("expected id in format <server_id>/db/<database>/<role>/<principal> or <server_id>/server/<role>/<principal>, got %q", id)
+		return roleAssignmentId{}, fmt.Errorf("expected id in format %s, got %q", format, id)
+	}
+}
+
+func (r *MssqlRoleAssignmentResource) MoveState(ctx context.Context) []resource.StateMover {
+	return []resource.StateMover{
+		{StateMover: r.movePgssoftDatabaseRoleMember},
+		{StateMover: r.movePgssoftServerRoleMember},
+	}
+}
+
+// pgssoftRoleMemberState is the state of a PGSSoft/mssql
+// mssql_database_role_member, whose ids are <database_id>/<principal_id>, or
+// mssql_server_role_member, whose ids are <principal_id>.
+type pgssoftRoleMemberState struct {
+	RoleId   string `json:"role_id"`
+	MemberId string `json:"member_id"`
+}
+
+func (r *MssqlRoleAssignmentResource) movePgssoftDatabaseRoleMember(ctx context.Context, req resource.MoveStateRequest, resp *resource.MoveStateResponse) {
+	if !movingFrom(req, pgssoftProvider, "mssql_database_role_member") {
+		return
+	}
+
+	var source pgssoftRoleMemberState
+	if !decodeMovedState(req, &source, &resp.Diagnostics) || !requireMoveServer(r.ctx, &resp.Diagnostics) {
+		return
+	}
+	roleIds, err := parseCatalogIds(source.RoleId, 2, "<database_id>/<principal_id>")
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to Move Resource State", err.Error())
+		return
+	}
+	memberIds, err := parseCatalogIds(source.MemberId, 2, "<database_id>/<principal_id>")
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to Move Resource State", err.Error())
+		return
+	}
+
+	database := lookupDatabase(ctx, r.ctx.Client, roleIds[0], &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	role := lookupDatabasePrincipal(ctx, r.ctx.Client, database, roleIds[1], &resp.Diagnostics)
+	member := lookupDatabasePrincipal(ctx, r.ctx.Client, database, memberIds[1], &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data := MssqlRoleAssignmentResourceModel{
+		Id:         types.StringValue(formatId(r.ctx.ServerID, "db", database, role, member)),
+		Database:   types.StringValue(database),
+		Role:       types.StringValue(role),
+		Principal:  types.StringValue(member),
+		ServerRole: types.BoolValue(false),
+	}
+	resp.Diagnostics.Append(resp.TargetState.Set(ctx, &data)...)
+}
+
+func (r *MssqlRoleAssignmentResource) movePgssoftServerRoleMember(ctx context.Context, req resource.MoveStateRequest, resp *resource.MoveStateResponse) {
+	if !movingFrom(req, pgssoftProvider, "mssql_server_role_member") {
+		return
+	}
+
+	var source pgssoftRoleMemberState
+	if !decodeMovedState(req, &source, &resp.Diagnostics) || !requireMoveServer(r.ctx, &resp.Diagnostics) {
+		return
+	}
+	roleIds, err := parseCatalogIds(source.RoleId, 1, "<principal_id>")
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to Move Resource State", err.Error())
+		return
+	}
+	memberIds, err := parseCatalogIds(source.MemberId, 1, "<principal_id>")
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to Move Resource State", err.Error())
+		return
+	}
+
+	role := lookupServerPrincipal(ctx, r.ctx.Client, roleIds[0], &resp.Diagnostics)
+	member := lookupServerPrincipal(ctx, r.ctx.Client, memberIds[0], &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data := MssqlRoleAssignmentResourceModel{
+		Id:         types.StringValue(formatId(r.ctx.ServerID, "server", role, member)),
+		Database:   types.StringNull(),
+		Role:       types.StringValue(role),
+		Principal:  types.StringValue(member),
+		ServerRole: types.BoolValue(true),
 	}
+	resp.Diagnostics.Append(resp.TargetState.Set(ctx, &data)...)
 }
@@ -3,8 +3,6 @@ package provider
 import (
 	"context"
 	"fmt"
-	"net/url"
-	"strings"
 
 	"database/sql"
 	"errors"
@@ -18,11 +16,15 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/vsabella/terraform-provider-mssql/internal/core"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &MssqlRoleResource{}
+var _ resource.ResourceWithModifyPlan = &MssqlRoleResource{}
 var _ resource.ResourceWithImportState = &MssqlRoleResource{}
+var _ resource.ResourceWithUpgradeState = &MssqlRoleResource{}
+var _ resource.ResourceWithMoveState = &MssqlRoleResource{}
 
 func NewMssqlRoleResource() resource.Resource {
 	return &MssqlRoleResource{}
@@ -45,12 +47,14 @@ func (r *MssqlRoleResource) Metadata(ctx context.Context, req resource.MetadataR
 
 func (r *MssqlRoleResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Version: schemaVersion,
+
 		// This description is used by the documentation generator and the language server.
 		MarkdownDescription: "MssqlRole resource",
 
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
-				MarkdownDescription: "Resource identifier in format `<server_id>/<database>/<role>` where `server_id` is `host:port`.",
+				MarkdownDescription: "Resource identifier in format `<server_id>/<database>/<role>` where `server_id` is the provider's `server_id`, by default `host:port`. Segments are URL path-escaped.",
 				Computed:            true,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
@@ -94,7 +98,28 @@ func (r *MssqlRoleResource) Configure(ctx context.Context, req resource.Configur
 	r.ctx = *client
 }
 
+func (r *MssqlRoleResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data MssqlRoleResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	database, databaseKnown := plannedDatabase(r.ctx, data.Database)
+	if name, ok := known(data.Name); ok && databaseKnown {
+		r.ctx.Planned.AddPrincipal(database, name)
+	}
+}
+
 func (r *MssqlRoleResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	var data MssqlRoleResourceModel
 
 	// Read Terraform plan data into the model
@@ -110,13 +135,17 @@ func (r *MssqlRoleResource) Create(ctx context.Context, req resource.CreateReque
 		data.Database = types.StringValue(database)
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_role", formatId(r.ctx.ServerID, database, data.Name.ValueString()))
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
+	defer r.ctx.LockDatabase(database)()
 	role, err := r.ctx.Client.CreateRole(ctx, database, data.Name.ValueString())
 	if err != nil {
 		resp.Diagnostics.AddError(fmt.Sprintf("Error creating role %s", data.Id.ValueString()), err.Error())
 		return
 	}
 
-	data.Id = types.StringValue(fmt.Sprintf("%s/%s/%s", r.ctx.ServerID, database, role.Name))
+	data.Id = types.StringValue(formatId(r.ctx.ServerID, database, role.Name))
 	data.Name = types.StringValue(role.Name)
 	tflog.Debug(ctx, fmt.Sprintf("Created role %s", data.Id))
 
@@ -136,6 +165,11 @@ func (r *MssqlRoleResource) Update(ctx context.Context, req resource.UpdateReque
 }
 
 func (r *MssqlRoleResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Without a reachable server there is nothing to refresh; keep the prior state.
+	if r.ctx.Unconfigured {
+		return
+	}
+
 	var data MssqlRoleResourceModel
 
 	// Read Terraform prior state data into the model
@@ -144,6 +178,8 @@ func (r *MssqlRoleResource) Read(ctx context.Context, req resource.ReadRequest,
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_role", data.Id.ValueString())
+
 	database := data.Database.ValueString()
 	if data.Database.IsUnknown() || data.Database.IsNull() || database == "" || data.Name.IsNull() || data.Name.ValueString() == "" {
 		dbName, roleName, err := parseRoleId(data.Id.ValueString())
@@ -166,13 +202,17 @@ func (r *MssqlRoleResource) Read(ctx context.Context, req resource.ReadRequest,
 		return
 	}
 
-	data.Id = types.StringValue(fmt.Sprintf("%s/%s/%s", r.ctx.ServerID, database, role.Name))
+	data.Id = types.StringValue(formatId(r.ctx.ServerID, database, role.Name))
 	data.Database = types.StringValue(database)
 	data.Name = types.StringValue(role.Name)
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *MssqlRoleResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	var data MssqlRoleResourceModel
 
 	// Read Terraform prior state data into the model
@@ -181,6 +221,9 @@ func (r *MssqlRoleResource) Delete(ctx context.Context, req resource.DeleteReque
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_role", data.Id.ValueString())
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
 	database := data.Database.ValueString()
 	if data.Database.IsUnknown() || data.Database.IsNull() || database == "" || data.Name.IsNull() || data.Name.ValueString() == "" {
 		dbName, roleName, err := parseRoleId(data.Id.ValueString())
@@ -192,6 +235,7 @@ func (r *MssqlRoleResource) Delete(ctx context.Context, req resource.DeleteReque
 		data.Name = types.StringValue(roleName)
 	}
 
+	defer r.ctx.LockDatabase(database)()
 	err := r.ctx.Client.DeleteRole(ctx, database, data.Name.ValueString())
 	if err != nil {
 		resp.Diagnostics.AddError("unable to delete role", fmt.Sprintf("unable to delete role %s, got error: %s", data.Id.ValueString(), err))
@@ -200,6 +244,10 @@ func (r *MssqlRoleResource) Delete(ctx context.Context, req resource.DeleteReque
 }
 
 func (r *MssqlRoleResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	// Import ID must be <server_id>/<database>/<role>
 	database, name, err := parseRoleId(req.ID)
 	if err != nil {
@@ -209,21 +257,66 @@ func (r *MssqlRoleResource) ImportState(ctx context.Context, req resource.Import
 
 	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("database"), database)...)
 	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), name)...)
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), fmt.Sprintf("%s/%s/%s", r.ctx.ServerID, database, name))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), formatId(r.ctx.ServerID, database, name))...)
 }
 
-func parseRoleId(id string) (string, string, error) {
-	parts := strings.Split(id, "/")
-	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
-		return "", "", fmt.Errorf("expected id in format <server_id>/<database>/<role>, got %q", id)
+func (r *MssqlRoleResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: upgradeFromV0(currentSchema(ctx, r), func(data *MssqlRoleResourceModel) error {
+			server, err := upgradeServerId(r.ctx, data.Id.ValueString(), false)
+			if err != nil {
+				return err
+			}
+			data.Id = types.StringValue(formatId(server, data.Database.ValueString(), data.Name.ValueString()))
+			return nil
+		}),
+		1: upgradeFromV1(currentSchema(ctx, r), r.ctx),
 	}
-	db, err := url.QueryUnescape(parts[1])
+}
+
+func parseRoleId(id string) (string, string, error) {
+	parts, err := parseId(id, 3, "<server_id>/<database>/<role>")
 	if err != nil {
 		return "", "", err
 	}
-	name, err := url.QueryUnescape(parts[2])
+	return parts[1], parts[2], nil
+}
+
+func (r *MssqlRoleResource) MoveState(ctx context.Context) []resource.StateMover {
+	return []resource.StateMover{
+		{StateMover: r.movePgssoftRole},
+	}
+}
+
+// pgssoftRoleState is the state of a PGSSoft/mssql mssql_database_role.
+type pgssoftRoleState struct {
+	Name       string `json:"name"`
+	DatabaseId string `json:"database_id"`
+}
+
+func (r *MssqlRoleResource) movePgssoftRole(ctx context.Context, req resource.MoveStateRequest, resp *resource.MoveStateResponse) {
+	if !movingFrom(req, pgssoftProvider, "mssql_database_role") {
+		return
+	}
+
+	var source pgssoftRoleState
+	if !decodeMovedState(req, &source, &resp.Diagnostics) || !requireMoveServer(r.ctx, &resp.Diagnostics) {
+		return
+	}
+	ids, err := parseCatalogIds(source.DatabaseId, 1, "<database_id>")
 	if err != nil {
-		return "", "", err
+		resp.Diagnostics.AddError("Unable to Move Resource State", err.Error())
+		return
+	}
+	database := lookupDatabase(ctx, r.ctx.Client, ids[0], &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data := MssqlRoleResourceModel{
+		Id:       types.StringValue(formatId(r.ctx.ServerID, database, source.Name)),
+		Database: types.StringValue(database),
+		Name:     types.StringValue(source.Name),
 	}
-	return db, name, nil
+	resp.Diagnostics.Append(resp.TargetState.Set(ctx, &data)...)
 }
@@ -3,8 +3,6 @@ package provider
 import (
 	"context"
 	"fmt"
-	"net/url"
-	"strings"
 
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -14,11 +12,14 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/vsabella/terraform-provider-mssql/internal/core"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &MssqlScriptResource{}
+var _ resource.ResourceWithModifyPlan = &MssqlScriptResource{}
 var _ resource.ResourceWithImportState = &MssqlScriptResource{}
+var _ resource.ResourceWithUpgradeState = &MssqlScriptResource{}
 
 func NewMssqlScriptResource() resource.Resource {
 	return &MssqlScriptResource{}
@@ -43,6 +44,8 @@ func (r *MssqlScriptResource) Metadata(ctx context.Context, req resource.Metadat
 
 func (r *MssqlScriptResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Version: schemaVersion,
+
 		MarkdownDescription: `Manages arbitrary SQL scripts with Terraform lifecycle tracking.
 
 Use this resource to install tools, run bootstrap scripts, or execute any SQL that needs to be managed as infrastructure.
@@ -64,7 +67,7 @@ resource "mssql_script" "first_responder_kit" {
 
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
-				MarkdownDescription: "Resource identifier in format `<server_id>/<database>/<name>` where `server_id` is `host:port`.",
+				MarkdownDescription: "Resource identifier in format `<server_id>/<database>/<name>` where `server_id` is the provider's `server_id`, by default `host:port`. Segments are URL path-escaped.",
 				Computed:            true,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
@@ -117,7 +120,28 @@ func (r *MssqlScriptResource) Configure(ctx context.Context, req resource.Config
 	r.ctx = *client
 }
 
+func (r *MssqlScriptResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data MssqlScriptResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The script may create objects that other resources refer to.
+	if database, ok := plannedDatabase(r.ctx, data.DatabaseName); ok {
+		r.ctx.Planned.AddScript(database)
+	}
+}
+
 func (r *MssqlScriptResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	var data MssqlScriptResourceModel
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -125,11 +149,15 @@ func (r *MssqlScriptResource) Create(ctx context.Context, req resource.CreateReq
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_script", formatId(r.ctx.ServerID, data.DatabaseName.ValueString(), data.Name.ValueString()))
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
 	resp.Diagnostics.AddWarning(
 		"Executing arbitrary SQL",
 		"The mssql_script resource executes the provided SQL as-is. Review scripts carefully and ensure they are idempotent and safe.",
 	)
 
+	defer r.ctx.LockDatabase(data.DatabaseName.ValueString())()
 	// Execute the create script
 	if err := r.ctx.Client.ExecScript(ctx, data.DatabaseName.ValueString(), data.CreateScript.ValueString()); err != nil {
 		resp.Diagnostics.AddError(
@@ -139,7 +167,7 @@ func (r *MssqlScriptResource) Create(ctx context.Context, req resource.CreateReq
 		return
 	}
 
-	data.Id = types.StringValue(fmt.Sprintf("%s/%s/%s", r.ctx.ServerID, data.DatabaseName.ValueString(), data.Name.ValueString()))
+	data.Id = types.StringValue(formatId(r.ctx.ServerID, data.DatabaseName.ValueString(), data.Name.ValueString()))
 	tflog.Debug(ctx, fmt.Sprintf("Executed script %s in database %s", data.Name.ValueString(), data.DatabaseName.ValueString()))
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -160,6 +188,10 @@ func (r *MssqlScriptResource) Read(ctx context.Context, req resource.ReadRequest
 }
 
 func (r *MssqlScriptResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	var plan, state MssqlScriptResourceModel
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
@@ -168,6 +200,9 @@ func (r *MssqlScriptResource) Update(ctx context.Context, req resource.UpdateReq
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_script", state.Id.ValueString())
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
 	// Re-execute on version change (in-place).
 	if !plan.Version.Equal(state.Version) {
 		resp.Diagnostics.AddWarning(
@@ -175,6 +210,7 @@ func (r *MssqlScriptResource) Update(ctx context.Context, req resource.UpdateReq
 			"The mssql_script resource executes the provided SQL as-is. Review scripts carefully and ensure they are idempotent and safe.",
 		)
 
+		defer r.ctx.LockDatabase(plan.DatabaseName.ValueString())()
 		if err := r.ctx.Client.ExecScript(ctx, plan.DatabaseName.ValueString(), plan.CreateScript.ValueString()); err != nil {
 			resp.Diagnostics.AddError(
 				fmt.Sprintf("Error executing script %s", plan.Name.ValueString()),
@@ -199,6 +235,10 @@ func (r *MssqlScriptResource) Update(ctx context.Context, req resource.UpdateReq
 }
 
 func (r *MssqlScriptResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	var data MssqlScriptResourceModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -206,8 +246,12 @@ func (r *MssqlScriptResource) Delete(ctx context.Context, req resource.DeleteReq
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_script", data.Id.ValueString())
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
 	// Execute delete script if provided
 	if !data.DeleteScript.IsNull() && data.DeleteScript.ValueString() != "" {
+		defer r.ctx.LockDatabase(data.DatabaseName.ValueString())()
 		if err := r.ctx.Client.ExecScript(ctx, data.DatabaseName.ValueString(), data.DeleteScript.ValueString()); err != nil {
 			// Log warning but don't fail - we still want to remove from state
 			tflog.Warn(ctx, fmt.Sprintf("Error executing delete script for %s: %v", data.Name.ValueString(), err))
@@ -224,6 +268,10 @@ func (r *MssqlScriptResource) Delete(ctx context.Context, req resource.DeleteReq
 }
 
 func (r *MssqlScriptResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	// Import ID must be <server_id>/<database>/<name>
 	database, name, err := parseScriptId(req.ID)
 	if err != nil {
@@ -233,21 +281,27 @@ func (r *MssqlScriptResource) ImportState(ctx context.Context, req resource.Impo
 
 	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("database_name"), database)...)
 	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), name)...)
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), fmt.Sprintf("%s/%s/%s", r.ctx.ServerID, database, name))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), formatId(r.ctx.ServerID, database, name))...)
 }
 
-func parseScriptId(id string) (string, string, error) {
-	parts := strings.Split(id, "/")
-	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
-		return "", "", fmt.Errorf("expected id in format <server_id>/<database>/<name>, got %q", id)
-	}
-	db, err := url.QueryUnescape(parts[1])
-	if err != nil {
-		return "", "", err
+func (r *MssqlScriptResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: upgradeFromV0(currentSchema(ctx, r), func(data *MssqlScriptResourceModel) error {
+			server, err := upgradeServerId(r.ctx, data.Id.ValueString(), false)
+			if err != nil {
+				return err
+			}
+			data.Id = types.StringValue(formatId(server, data.DatabaseName.ValueString(), data.Name.ValueString()))
+			return nil
+		}),
+		1: upgradeFromV1(currentSchema(ctx, r), r.ctx),
 	}
-	name, err := url.QueryUnescape(parts[2])
+}
+
+func parseScriptId(id string) (string, string, error) {
+	parts, err := parseId(id, 3, "<server_id>/<database>/<name>")
 	if err != nil {
 		return "", "", err
 	}
-	return db, name, nil
+	return parts[1], parts[2], nil
 }
@@ -0,0 +1,408 @@
+package provider
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/vsabella/terraform-provider-mssql/internal/core"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &MssqlServerPermissionResource{}
+var _ resource.ResourceWithModifyPlan = &MssqlServerPermissionResource{}
+var _ resource.ResourceWithValidateConfig = &MssqlServerPermissionResource{}
+var _ resource.ResourceWithImportState = &MssqlServerPermissionResource{}
+
+func NewMssqlServerPermissionResource() resource.Resource {
+	return &MssqlServerPermissionResource{}
+}
+
+type MssqlServerPermissionResource struct {
+	ctx core.ProviderData
+}
+
+type MssqlServerPermissionResourceModel struct {
+	Id              types.String `tfsdk:"id"`
+	Permission      types.String `tfsdk:"permission"`
+	Principal       types.String `tfsdk:"principal"`
+	ObjectType      types.String `tfsdk:"object_type"`
+	ObjectName      types.String `tfsdk:"object_name"`
+	State           types.String `tfsdk:"state"`
+	WithGrantOption types.Bool   `tfsdk:"with_grant_option"`
+}
+
+func serverPermissionToId(serverID string, permission mssql.ServerPermission) string {
+	segments := []string{serverID, permission.Principal, strings.ToUpper(permission.Permission)}
+	if permission.ObjectType != "" && permission.ObjectName != "" {
+		segments = append(segments, strings.ToUpper(permission.ObjectType), permission.ObjectName)
+	}
+	return formatId(segments...)
+}
+
+func decodeServerPermissionId(id string) (mssql.ServerPermission, error) {
+	const format = "<server_id>/<principal>/<permission>[/object_type/object_name]"
+	n := strings.Count(id, "/") + 1
+	if n != 3 && n != 5 {
+		return mssql.ServerPermission{}, fmt.Errorf("expected id in format %s, got %q", format, id)
+	}
+	parts, err := parseId(id, n, format)
+	if err != nil {
+		return mssql.ServerPermission{}, err
+	}
+
+	permission := mssql.ServerPermission{
+		Principal:  parts[1],
+		Permission: parts[2],
+	}
+	if n == 5 {
+		permission.ObjectType = parts[3]
+		permission.ObjectName = parts[4]
+	}
+	return permission, nil
+}
+
+// serverPermission returns the server permission of a model.
+func serverPermission(data MssqlServerPermissionResourceModel) mssql.ServerPermission {
+	return mssql.ServerPermission{
+		Principal:       data.Principal.ValueString(),
+		Permission:      strings.ToUpper(data.Permission.ValueString()),
+		ObjectType:      strings.ToUpper(data.ObjectType.ValueString()),
+		ObjectName:      data.ObjectName.ValueString(),
+		State:           data.State.ValueString(),
+		WithGrantOption: data.WithGrantOption.ValueBool(),
+	}
+}
+
+func (r *MssqlServerPermissionResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_server_permission"
+}
+
+func (r *MssqlServerPermissionResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: schemaVersion,
+
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: `Grants or denies server permissions to a login or server role.
+
+Supports permissions on the server (e.g., VIEW SERVER STATE or ALTER ANY LOGIN) and on a securable of the server (e.g., IMPERSONATE on a LOGIN). Use ` + "`mssql_grant`" + ` for database permissions.
+
+**Examples:**
+
+Server-level grant:
+` + "```hcl" + `
+resource "mssql_server_permission" "monitoring" {
+  permission = "VIEW SERVER STATE"
+  principal  = mssql_login.monitoring.name
+}
+` + "```" + `
+
+Impersonation of a login:
+` + "```hcl" + `
+resource "mssql_server_permission" "impersonate_etl" {
+  permission  = "IMPERSONATE"
+  principal   = "dba"
+  object_type = "LOGIN"
+  object_name = "etl"
+}
+` + "```" + `
+
+Deny on the server:
+` + "```hcl" + `
+resource "mssql_server_permission" "deny_connect_any" {
+  permission = "CONNECT ANY DATABASE"
+  principal  = "contractor"
+  state      = "DENY"
+}
+` + "```",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Resource identifier in format `<server_id>/<principal>/<permission>[/object_type/object_name]` where `server_id` is the provider's `server_id`, by default `host:port`. Segments are URL path-escaped.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"permission": schema.StringAttribute{
+				MarkdownDescription: "Server permission to grant (e.g., VIEW SERVER STATE, ALTER ANY LOGIN, CONNECT ANY DATABASE, IMPERSONATE).",
+				Required:            true,
+				Validators: []validator.String{
+					databasePermissionValidator{},
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"principal": schema.StringAttribute{
+				MarkdownDescription: "Login or server role to grant the permission to.",
+				Required:            true,
+				Validators: []validator.String{
+					principalNameValidator{},
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"object_type": schema.StringAttribute{
+				MarkdownDescription: "Type of server securable to grant the permission on: LOGIN, SERVER ROLE or ENDPOINT. If not specified, grants a permission on the server.",
+				Optional:            true,
+				Validators: []validator.String{
+					objectTypeValidator{types: mssql.ServerObjectTypes},
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"object_name": schema.StringAttribute{
+				MarkdownDescription: "Name of the login, server role or endpoint to grant the permission on. Required if `object_type` is specified.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"state": schema.StringAttribute{
+				MarkdownDescription: "`GRANT` or `DENY`. Changing it grants or denies the permission in place. Default: `GRANT`",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("GRANT"),
+				Validators: []validator.String{
+					stringOneOfValidator{values: []string{"GRANT", "DENY"}},
+				},
+			},
+			"with_grant_option": schema.BoolAttribute{
+				MarkdownDescription: "Let the principal grant the permission to others (`WITH GRANT OPTION`). Only valid when `state` is `GRANT`. Turning it off revokes the grant option in place, together with the permissions the principal granted through it. Default: `false`",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+		},
+	}
+}
+
+func (r *MssqlServerPermissionResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*core.ProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *core.ProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.ctx = *client
+}
+
+func (r *MssqlServerPermissionResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data MssqlServerPermissionResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if data.WithGrantOption.ValueBool() && data.State.ValueString() == "DENY" {
+		resp.Diagnostics.AddAttributeError(path.Root("with_grant_option"), "Invalid Grant Option",
+			"with_grant_option cannot be set when state is DENY.")
+	}
+	if !data.ObjectType.IsUnknown() && !data.ObjectName.IsUnknown() && data.ObjectType.IsNull() != data.ObjectName.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("object_name"), "Invalid Securable",
+			"Both 'object_type' and 'object_name' must be specified together, or neither.")
+	}
+}
+
+func (r *MssqlServerPermissionResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	refs, ok := checkReferences(ctx, r.ctx, req, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+
+	var data MssqlServerPermissionResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	refs.serverPrincipal(path.Root("principal"), "Server Principal", data.Principal)
+	switch strings.ToUpper(strings.TrimSpace(data.ObjectType.ValueString())) {
+	case "LOGIN":
+		refs.serverPrincipal(path.Root("object_name"), "Login", data.ObjectName)
+	case "SERVER ROLE":
+		refs.serverPrincipal(path.Root("object_name"), "Server Role", data.ObjectName)
+	}
+}
+
+func (r *MssqlServerPermissionResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
+	var data MssqlServerPermissionResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	permission := serverPermission(data)
+	ctx = mssql.WithResource(ctx, "mssql_server_permission", serverPermissionToId(r.ctx.ServerID, permission))
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
+	defer r.ctx.LockServer()()
+	result, err := r.ctx.Client.GrantServerPermission(ctx, permission)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			fmt.Sprintf("Error granting server permission %s to principal %s", permission.Permission, permission.Principal),
+			err.Error())
+		return
+	}
+
+	data.Id = types.StringValue(serverPermissionToId(r.ctx.ServerID, result))
+	data.Principal = types.StringValue(result.Principal)
+	data.Permission = types.StringValue(result.Permission)
+	data.State = types.StringValue(result.State)
+	data.WithGrantOption = types.BoolValue(result.WithGrantOption)
+	tflog.Debug(ctx, fmt.Sprintf("Granted server permission %s to principal %s (id: %s)", permission.Permission, permission.Principal, data.Id.ValueString()))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MssqlServerPermissionResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Without a reachable server there is nothing to refresh; keep the prior state.
+	if r.ctx.Unconfigured {
+		return
+	}
+
+	var data MssqlServerPermissionResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = mssql.WithResource(ctx, "mssql_server_permission", data.Id.ValueString())
+
+	perm, err := r.ctx.Client.ReadServerPermission(ctx, serverPermission(data))
+	if errors.Is(err, sql.ErrNoRows) {
+		resp.State.RemoveResource(ctx)
+		return
+	} else if err != nil {
+		resp.Diagnostics.AddError("Unable to read server permission", fmt.Sprintf("Error: %s", err))
+		return
+	}
+
+	data.Id = types.StringValue(serverPermissionToId(r.ctx.ServerID, perm))
+	data.Principal = types.StringValue(perm.Principal)
+	data.Permission = types.StringValue(perm.Permission)
+	data.State = types.StringValue(perm.State)
+	data.WithGrantOption = types.BoolValue(perm.WithGrantOption)
+	if perm.ObjectType != "" {
+		data.ObjectType = types.StringValue(perm.ObjectType)
+	}
+	if perm.ObjectName != "" {
+		data.ObjectName = types.StringValue(perm.ObjectName)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Update changes the state and grant option of the permission, the only
+// attributes that do not force replacement.
+func (r *MssqlServerPermissionResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state MssqlServerPermissionResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.State.Equal(state.State) || !data.WithGrantOption.Equal(state.WithGrantOption) {
+		if !r.ctx.RequireServer(&resp.Diagnostics) {
+			return
+		}
+		ctx = mssql.WithResource(ctx, "mssql_server_permission", data.Id.ValueString())
+		defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
+		permission := serverPermission(data)
+
+		defer r.ctx.LockServer()()
+		var err error
+		if permission.State == "GRANT" && !permission.WithGrantOption && state.WithGrantOption.ValueBool() {
+			// GRANT keeps an existing grant option; only revoking it drops it.
+			err = r.ctx.Client.RevokeServerGrantOption(ctx, permission)
+		} else {
+			_, err = r.ctx.Client.GrantServerPermission(ctx, permission)
+		}
+		if err != nil {
+			resp.Diagnostics.AddError(
+				fmt.Sprintf("Error changing server permission %s of principal %s to %s", permission.Permission, permission.Principal, permission.State),
+				err.Error())
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *MssqlServerPermissionResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
+	var data MssqlServerPermissionResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = mssql.WithResource(ctx, "mssql_server_permission", data.Id.ValueString())
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
+	defer r.ctx.LockServer()()
+	if err := r.ctx.Client.RevokeServerPermission(ctx, serverPermission(data)); err != nil {
+		resp.Diagnostics.AddError("Unable to revoke server permission",
+			fmt.Sprintf("Unable to revoke server permission %s from principal %s, got error: %s", data.Permission.ValueString(), data.Principal.ValueString(), err))
+		return
+	}
+}
+
+func (r *MssqlServerPermissionResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
+	permission, err := decodeServerPermissionId(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid import ID", err.Error())
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("principal"), permission.Principal)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("permission"), strings.ToUpper(permission.Permission))...)
+	if permission.ObjectType != "" {
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("object_type"), strings.ToUpper(permission.ObjectType))...)
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("object_name"), permission.ObjectName)...)
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), serverPermissionToId(r.ctx.ServerID, permission))...)
+}
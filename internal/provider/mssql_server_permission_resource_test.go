@@ -0,0 +1,69 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccMssqlServerPermissionResource_Server(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + testAccMssqlServerPermissionConfig("GRANT"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("mssql_server_permission.state", "permission", "VIEW SERVER STATE"),
+					resource.TestCheckResourceAttr("mssql_server_permission.state", "principal", "server_perm_monitor"),
+					resource.TestCheckResourceAttr("mssql_server_permission.state", "state", "GRANT"),
+					resource.TestCheckResourceAttr("mssql_server_permission.impersonate", "object_type", "LOGIN"),
+					resource.TestCheckResourceAttr("mssql_server_permission.impersonate", "object_name", "server_perm_etl"),
+				),
+			},
+			// Deny in place
+			{
+				Config: providerConfig + testAccMssqlServerPermissionConfig("DENY"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("mssql_server_permission.state", "state", "DENY"),
+				),
+			},
+			{
+				ResourceName:      "mssql_server_permission.impersonate",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testAccMssqlServerPermissionConfig(state string) string {
+	return fmt.Sprintf(`
+resource "mssql_login" "monitor" {
+  name     = "server_perm_monitor"
+  password = "MonitorPassword123!@#"
+}
+
+resource "mssql_login" "etl" {
+  name     = "server_perm_etl"
+  password = "EtlPassword123!@#"
+}
+
+resource "mssql_server_permission" "state" {
+  permission = "VIEW SERVER STATE"
+  principal  = mssql_login.monitor.name
+  state      = %q
+}
+
+resource "mssql_server_permission" "impersonate" {
+  permission  = "IMPERSONATE"
+  principal   = mssql_login.monitor.name
+  object_type = "LOGIN"
+  object_name = mssql_login.etl.name
+}
+`, state)
+}
@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"net/url"
 	"strings"
 
 	"github.com/hashicorp/terraform-plugin-framework/path"
@@ -19,12 +18,15 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/vsabella/terraform-provider-mssql/internal/core"
-	"github.com/vsabella/terraform-provider-mssql/internal/mssql"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &MssqlUserResource{}
 var _ resource.ResourceWithImportState = &MssqlUserResource{}
+var _ resource.ResourceWithUpgradeState = &MssqlUserResource{}
+var _ resource.ResourceWithModifyPlan = &MssqlUserResource{}
+var _ resource.ResourceWithMoveState = &MssqlUserResource{}
 
 func NewMssqlUserResource() resource.Resource {
 	return &MssqlUserResource{}
@@ -51,11 +53,13 @@ func (r *MssqlUserResource) Metadata(ctx context.Context, req resource.MetadataR
 
 func (r *MssqlUserResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Version: schemaVersion,
+
 		MarkdownDescription: "Manages a SQL Server database user. Supports both contained users (with password) and login-based users (mapped to a server login).",
 
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
-				MarkdownDescription: "Resource identifier in format `<server_id>/<database>/<username>` where `server_id` is `host:port`.",
+				MarkdownDescription: "Resource identifier in format `<server_id>/<database>/<username>` where `server_id` is the provider's `server_id`, by default `host:port`. Segments are URL path-escaped.",
 				Computed:            true,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
@@ -143,7 +147,40 @@ func (r *MssqlUserResource) Configure(ctx context.Context, req resource.Configur
 	r.ctx = *client
 }
 
+func (r *MssqlUserResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to check on destroy.
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data MssqlUserResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	database, databaseKnown := plannedDatabase(r.ctx, data.Database)
+	if username, ok := known(data.Username); ok && databaseKnown {
+		r.ctx.Planned.AddPrincipal(database, username)
+	}
+
+	if r.ctx.Server != nil {
+		validateUserCapabilities(data, *r.ctx.Server, &resp.Diagnostics)
+	}
+
+	if refs, ok := checkReferences(ctx, r.ctx, req, &resp.Diagnostics); ok {
+		if databaseKnown {
+			refs.database(path.Root("database"), database)
+		}
+		refs.serverPrincipal(path.Root("login_name"), "Login", data.LoginName)
+	}
+}
+
 func (r *MssqlUserResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	var data MssqlUserResourceModel
 
 	// Read Terraform plan data into the model
@@ -159,6 +196,9 @@ func (r *MssqlUserResource) Create(ctx context.Context, req resource.CreateReque
 		data.Database = types.StringValue(database)
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_user", formatId(r.ctx.ServerID, database, data.Username.ValueString()))
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
 	hasPassword := !data.Password.IsNull() && data.Password.ValueString() != ""
 	hasLoginName := !data.LoginName.IsNull() && data.LoginName.ValueString() != ""
 	isExternal := data.External.ValueBool()
@@ -190,6 +230,7 @@ func (r *MssqlUserResource) Create(ctx context.Context, req resource.CreateReque
 		DefaultSchema: data.DefaultSchema.ValueString(),
 	}
 
+	defer r.ctx.LockDatabase(database)()
 	user, err := r.ctx.Client.CreateUser(ctx, database, create)
 	if err != nil {
 		resp.Diagnostics.AddError(fmt.Sprintf("Error creating user %s", create.Username), err.Error())
@@ -204,7 +245,7 @@ func (r *MssqlUserResource) Create(ctx context.Context, req resource.CreateReque
 }
 
 func userToResource(data *MssqlUserResourceModel, serverID string, database string, user mssql.User) {
-	data.Id = types.StringValue(fmt.Sprintf("%s/%s/%s", serverID, database, user.Username))
+	data.Id = types.StringValue(formatId(serverID, database, user.Username))
 	data.Database = types.StringValue(database)
 	data.Username = types.StringValue(user.Username)
 
@@ -223,6 +264,11 @@ func userToResource(data *MssqlUserResourceModel, serverID string, database stri
 }
 
 func (r *MssqlUserResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Without a reachable server there is nothing to refresh; keep the prior state.
+	if r.ctx.Unconfigured {
+		return
+	}
+
 	var data MssqlUserResourceModel
 
 	// Read Terraform prior state data into the model
@@ -231,6 +277,8 @@ func (r *MssqlUserResource) Read(ctx context.Context, req resource.ReadRequest,
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_user", data.Id.ValueString())
+
 	database := data.Database.ValueString()
 	if data.Database.IsUnknown() || data.Database.IsNull() || database == "" || data.Username.IsNull() || data.Username.ValueString() == "" {
 		dbName, username, err := parseUserId(data.Id.ValueString())
@@ -258,6 +306,10 @@ func (r *MssqlUserResource) Read(ctx context.Context, req resource.ReadRequest,
 }
 
 func (r *MssqlUserResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	var data MssqlUserResourceModel
 
 	// Read Terraform plan data into the model
@@ -266,6 +318,9 @@ func (r *MssqlUserResource) Update(ctx context.Context, req resource.UpdateReque
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_user", data.Id.ValueString())
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
 	user := mssql.UpdateUser{
 		Id:            data.Username.ValueString(),
 		Password:      data.Password.ValueString(),
@@ -278,13 +333,14 @@ func (r *MssqlUserResource) Update(ctx context.Context, req resource.UpdateReque
 		data.Database = types.StringValue(database)
 	}
 
+	defer r.ctx.LockDatabase(database)()
 	cur, err := r.ctx.Client.UpdateUser(ctx, database, user)
 	if err != nil {
 		resp.Diagnostics.AddError("could not update user", err.Error())
 		return
 	}
 
-	data.Id = types.StringValue(fmt.Sprintf("%s/%s/%s", r.ctx.ServerID, database, cur.Username))
+	data.Id = types.StringValue(formatId(r.ctx.ServerID, database, cur.Username))
 	data.Database = types.StringValue(database)
 	data.DefaultSchema = types.StringValue(cur.DefaultSchema)
 
@@ -293,6 +349,10 @@ func (r *MssqlUserResource) Update(ctx context.Context, req resource.UpdateReque
 }
 
 func (r *MssqlUserResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	var data MssqlUserResourceModel
 
 	// Read Terraform prior state data into the model
@@ -301,6 +361,9 @@ func (r *MssqlUserResource) Delete(ctx context.Context, req resource.DeleteReque
 		return
 	}
 
+	ctx = mssql.WithResource(ctx, "mssql_user", data.Id.ValueString())
+	defer r.ctx.ReportDryRun(ctx, &resp.Diagnostics)
+
 	database := data.Database.ValueString()
 	if data.Database.IsUnknown() || data.Database.IsNull() || database == "" || data.Username.IsNull() || data.Username.ValueString() == "" {
 		dbName, username, err := parseUserId(data.Id.ValueString())
@@ -312,6 +375,7 @@ func (r *MssqlUserResource) Delete(ctx context.Context, req resource.DeleteReque
 		data.Username = types.StringValue(username)
 	}
 
+	defer r.ctx.LockDatabase(database)()
 	err := r.ctx.Client.DeleteUser(ctx, database, data.Username.ValueString())
 	if err != nil {
 		resp.Diagnostics.AddError("unable to delete user", fmt.Sprintf("unable to delete user %s, got error: %s", data.Username.ValueString(), err))
@@ -320,6 +384,10 @@ func (r *MssqlUserResource) Delete(ctx context.Context, req resource.DeleteReque
 }
 
 func (r *MssqlUserResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	if !r.ctx.RequireServer(&resp.Diagnostics) {
+		return
+	}
+
 	// Import ID must be <server_id>/<database>/<username>
 	database, username, err := parseUserId(req.ID)
 	if err != nil {
@@ -329,21 +397,124 @@ func (r *MssqlUserResource) ImportState(ctx context.Context, req resource.Import
 
 	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("database"), database)...)
 	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("username"), username)...)
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), fmt.Sprintf("%s/%s/%s", r.ctx.ServerID, database, username))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), formatId(r.ctx.ServerID, database, username))...)
 }
 
-func parseUserId(id string) (string, string, error) {
-	parts := strings.Split(id, "/")
-	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
-		return "", "", fmt.Errorf("expected id in format <server_id>/<database>/<username>, got %q", id)
+func (r *MssqlUserResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: upgradeFromV0(currentSchema(ctx, r), func(data *MssqlUserResourceModel) error {
+			server, err := upgradeServerId(r.ctx, data.Id.ValueString(), false)
+			if err != nil {
+				return err
+			}
+			data.Id = types.StringValue(formatId(server, data.Database.ValueString(), data.Username.ValueString()))
+			return nil
+		}),
+		1: upgradeFromV1(currentSchema(ctx, r), r.ctx),
 	}
-	db, err := url.QueryUnescape(parts[1])
+}
+
+func parseUserId(id string) (string, string, error) {
+	parts, err := parseId(id, 3, "<server_id>/<database>/<username>")
 	if err != nil {
 		return "", "", err
 	}
-	username, err := url.QueryUnescape(parts[2])
+	return parts[1], parts[2], nil
+}
+
+func (r *MssqlUserResource) MoveState(ctx context.Context) []resource.StateMover {
+	return []resource.StateMover{
+		{StateMover: r.moveBetrUser},
+		{StateMover: r.movePgssoftUser},
+	}
+}
+
+// betrUserState is the state of a betr-io/mssql mssql_user.
+type betrUserState struct {
+	Id                 string   `json:"id"`
+	Database           string   `json:"database"`
+	Username           string   `json:"username"`
+	LoginName          string   `json:"login_name"`
+	Password           string   `json:"password"`
+	Sid                string   `json:"sid"`
+	AuthenticationType string   `json:"authentication_type"`
+	DefaultSchema      string   `json:"default_schema"`
+	Roles              []string `json:"roles"`
+}
+
+func (r *MssqlUserResource) moveBetrUser(ctx context.Context, req resource.MoveStateRequest, resp *resource.MoveStateResponse) {
+	if !movingFrom(req, betrProvider, "mssql_user") {
+		return
+	}
+
+	var source betrUserState
+	if !decodeMovedState(req, &source, &resp.Diagnostics) || !requireMoveServer(r.ctx, &resp.Diagnostics) {
+		return
+	}
+	checkBetrServer(source.Id, r.ctx.ServerID, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	database := source.Database
+	if database == "" {
+		database = r.ctx.Database
+	}
+	data := MssqlUserResourceModel{
+		Id:            types.StringValue(formatId(r.ctx.ServerID, database, source.Username)),
+		Database:      types.StringValue(database),
+		Username:      types.StringValue(source.Username),
+		Password:      stringOrNull(source.Password),
+		External:      types.BoolValue(strings.EqualFold(source.AuthenticationType, "EXTERNAL")),
+		Sid:           stringOrNull(source.Sid),
+		DefaultSchema: stringOrNull(source.DefaultSchema),
+		LoginName:     stringOrNull(source.LoginName),
+	}
+	if len(source.Roles) > 0 {
+		resp.Diagnostics.AddWarning("User Roles Not Moved",
+			fmt.Sprintf("mssql_user does not manage role memberships. The memberships of %s in %s are kept on the server; "+
+				"manage them with an mssql_role_assignment resource for each of these roles: %s.", source.Username, database, strings.Join(source.Roles, ", ")))
+	}
+	resp.Diagnostics.Append(resp.TargetState.Set(ctx, &data)...)
+}
+
+// pgssoftUserState is the state of a PGSSoft/mssql mssql_sql_user,
+// mssql_azuread_user or mssql_azuread_service_principal.
+type pgssoftUserState struct {
+	Name       string `json:"name"`
+	DatabaseId string `json:"database_id"`
+}
+
+func (r *MssqlUserResource) movePgssoftUser(ctx context.Context, req resource.MoveStateRequest, resp *resource.MoveStateResponse) {
+	external := movingFrom(req, pgssoftProvider, "mssql_azuread_user") || movingFrom(req, pgssoftProvider, "mssql_azuread_service_principal")
+	if !external && !movingFrom(req, pgssoftProvider, "mssql_sql_user") {
+		return
+	}
+
+	var source pgssoftUserState
+	if !decodeMovedState(req, &source, &resp.Diagnostics) || !requireMoveServer(r.ctx, &resp.Diagnostics) {
+		return
+	}
+	ids, err := parseCatalogIds(source.DatabaseId, 1, "<database_id>")
 	if err != nil {
-		return "", "", err
+		resp.Diagnostics.AddError("Unable to Move Resource State", err.Error())
+		return
+	}
+	database := lookupDatabase(ctx, r.ctx.Client, ids[0], &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The login is known by its SID only; Read looks it up.
+	data := MssqlUserResourceModel{
+		Id:            types.StringValue(formatId(r.ctx.ServerID, database, source.Name)),
+		Database:      types.StringValue(database),
+		Username:      types.StringValue(source.Name),
+		Password:      types.StringNull(),
+		External:      types.BoolValue(external),
+		Sid:           types.StringNull(),
+		DefaultSchema: types.StringNull(),
+		LoginName:     types.StringNull(),
 	}
-	return db, username, nil
+	resp.Diagnostics.Append(resp.TargetState.Set(ctx, &data)...)
 }
@@ -5,23 +5,35 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/function"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/vsabella/terraform-provider-mssql/internal/core"
-	"github.com/vsabella/terraform-provider-mssql/internal/mssql"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
 )
 
 // Ensure MssqlProvider satisfies various provider interfaces.
 var _ provider.Provider = &MssqlProvider{}
 var _ provider.ProviderWithFunctions = &MssqlProvider{}
+var _ provider.ProviderWithValidateConfig = &MssqlProvider{}
+
+// defaultDryRunFile is where dry runs record their statements when
+// dry_run_file is not set.
+const defaultDryRunFile = "mssql-dry-run.sql"
 
 // MssqlProvider defines the provider implementation.
 type MssqlProvider struct {
@@ -29,18 +41,82 @@ type MssqlProvider struct {
 	// provider is built and ran locally, and "test" when running acceptance
 	// testing.
 	version string
+
+	// data is the provider data of the last Configure call, guarded by mu.
+	// Resources are created configured with it; see configured.
+	mu   sync.Mutex
+	data *core.ProviderData
 }
 
 type SqlAuth struct {
-	Username types.String `tfsdk:"username"`
-	Password types.String `tfsdk:"password"`
+	Username          types.String `tfsdk:"username"`
+	Password          types.String `tfsdk:"password"`
+	PasswordFile      types.String `tfsdk:"password_file"`
+	CredentialCommand types.List   `tfsdk:"credential_command"`
+}
+
+type AzureAuth struct {
+	Mode                      types.String `tfsdk:"mode"`
+	TenantID                  types.String `tfsdk:"tenant_id"`
+	ClientID                  types.String `tfsdk:"client_id"`
+	ClientSecret              types.String `tfsdk:"client_secret"`
+	ClientCertificatePath     types.String `tfsdk:"client_certificate_path"`
+	ClientCertificatePassword types.String `tfsdk:"client_certificate_password"`
+	FederatedTokenFile        types.String `tfsdk:"federated_token_file"`
+	AuthorityHost             types.String `tfsdk:"authority_host"`
+}
+
+type TLS struct {
+	Encrypt                types.String `tfsdk:"encrypt"`
+	TrustServerCertificate types.Bool   `tfsdk:"trust_server_certificate"`
+	Certificate            types.String `tfsdk:"certificate"`
+	HostNameInCertificate  types.String `tfsdk:"host_name_in_certificate"`
+	AllowInsecure          types.Bool   `tfsdk:"allow_insecure"`
+}
+
+type SSHTunnel struct {
+	Host                 types.String `tfsdk:"host"`
+	Port                 types.Int64  `tfsdk:"port"`
+	User                 types.String `tfsdk:"user"`
+	PrivateKey           types.String `tfsdk:"private_key"`
+	PrivateKeyPassphrase types.String `tfsdk:"private_key_passphrase"`
+	UseAgent             types.Bool   `tfsdk:"use_agent"`
+	HostKey              types.String `tfsdk:"host_key"`
+	KnownHostsFile       types.String `tfsdk:"known_hosts_file"`
+}
+
+type Retry struct {
+	MaxAttempts    types.Int64  `tfsdk:"max_attempts"`
+	InitialBackoff types.String `tfsdk:"initial_backoff"`
+	MaxBackoff     types.String `tfsdk:"max_backoff"`
+}
+
+type Pool struct {
+	MaxOpenConns     types.Int64  `tfsdk:"max_open_conns"`
+	MaxIdleConns     types.Int64  `tfsdk:"max_idle_conns"`
+	ConnMaxLifetime  types.String `tfsdk:"conn_max_lifetime"`
+	MaxDatabasePools types.Int64  `tfsdk:"max_database_pools"`
 }
 
 type MssqlProviderModel struct {
-	Host     types.String `tfsdk:"host"`
-	Port     types.Int64  `tfsdk:"port"`
-	Database types.String `tfsdk:"database"`
-	SqlAuth  *SqlAuth     `tfsdk:"sql_auth"`
+	Host      types.String `tfsdk:"host"`
+	Port      types.Int64  `tfsdk:"port"`
+	Database  types.String `tfsdk:"database"`
+	ServerId  types.String `tfsdk:"server_id"`
+	SqlAuth   *SqlAuth     `tfsdk:"sql_auth"`
+	AzureAuth *AzureAuth   `tfsdk:"azure_auth"`
+	TLS       *TLS         `tfsdk:"tls"`
+	Retry     *Retry       `tfsdk:"retry"`
+	Pool      *Pool        `tfsdk:"pool"`
+	SSHTunnel *SSHTunnel   `tfsdk:"ssh_tunnel"`
+
+	ConnectionString types.String `tfsdk:"connection_string"`
+	Parameters       types.Map    `tfsdk:"parameters"`
+
+	StatementLog types.String `tfsdk:"statement_log"`
+	DryRun       types.Bool   `tfsdk:"dry_run"`
+	DryRunFile   types.String `tfsdk:"dry_run_file"`
+	CatalogCache types.Bool   `tfsdk:"catalog_cache"`
 }
 
 func (p *MssqlProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -52,29 +128,229 @@ func (p *MssqlProvider) Schema(ctx context.Context, req provider.SchemaRequest,
 	resp.Schema = schema.Schema{
 		Attributes: map[string]schema.Attribute{
 			"host": schema.StringAttribute{
-				MarkdownDescription: "MSSQL Server Hostname",
-				Required:            true,
+				MarkdownDescription: "MSSQL Server Hostname. Can also be set with the `MSSQL_HOST` environment variable.",
+				Optional:            true,
 			},
 			"port": schema.Int64Attribute{
-				MarkdownDescription: "MSSQL Server Port. Default: `1433`",
+				MarkdownDescription: "MSSQL Server Port. Can also be set with the `MSSQL_PORT` environment variable. Default: `1433`",
 				Optional:            true,
 			},
 			"database": schema.StringAttribute{
-				MarkdownDescription: "Database to connect to. Default: `master`",
+				MarkdownDescription: "Database to connect to. Can also be set with the `MSSQL_DATABASE` environment variable. Default: `master`",
+				Optional:            true,
+			},
+			"server_id": schema.StringAttribute{
+				MarkdownDescription: "Stable name of the server used as the first segment of resource IDs and to serialize DDL between provider configurations, e.g. the DNS alias or availability group listener. Set it to keep IDs unchanged when `host` changes; existing state is rewritten to it on the next refresh. Can also be set with the `MSSQL_SERVER_ID` environment variable. Default: `host:port`",
+				Optional:            true,
+			},
+			"connection_string": schema.StringAttribute{
+				MarkdownDescription: "A go-mssqldb connection string in ADO (`server=...;app name=...`), ODBC or `sqlserver://` URL form. Its server, port, database and credentials are used when `host`, `port`, `database` and the authentication blocks are not set; setting both is an error. Use the ODBC or URL form for values that contain `;`.",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"parameters": schema.MapAttribute{
+				MarkdownDescription: "Additional go-mssqldb connection parameters, e.g. `{ \"app name\" = \"terraform\", \"dial timeout\" = \"30\" }`. They override `connection_string`. Parameters controlled by other attributes, such as `server`, `user id` or `encrypt`, are not allowed.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"statement_log": schema.StringAttribute{
+				MarkdownDescription: "Path of a file to which every SQL statement the provider runs is appended as a JSON line, with the resource type and ID, database, duration, rows affected and error number. Password parameters are redacted; statements of `mssql_script` are logged as written.",
+				Optional:            true,
+			},
+			"dry_run": schema.BoolAttribute{
+				MarkdownDescription: "Record the SQL that would change the server instead of executing it. Reads still run against the server. Recorded statements are reported as warnings and appended to `dry_run_file`. Terraform records the changes as applied, so run dry runs against a copy of the state. Default: `false`",
+				Optional:            true,
+			},
+			"dry_run_file": schema.StringAttribute{
+				MarkdownDescription: "File to which the statements of a dry run are appended. Default: `mssql-dry-run.sql`",
+				Optional:            true,
+			},
+			"catalog_cache": schema.BoolAttribute{
+				MarkdownDescription: "Read the permissions and role memberships of each database once and refresh `mssql_grant` and `mssql_role_assignment` from that snapshot, instead of querying once per resource. Once the provider executes a statement in a database, it reads that database from the server instead. Default: `false`",
 				Optional:            true,
 			},
 			"sql_auth": schema.SingleNestedAttribute{
-				Description: "SQL authentication credentials used when connecting.",
-				Required:    true,
+				MarkdownDescription: "SQL authentication credentials used when connecting. Exactly one of `sql_auth` or `azure_auth` must be set. When neither block is configured, `MSSQL_USERNAME` and `MSSQL_PASSWORD` enable SQL authentication.",
+				Optional:            true,
 				Attributes: map[string]schema.Attribute{
 					"username": schema.StringAttribute{
-						Description: "User name for SQL authentication.",
-						Required:    true,
+						MarkdownDescription: "User name for SQL authentication. Can also be set with the `MSSQL_USERNAME` environment variable. Optional with `credential_command` when the command prints a username.",
+						Optional:            true,
 					},
 					"password": schema.StringAttribute{
-						Description: "Password for SQL authentication.",
-						Required:    true,
-						Sensitive:   true,
+						MarkdownDescription: "Password for SQL authentication. Can also be set with the `MSSQL_PASSWORD` environment variable. Exactly one of `password`, `password_file` or `credential_command` must be set.",
+						Optional:            true,
+						Sensitive:           true,
+					},
+					"password_file": schema.StringAttribute{
+						MarkdownDescription: "Path of a file holding the password, such as a mounted Kubernetes or Vault agent secret. The file is read for every new connection, so a rotated password is picked up without re-running Terraform. Trailing newlines are ignored.",
+						Optional:            true,
+					},
+					"credential_command": schema.ListAttribute{
+						MarkdownDescription: "Command and arguments run to obtain credentials, e.g. `[\"vault\", \"read\", \"-format=json\", \"database/creds/terraform\"]`. The command must print a JSON object with `password` or `token`, and optionally `username` and an RFC 3339 `expiry`. The result is cached and the command is run again shortly before `expiry` or when the server rejects the login.",
+						ElementType:         types.StringType,
+						Optional:            true,
+					},
+				},
+			},
+			"azure_auth": schema.SingleNestedAttribute{
+				MarkdownDescription: "Microsoft Entra ID (Azure AD) authentication used when connecting. Exactly one of `sql_auth` or `azure_auth` must be set. When neither block is configured, `MSSQL_AZURE_MODE` enables Entra ID authentication. Each attribute can also be set with the matching `MSSQL_AZURE_*` environment variable, e.g. `MSSQL_AZURE_CLIENT_SECRET`.",
+				Optional:            true,
+				Attributes: map[string]schema.Attribute{
+					"mode": schema.StringAttribute{
+						MarkdownDescription: "Authentication mode: `service_principal` (client secret), `client_certificate`, `managed_identity`, `workload_identity` (federated token file) or `default` (the Azure SDK default credential chain). Can also be set with the `MSSQL_AZURE_MODE` environment variable.",
+						Optional:            true,
+						Validators: []validator.String{
+							stringOneOfValidator{values: mssql.AzureAuthModes},
+						},
+					},
+					"tenant_id": schema.StringAttribute{
+						MarkdownDescription: "Entra ID tenant. Required for `service_principal` and `client_certificate`.",
+						Optional:            true,
+					},
+					"client_id": schema.StringAttribute{
+						MarkdownDescription: "Application (client) ID. Required for `service_principal` and `client_certificate`; selects a user-assigned identity for `managed_identity`.",
+						Optional:            true,
+					},
+					"client_secret": schema.StringAttribute{
+						MarkdownDescription: "Client secret for `service_principal`.",
+						Optional:            true,
+						Sensitive:           true,
+					},
+					"client_certificate_path": schema.StringAttribute{
+						MarkdownDescription: "Path to a PEM or PKCS#12 certificate (with private key) for `client_certificate`.",
+						Optional:            true,
+					},
+					"client_certificate_password": schema.StringAttribute{
+						MarkdownDescription: "Password protecting `client_certificate_path`, if any.",
+						Optional:            true,
+						Sensitive:           true,
+					},
+					"federated_token_file": schema.StringAttribute{
+						MarkdownDescription: "Path to the federated (Kubernetes service account) token for `workload_identity`. Defaults to `AZURE_FEDERATED_TOKEN_FILE`.",
+						Optional:            true,
+					},
+					"authority_host": schema.StringAttribute{
+						MarkdownDescription: "Entra ID authority host, e.g. `https://login.microsoftonline.us/` for sovereign clouds. Defaults to the Azure public cloud.",
+						Optional:            true,
+					},
+				},
+			},
+			"tls": schema.SingleNestedAttribute{
+				MarkdownDescription: "Transport encryption settings. When omitted, only the login packet is encrypted and the server certificate is not validated. Settings that disable encryption or certificate validation are rejected unless `allow_insecure` is set.",
+				Optional:            true,
+				Attributes: map[string]schema.Attribute{
+					"encrypt": schema.StringAttribute{
+						MarkdownDescription: "Encryption mode: `mandatory` (encrypt the whole session), `strict` (TDS 8.0, TLS before any TDS traffic), `optional` (encrypt only the login packet) or `disable`. Default: `mandatory`",
+						Optional:            true,
+						Validators: []validator.String{
+							stringOneOfValidator{values: mssql.EncryptModes},
+						},
+					},
+					"trust_server_certificate": schema.BoolAttribute{
+						MarkdownDescription: "Skip validation of the server certificate. Requires `allow_insecure`. Default: `false`",
+						Optional:            true,
+					},
+					"certificate": schema.StringAttribute{
+						MarkdownDescription: "Path to a PEM file with the CA, or the self-signed server certificate, used to validate the server instead of the system roots.",
+						Optional:            true,
+					},
+					"host_name_in_certificate": schema.StringAttribute{
+						MarkdownDescription: "Host name expected in the server certificate. Defaults to `host`.",
+						Optional:            true,
+					},
+					"allow_insecure": schema.BoolAttribute{
+						MarkdownDescription: "Permit `encrypt` values `optional` and `disable`, and `trust_server_certificate`. Default: `false`",
+						Optional:            true,
+					},
+				},
+			},
+			"ssh_tunnel": schema.SingleNestedAttribute{
+				MarkdownDescription: "Reach the server through an SSH bastion. Every connection is dialed by an in-process SSH client and `host` is resolved by the bastion, so private DNS names work.",
+				Optional:            true,
+				Attributes: map[string]schema.Attribute{
+					"host": schema.StringAttribute{
+						MarkdownDescription: "Hostname or IP address of the bastion.",
+						Required:            true,
+					},
+					"port": schema.Int64Attribute{
+						MarkdownDescription: "SSH port of the bastion. Default: `22`",
+						Optional:            true,
+					},
+					"user": schema.StringAttribute{
+						MarkdownDescription: "User to log in to the bastion as.",
+						Required:            true,
+					},
+					"private_key": schema.StringAttribute{
+						MarkdownDescription: "PEM encoded private key, e.g. `file(\"~/.ssh/id_ed25519\")`. At least one of `private_key` or `use_agent` is required.",
+						Optional:            true,
+						Sensitive:           true,
+					},
+					"private_key_passphrase": schema.StringAttribute{
+						MarkdownDescription: "Passphrase of an encrypted `private_key`.",
+						Optional:            true,
+						Sensitive:           true,
+					},
+					"use_agent": schema.BoolAttribute{
+						MarkdownDescription: "Authenticate with the keys of the SSH agent at `SSH_AUTH_SOCK`. Default: `false`",
+						Optional:            true,
+					},
+					"host_key": schema.StringAttribute{
+						MarkdownDescription: "Public key of the bastion in `authorized_keys` format, e.g. `ssh-ed25519 AAAA...`. Conflicts with `known_hosts_file`.",
+						Optional:            true,
+					},
+					"known_hosts_file": schema.StringAttribute{
+						MarkdownDescription: "Path to a `known_hosts` file used to verify the bastion when `host_key` is not set. Default: `~/.ssh/known_hosts`",
+						Optional:            true,
+					},
+				},
+			},
+			"retry": schema.SingleNestedAttribute{
+				MarkdownDescription: "Retry of transient errors such as deadlocks (1205), Azure SQL failovers (40613, 40197, 49918) and dropped connections, with exponential backoff and jitter. Statements that are not idempotent, like `CREATE`, `DROP` and `mssql_script` batches, are only retried when the server reports that they did not run. Retries are enabled with the defaults below when this block is omitted.",
+				Optional:            true,
+				Attributes: map[string]schema.Attribute{
+					"max_attempts": schema.Int64Attribute{
+						MarkdownDescription: "Total attempts per statement, including the first. `1` disables retries. Default: `4`",
+						Optional:            true,
+					},
+					"initial_backoff": schema.StringAttribute{
+						MarkdownDescription: "Delay before the first retry; doubles with every attempt. Default: `500ms`",
+						Optional:            true,
+						Validators: []validator.String{
+							durationValidator{},
+						},
+					},
+					"max_backoff": schema.StringAttribute{
+						MarkdownDescription: "Upper bound for the delay between attempts. Default: `30s`",
+						Optional:            true,
+						Validators: []validator.String{
+							durationValidator{},
+						},
+					},
+				},
+			},
+			"pool": schema.SingleNestedAttribute{
+				MarkdownDescription: "Connection pool limits. The provider keeps one pool for `database` and one for every other database a resource targets; the limits apply to each pool.",
+				Optional:            true,
+				Attributes: map[string]schema.Attribute{
+					"max_open_conns": schema.Int64Attribute{
+						MarkdownDescription: "Maximum open connections per pool. `0` means unlimited. Default: `0`",
+						Optional:            true,
+					},
+					"max_idle_conns": schema.Int64Attribute{
+						MarkdownDescription: "Maximum idle connections kept per pool. Default: `2`",
+						Optional:            true,
+					},
+					"conn_max_lifetime": schema.StringAttribute{
+						MarkdownDescription: "Maximum time a connection may be reused, e.g. `5m`. `0s` means forever. Default: `0s`",
+						Optional:            true,
+						Validators: []validator.String{
+							durationValidator{},
+						},
+					},
+					"max_database_pools": schema.Int64Attribute{
+						MarkdownDescription: "Maximum number of per-database pools kept open. The least recently used pool is closed when the limit is exceeded. `0` means unlimited. Default: `32`",
+						Optional:            true,
 					},
 				},
 			},
@@ -82,37 +358,72 @@ func (p *MssqlProvider) Schema(ctx context.Context, req provider.SchemaRequest,
 	}
 }
 
+func (p *MssqlProvider) ValidateConfig(ctx context.Context, req provider.ValidateConfigRequest, resp *provider.ValidateConfigResponse) {
+	var data MssqlProviderModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	validateConnectionParameters(&data, &resp.Diagnostics)
+}
+
 func (p *MssqlProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
 	var data MssqlProviderModel
 
 	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	// Configuration values are now available.
-	// if data.Endpoint.IsNull() { /* ... */ }
+	// Configuration values are now available; fill the gaps from the
+	// connection string, then the environment.
+	applyConnectionString(&data, &resp.Diagnostics)
+	applyEnvironment(&data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	if data.Host.IsUnknown() {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("host"),
-			"Unknown Sql Server Host",
-			"The provider needs the hostname or IP address of Microsoft SQL Server.",
-		)
-	} else if data.Host.IsNull() || strings.TrimSpace(data.Host.ValueString()) == "" {
+	// Values that depend on resources created in the same run, such as the
+	// host of a new Azure SQL server, are unknown during plan. Defer
+	// connecting until Terraform configures the provider again at apply.
+	if configUnknown(&data) {
+		tflog.Info(ctx, "Provider configuration contains unknown values; deferring connection until they are known")
+		// Resources cannot create or import anything until the provider is
+		// configured, so an unknown server ID only reaches state upgrades,
+		// which then keep the server ID of the prior state.
+		serverID, _ := providerServerId(&data)
+		client := &core.ProviderData{
+			Client:       mssql.NewNotConfiguredClient(),
+			ServerID:     serverID,
+			Database:     data.Database.ValueString(),
+			Locks:        locks,
+			Unconfigured: true,
+		}
+		resp.DataSourceData = client
+		resp.ResourceData = client
+		p.setData(client)
+		return
+	}
+
+	if data.Host.IsNull() || strings.TrimSpace(data.Host.ValueString()) == "" {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("host"),
 			"Missing Sql Server Host",
-			"`host` must be set to the hostname or IP address of Microsoft SQL Server.",
+			fmt.Sprintf("Set `host` in the provider configuration or the %s environment variable to the hostname or IP address of Microsoft SQL Server.", envHost),
 		)
 	}
 
-	if data.Port.IsUnknown() {
+	if !data.ServerId.IsNull() && strings.TrimSpace(data.ServerId.ValueString()) == "" {
 		resp.Diagnostics.AddAttributeError(
-			path.Root("port"),
-			"Unknown Sql Server Port",
-			"The provider needs the port of Microsoft SQL Server.",
+			path.Root("server_id"),
+			"Invalid Server ID",
+			"`server_id` must not be empty. Remove it to identify the server by `host:port`.",
 		)
 	}
 
-	if data.Database.IsUnknown() || data.Database.IsNull() || data.Database.ValueString() == "" {
+	if data.Database.IsNull() || data.Database.ValueString() == "" {
 		resp.Diagnostics.AddWarning(
 			"Unknown Sql Server Database, defaults to 'master'",
 			"If not provided, the provider will default to 'master'. Database-scoped resources can target other databases using their `database` attribute.",
@@ -124,28 +435,41 @@ func (p *MssqlProvider) Configure(ctx context.Context, req provider.ConfigureReq
 		return
 	}
 
-	// Validate sql_auth (required to connect)
-	if data.SqlAuth == nil {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("sql_auth"),
-			"Missing SQL Authentication",
-			"`sql_auth` is required. Provide `sql_auth { username = \"...\" password = \"...\" }`.",
+	// Validate authentication (exactly one method is required to connect)
+	if data.SqlAuth == nil && data.AzureAuth == nil {
+		resp.Diagnostics.AddError(
+			"Missing Authentication",
+			fmt.Sprintf("One of `sql_auth` or `azure_auth` is required. Provide `sql_auth { username = \"...\" password = \"...\" }` or an `azure_auth` block, or set the %s and %s (or %s) environment variables.", envUsername, envPassword, envAzureMode),
 		)
 		return
 	}
-	if data.SqlAuth.Username.IsUnknown() || data.SqlAuth.Username.IsNull() || data.SqlAuth.Username.ValueString() == "" {
+	if data.SqlAuth != nil && data.AzureAuth != nil {
 		resp.Diagnostics.AddAttributeError(
-			path.Root("sql_auth").AtName("username"),
-			"Missing SQL Username",
-			"`sql_auth.username` must be set.",
+			path.Root("azure_auth"),
+			"Conflicting Authentication",
+			"Only one of `sql_auth` or `azure_auth` may be set.",
 		)
+		return
 	}
-	if data.SqlAuth.Password.IsUnknown() || data.SqlAuth.Password.IsNull() || data.SqlAuth.Password.ValueString() == "" {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("sql_auth").AtName("password"),
-			"Missing SQL Password",
-			"`sql_auth.password` must be set.",
-		)
+	if data.SqlAuth != nil {
+		validateSqlAuth(data.SqlAuth, &resp.Diagnostics)
+	}
+	if data.AzureAuth != nil {
+		validateAzureAuth(data.AzureAuth, &resp.Diagnostics)
+	}
+	if data.TLS != nil {
+		validateTLS(data.TLS, &resp.Diagnostics)
+	}
+	if data.SSHTunnel != nil {
+		validateSSHTunnel(data.SSHTunnel, &resp.Diagnostics)
+	}
+	var retry *mssql.Retry
+	if data.Retry != nil {
+		retry = toRetry(data.Retry, &resp.Diagnostics)
+	}
+	var pool *mssql.Pool
+	if data.Pool != nil {
+		pool = toPool(data.Pool, &resp.Diagnostics)
 	}
 	if resp.Diagnostics.HasError() {
 		return
@@ -157,27 +481,380 @@ func (p *MssqlProvider) Configure(ctx context.Context, req provider.ConfigureReq
 	if data.Port.IsNull() || port <= 0 {
 		port = 1433
 	}
-	serverID := fmt.Sprintf("%s:%d", host, port)
+	serverID, _ := providerServerId(&data)
+	cfg := mssql.Config{
+		Host:     host,
+		Port:     port,
+		Database: data.Database.ValueString(),
+	}
+	if data.SqlAuth != nil {
+		cfg.SqlAuth = &mssql.SqlAuth{
+			Username:     data.SqlAuth.Username.ValueString(),
+			Password:     data.SqlAuth.Password.ValueString(),
+			PasswordFile: data.SqlAuth.PasswordFile.ValueString(),
+		}
+		for _, v := range data.SqlAuth.CredentialCommand.Elements() {
+			cfg.SqlAuth.CredentialCommand = append(cfg.SqlAuth.CredentialCommand, v.(types.String).ValueString())
+		}
+	}
+	if data.AzureAuth != nil {
+		cfg.AzureAuth = toAzureAuth(data.AzureAuth)
+	}
+	if data.TLS != nil {
+		cfg.TLS = toTLS(data.TLS)
+	}
+	if data.SSHTunnel != nil {
+		cfg.SSHTunnel = toSSHTunnel(data.SSHTunnel)
+	}
+	cfg.Retry = retry
+	cfg.Pool = pool
+	cfg.ConnectionString = data.ConnectionString.ValueString()
+	cfg.Parameters = toParameters(data.Parameters)
+	cfg.StatementLog = data.StatementLog.ValueString()
+	cfg.CatalogCache = data.CatalogCache.ValueBool()
+	if data.DryRun.ValueBool() {
+		cfg.DryRunFile = defaultDryRunFile
+		if !data.DryRunFile.IsNull() {
+			cfg.DryRunFile = data.DryRunFile.ValueString()
+		}
+	}
+	sqlClient, err := mssql.NewClient(cfg)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to Create Sql Server Client", err.Error())
+		return
+	}
+
+	// Verify connectivity once here, so that an unreachable server or bad
+	// credentials surface as a single provider error instead of one per resource.
+	if err := sqlClient.Ping(ctx); err != nil {
+		summary := "Unable to Connect to Sql Server"
+		var connErr *mssql.ConnectError
+		if errors.As(err, &connErr) {
+			summary = connErr.Summary
+		}
+		resp.Diagnostics.AddError(summary, err.Error())
+		sqlClient.Close()
+		return
+	}
+	registerClient(sqlClient)
 	client := &core.ProviderData{
-		Client:   mssql.NewClient(host, port, data.Database.ValueString(), data.SqlAuth.Username.ValueString(), data.SqlAuth.Password.ValueString()),
+		Client:   sqlClient,
 		ServerID: serverID,
 		Database: data.Database.ValueString(),
+		Locks:    locks,
+		Planned:  core.NewPlanned(),
+		DryRun:   cfg.DryRunFile != "",
+	}
+	if client.DryRun {
+		resp.Diagnostics.AddWarning(
+			"Dry Run Enabled",
+			fmt.Sprintf("Statements that change the server are recorded to %s instead of executed. Terraform will still record the changes as applied; discard the resulting state.", cfg.DryRunFile),
+		)
+	}
+
+	if info, err := sqlClient.GetServerInfo(ctx); err != nil {
+		resp.Diagnostics.AddWarning(
+			"Unable to Detect Server Capabilities",
+			fmt.Sprintf("Attributes will not be checked against the server version and edition during plan: %v", err),
+		)
+	} else {
+		tflog.Info(ctx, fmt.Sprintf("Connected to %s", info))
+		client.Server = &info
 	}
 
 	resp.DataSourceData = client
 	resp.ResourceData = client
+	p.setData(client)
+}
+
+func (p *MssqlProvider) setData(data *core.ProviderData) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.data = data
+}
+
+// configured wraps a resource factory so that, once the provider is
+// configured, new resources are configured with the provider data. The
+// framework configures resources before every operation except MoveState,
+// which needs the client to build IDs and look up names.
+func (p *MssqlProvider) configured(factory func() resource.Resource) func() resource.Resource {
+	return func() resource.Resource {
+		r := factory()
+		p.mu.Lock()
+		data := p.data
+		p.mu.Unlock()
+		if rc, ok := r.(resource.ResourceWithConfigure); ok && data != nil {
+			rc.Configure(context.Background(), resource.ConfigureRequest{ProviderData: data}, &resource.ConfigureResponse{})
+		}
+		return r
+	}
+}
+
+// providerServerId returns the server ID that resources put in their IDs:
+// server_id, or host:port when it is not set. It reports false when the
+// attributes it depends on are unknown or the host is missing.
+func providerServerId(data *MssqlProviderModel) (string, bool) {
+	if !data.ServerId.IsNull() {
+		return data.ServerId.ValueString(), !data.ServerId.IsUnknown()
+	}
+	if data.Host.IsUnknown() || data.Port.IsUnknown() || data.Host.ValueString() == "" {
+		return "", false
+	}
+	port := data.Port.ValueInt64()
+	if data.Port.IsNull() || port <= 0 {
+		port = 1433
+	}
+	return fmt.Sprintf("%s:%d", data.Host.ValueString(), port), true
+}
+
+// configUnknown reports whether any provider attribute is unknown.
+func configUnknown(data *MssqlProviderModel) bool {
+	values := []attr.Value{data.Host, data.Port, data.Database, data.ServerId, data.ConnectionString, data.Parameters,
+		data.StatementLog, data.DryRun, data.DryRunFile, data.CatalogCache}
+	if !data.Parameters.IsUnknown() {
+		for _, v := range data.Parameters.Elements() {
+			values = append(values, v)
+		}
+	}
+	if a := data.SqlAuth; a != nil {
+		values = append(values, a.Username, a.Password, a.PasswordFile, a.CredentialCommand)
+		if !a.CredentialCommand.IsUnknown() {
+			values = append(values, a.CredentialCommand.Elements()...)
+		}
+	}
+	if a := data.AzureAuth; a != nil {
+		values = append(values, a.Mode, a.TenantID, a.ClientID, a.ClientSecret, a.ClientCertificatePath,
+			a.ClientCertificatePassword, a.FederatedTokenFile, a.AuthorityHost)
+	}
+	if t := data.TLS; t != nil {
+		values = append(values, t.Encrypt, t.TrustServerCertificate, t.Certificate, t.HostNameInCertificate, t.AllowInsecure)
+	}
+	if t := data.SSHTunnel; t != nil {
+		values = append(values, t.Host, t.Port, t.User, t.PrivateKey, t.PrivateKeyPassphrase, t.UseAgent, t.HostKey, t.KnownHostsFile)
+	}
+	if r := data.Retry; r != nil {
+		values = append(values, r.MaxAttempts, r.InitialBackoff, r.MaxBackoff)
+	}
+	if p := data.Pool; p != nil {
+		values = append(values, p.MaxOpenConns, p.MaxIdleConns, p.ConnMaxLifetime, p.MaxDatabasePools)
+	}
+
+	for _, v := range values {
+		if v.IsUnknown() {
+			return true
+		}
+	}
+	return false
+}
+
+func validateSqlAuth(auth *SqlAuth, diags *diag.Diagnostics) {
+	hasCommand := !auth.CredentialCommand.IsNull()
+	if !hasCommand && (auth.Username.IsUnknown() || auth.Username.IsNull() || auth.Username.ValueString() == "") {
+		diags.AddAttributeError(
+			path.Root("sql_auth").AtName("username"),
+			"Missing SQL Username",
+			fmt.Sprintf("Set `sql_auth.username` in the provider configuration or the %s environment variable.", envUsername),
+		)
+	}
+
+	sources := 0
+	if !auth.Password.IsNull() && auth.Password.ValueString() != "" {
+		sources++
+	}
+	if !auth.PasswordFile.IsNull() {
+		sources++
+		if auth.PasswordFile.ValueString() == "" {
+			diags.AddAttributeError(
+				path.Root("sql_auth").AtName("password_file"),
+				"Invalid SQL Password File",
+				"`sql_auth.password_file` must not be empty.",
+			)
+		}
+	}
+	if hasCommand {
+		sources++
+		if len(auth.CredentialCommand.Elements()) == 0 {
+			diags.AddAttributeError(
+				path.Root("sql_auth").AtName("credential_command"),
+				"Invalid SQL Credential Command",
+				"`sql_auth.credential_command` must name the command to run.",
+			)
+		}
+	}
+
+	switch {
+	case sources == 0:
+		diags.AddAttributeError(
+			path.Root("sql_auth").AtName("password"),
+			"Missing SQL Password",
+			fmt.Sprintf("Set one of `sql_auth.password`, `sql_auth.password_file` or `sql_auth.credential_command` in the provider configuration, or the %s environment variable.", envPassword),
+		)
+	case sources > 1:
+		diags.AddAttributeError(
+			path.Root("sql_auth"),
+			"Conflicting SQL Password Sources",
+			"Only one of `sql_auth.password`, `sql_auth.password_file` or `sql_auth.credential_command` may be set.",
+		)
+	}
+}
+
+func validateAzureAuth(auth *AzureAuth, diags *diag.Diagnostics) {
+	if auth.Mode.IsNull() || auth.Mode.ValueString() == "" {
+		diags.AddAttributeError(
+			path.Root("azure_auth").AtName("mode"),
+			"Missing Azure Authentication Mode",
+			fmt.Sprintf("Set `azure_auth.mode` in the provider configuration or the %s environment variable.", envAzureMode),
+		)
+		return
+	}
+
+	if err := toAzureAuth(auth).Validate(); err != nil {
+		diags.AddAttributeError(
+			path.Root("azure_auth"),
+			"Invalid Azure Authentication",
+			fmt.Sprintf("%s. Attributes can be set in the provider configuration or the matching MSSQL_AZURE_* environment variables.", err),
+		)
+	}
+}
+
+func toAzureAuth(auth *AzureAuth) *mssql.AzureAuth {
+	return &mssql.AzureAuth{
+		Mode:                      auth.Mode.ValueString(),
+		TenantID:                  auth.TenantID.ValueString(),
+		ClientID:                  auth.ClientID.ValueString(),
+		ClientSecret:              auth.ClientSecret.ValueString(),
+		ClientCertificatePath:     auth.ClientCertificatePath.ValueString(),
+		ClientCertificatePassword: auth.ClientCertificatePassword.ValueString(),
+		FederatedTokenFile:        auth.FederatedTokenFile.ValueString(),
+		AuthorityHost:             auth.AuthorityHost.ValueString(),
+	}
+}
+
+func validateTLS(t *TLS, diags *diag.Diagnostics) {
+	if err := toTLS(t).Validate(); err != nil {
+		diags.AddAttributeError(path.Root("tls"), "Invalid TLS Configuration", err.Error())
+	}
+}
+
+func toTLS(t *TLS) *mssql.TLS {
+	return &mssql.TLS{
+		Encrypt:                t.Encrypt.ValueString(),
+		TrustServerCertificate: t.TrustServerCertificate.ValueBool(),
+		Certificate:            t.Certificate.ValueString(),
+		HostNameInCertificate:  t.HostNameInCertificate.ValueString(),
+		AllowInsecure:          t.AllowInsecure.ValueBool(),
+	}
+}
+
+func validateSSHTunnel(t *SSHTunnel, diags *diag.Diagnostics) {
+	if err := toSSHTunnel(t).Validate(); err != nil {
+		diags.AddAttributeError(path.Root("ssh_tunnel"), "Invalid SSH Tunnel Configuration", err.Error())
+	}
+}
+
+func toSSHTunnel(t *SSHTunnel) *mssql.SSHTunnel {
+	return &mssql.SSHTunnel{
+		Host:                 t.Host.ValueString(),
+		Port:                 t.Port.ValueInt64(),
+		User:                 t.User.ValueString(),
+		PrivateKey:           t.PrivateKey.ValueString(),
+		PrivateKeyPassphrase: t.PrivateKeyPassphrase.ValueString(),
+		UseAgent:             t.UseAgent.ValueBool(),
+		HostKey:              t.HostKey.ValueString(),
+		KnownHostsFile:       t.KnownHostsFile.ValueString(),
+	}
+}
+
+// toRetry converts the retry block, applying mssql.DefaultRetry for unset
+// attributes.
+func toRetry(r *Retry, diags *diag.Diagnostics) *mssql.Retry {
+	retry := mssql.DefaultRetry
+	if !r.MaxAttempts.IsNull() {
+		retry.MaxAttempts = int(r.MaxAttempts.ValueInt64())
+	}
+	// The duration strings were checked by durationValidator.
+	if !r.InitialBackoff.IsNull() {
+		retry.InitialBackoff, _ = time.ParseDuration(r.InitialBackoff.ValueString())
+	}
+	if !r.MaxBackoff.IsNull() {
+		retry.MaxBackoff, _ = time.ParseDuration(r.MaxBackoff.ValueString())
+	}
+
+	if err := retry.Validate(); err != nil {
+		diags.AddAttributeError(path.Root("retry"), "Invalid Retry Configuration", err.Error())
+		return nil
+	}
+	return &retry
+}
+
+// toPool converts the pool block, applying mssql.DefaultPool for unset
+// attributes.
+func toPool(p *Pool, diags *diag.Diagnostics) *mssql.Pool {
+	pool := mssql.DefaultPool
+	if !p.MaxOpenConns.IsNull() {
+		pool.MaxOpenConns = int(p.MaxOpenConns.ValueInt64())
+	}
+	if !p.MaxIdleConns.IsNull() {
+		pool.MaxIdleConns = int(p.MaxIdleConns.ValueInt64())
+	}
+	// The duration string was checked by durationValidator.
+	if !p.ConnMaxLifetime.IsNull() {
+		pool.ConnMaxLifetime, _ = time.ParseDuration(p.ConnMaxLifetime.ValueString())
+	}
+	if !p.MaxDatabasePools.IsNull() {
+		pool.MaxDatabasePools = int(p.MaxDatabasePools.ValueInt64())
+	}
+
+	if err := pool.Validate(); err != nil {
+		diags.AddAttributeError(path.Root("pool"), "Invalid Pool Configuration", err.Error())
+		return nil
+	}
+	return &pool
+}
+
+// locks is shared by every provider instance, so that aliases configured for
+// the same server coordinate their DDL.
+var locks = core.NewLocks()
+
+// clients tracks every client created by Configure so that their connection
+// pools can be closed when the provider process exits.
+var clients struct {
+	sync.Mutex
+	all []mssql.SqlClient
+}
+
+func registerClient(c mssql.SqlClient) {
+	clients.Lock()
+	defer clients.Unlock()
+	clients.all = append(clients.all, c)
+}
+
+// Shutdown closes the connection pools of every client created by the
+// provider. It is called once the plugin server has stopped.
+func Shutdown() {
+	clients.Lock()
+	defer clients.Unlock()
+	for _, c := range clients.all {
+		_ = c.Close()
+	}
+	clients.all = nil
 }
 
 func (p *MssqlProvider) Resources(ctx context.Context) []func() resource.Resource {
-	return []func() resource.Resource{
+	factories := []func() resource.Resource{
 		NewMssqlUserResource,
 		NewMssqlRoleResource,
 		NewMssqlRoleAssignmentResource,
 		NewMssqlGrantResource,
+		NewMssqlServerPermissionResource,
 		NewMssqlDatabaseResource,
 		NewMssqlLoginResource,
 		NewMssqlScriptResource,
 	}
+	for i, factory := range factories {
+		factories[i] = p.configured(factory)
+	}
+	return factories
 }
 
 func (p *MssqlProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
+)
+
+// reservedParameters maps the connection string keys that first-class
+// provider attributes control to those attributes.
+var reservedParameters = map[string][]string{
+	"server":                 {"host"},
+	"port":                   {"port"},
+	"database":               {"database"},
+	"user id":                {"sql_auth", "azure_auth"},
+	"password":               {"sql_auth", "azure_auth"},
+	"encrypt":                {"tls"},
+	"trustservercertificate": {"tls"},
+	"certificate":            {"tls"},
+	"hostnameincertificate":  {"tls"},
+}
+
+// attributeSet reports whether the named first-class attribute is configured.
+func attributeSet(data *MssqlProviderModel, name string) bool {
+	switch name {
+	case "host":
+		return !data.Host.IsNull()
+	case "port":
+		return !data.Port.IsNull()
+	case "database":
+		return !data.Database.IsNull()
+	case "sql_auth":
+		return data.SqlAuth != nil
+	case "azure_auth":
+		return data.AzureAuth != nil
+	case "tls":
+		return data.TLS != nil
+	}
+	return false
+}
+
+// validateConnectionParameters reports `parameters` that set keys owned by
+// first-class attributes, and `connection_string` parameters that conflict
+// with configured attributes. Unknown values are skipped.
+func validateConnectionParameters(data *MssqlProviderModel, diags *diag.Diagnostics) {
+	if !data.Parameters.IsNull() && !data.Parameters.IsUnknown() {
+		for key := range data.Parameters.Elements() {
+			p := path.Root("parameters").AtMapKey(key)
+			if attrs, ok := reservedParameters[mssql.NormalizeParameterKey(key)]; ok {
+				diags.AddAttributeError(p,
+					"Reserved Connection Parameter",
+					fmt.Sprintf("The %q parameter is controlled by the `%s` attribute; set it there instead.", key, attrs[0]),
+				)
+			}
+		}
+	}
+
+	if data.ConnectionString.IsNull() || data.ConnectionString.IsUnknown() {
+		return
+	}
+	params, err := mssql.ParseConnectionString(data.ConnectionString.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("connection_string"), "Invalid Connection String", err.Error())
+		return
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, attr := range reservedParameters[key] {
+			if attributeSet(data, attr) {
+				diags.AddAttributeError(
+					path.Root("connection_string"),
+					"Conflicting Connection String Parameter",
+					fmt.Sprintf("The connection string sets %q, which conflicts with the `%s` attribute. Remove one of them.", key, attr),
+				)
+			}
+		}
+	}
+}
+
+// applyConnectionString fills unset host, port, database and sql_auth
+// attributes from `connection_string`, so that it takes precedence over the
+// environment and the usual validation applies.
+func applyConnectionString(data *MssqlProviderModel, diags *diag.Diagnostics) {
+	if data.ConnectionString.IsNull() || data.ConnectionString.IsUnknown() {
+		return
+	}
+	params, err := mssql.ParseConnectionString(data.ConnectionString.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("connection_string"), "Invalid Connection String", err.Error())
+		return
+	}
+
+	if v := params["server"]; v != "" && data.Host.IsNull() {
+		data.Host = types.StringValue(v)
+	}
+	if v := params["port"]; v != "" && data.Port.IsNull() {
+		port, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root("connection_string"),
+				"Invalid Sql Server Port",
+				fmt.Sprintf("The connection string port %q is not a valid port number.", v),
+			)
+			return
+		}
+		data.Port = types.Int64Value(port)
+	}
+	if v := params["database"]; v != "" && data.Database.IsNull() {
+		data.Database = types.StringValue(v)
+	}
+	if v := params["user id"]; v != "" && data.SqlAuth == nil && data.AzureAuth == nil {
+		data.SqlAuth = &SqlAuth{
+			Username:          types.StringValue(v),
+			Password:          types.StringValue(params["password"]),
+			PasswordFile:      types.StringNull(),
+			CredentialCommand: types.ListNull(types.StringType),
+		}
+	}
+}
+
+// toParameters converts the `parameters` map.
+func toParameters(m types.Map) map[string]string {
+	if m.IsNull() {
+		return nil
+	}
+	params := make(map[string]string, len(m.Elements()))
+	for key, value := range m.Elements() {
+		if s, ok := value.(types.String); ok {
+			params[key] = s.ValueString()
+		}
+	}
+	return params
+}
@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Environment variables used as fallbacks for provider attributes that are
+// not set in configuration.
+const (
+	envHost     = "MSSQL_HOST"
+	envPort     = "MSSQL_PORT"
+	envDatabase = "MSSQL_DATABASE"
+	envServerId = "MSSQL_SERVER_ID"
+	envUsername = "MSSQL_USERNAME"
+	envPassword = "MSSQL_PASSWORD"
+
+	envAzureMode                      = "MSSQL_AZURE_MODE"
+	envAzureTenantID                  = "MSSQL_AZURE_TENANT_ID"
+	envAzureClientID                  = "MSSQL_AZURE_CLIENT_ID"
+	envAzureClientSecret              = "MSSQL_AZURE_CLIENT_SECRET"
+	envAzureClientCertificatePath     = "MSSQL_AZURE_CLIENT_CERTIFICATE_PATH"
+	envAzureClientCertificatePassword = "MSSQL_AZURE_CLIENT_CERTIFICATE_PASSWORD"
+	envAzureFederatedTokenFile        = "MSSQL_AZURE_FEDERATED_TOKEN_FILE"
+	envAzureAuthorityHost             = "MSSQL_AZURE_AUTHORITY_HOST"
+)
+
+// stringFromEnv returns v, or the value of env when v is null and env is set.
+// Unknown values are returned as-is so that they are reported by validation.
+func stringFromEnv(v types.String, env string) types.String {
+	if !v.IsNull() {
+		return v
+	}
+	if val, ok := os.LookupEnv(env); ok && val != "" {
+		return types.StringValue(val)
+	}
+	return v
+}
+
+// envSet reports whether any of the named environment variables are non-empty.
+func envSet(names ...string) bool {
+	for _, name := range names {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// applyEnvironment fills unset provider attributes from MSSQL_* environment
+// variables. Values set in configuration always take precedence.
+func applyEnvironment(data *MssqlProviderModel, diags *diag.Diagnostics) {
+	data.Host = stringFromEnv(data.Host, envHost)
+	data.Database = stringFromEnv(data.Database, envDatabase)
+	data.ServerId = stringFromEnv(data.ServerId, envServerId)
+
+	if data.Port.IsNull() {
+		if raw := os.Getenv(envPort); raw != "" {
+			port, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+			if err != nil || port <= 0 || port > 65535 {
+				diags.AddAttributeError(
+					path.Root("port"),
+					"Invalid Sql Server Port",
+					fmt.Sprintf("The %s environment variable must be a valid TCP port; got %q.", envPort, raw),
+				)
+			} else {
+				data.Port = types.Int64Value(port)
+			}
+		}
+	}
+
+	// Only synthesize an authentication block from the environment when none
+	// is configured, so that an explicit sql_auth never turns into a conflict.
+	if data.SqlAuth == nil && data.AzureAuth == nil {
+		sqlEnv := envSet(envUsername, envPassword)
+		azureEnv := envSet(envAzureMode)
+		switch {
+		case sqlEnv && azureEnv:
+			diags.AddError(
+				"Conflicting Authentication",
+				fmt.Sprintf("Both %s/%s and %s are set. Only one authentication method may be supplied through the environment.", envUsername, envPassword, envAzureMode),
+			)
+			return
+		case sqlEnv:
+			data.SqlAuth = &SqlAuth{
+				Username:          types.StringNull(),
+				Password:          types.StringNull(),
+				PasswordFile:      types.StringNull(),
+				CredentialCommand: types.ListNull(types.StringType),
+			}
+		case azureEnv:
+			data.AzureAuth = &AzureAuth{
+				Mode:                      types.StringNull(),
+				TenantID:                  types.StringNull(),
+				ClientID:                  types.StringNull(),
+				ClientSecret:              types.StringNull(),
+				ClientCertificatePath:     types.StringNull(),
+				ClientCertificatePassword: types.StringNull(),
+				FederatedTokenFile:        types.StringNull(),
+				AuthorityHost:             types.StringNull(),
+			}
+		}
+	}
+
+	if data.SqlAuth != nil {
+		data.SqlAuth.Username = stringFromEnv(data.SqlAuth.Username, envUsername)
+		// MSSQL_PASSWORD would conflict with a configured password source.
+		if data.SqlAuth.PasswordFile.IsNull() && data.SqlAuth.CredentialCommand.IsNull() {
+			data.SqlAuth.Password = stringFromEnv(data.SqlAuth.Password, envPassword)
+		}
+	}
+
+	if data.AzureAuth != nil {
+		a := data.AzureAuth
+		a.Mode = stringFromEnv(a.Mode, envAzureMode)
+		a.TenantID = stringFromEnv(a.TenantID, envAzureTenantID)
+		a.ClientID = stringFromEnv(a.ClientID, envAzureClientID)
+		a.ClientSecret = stringFromEnv(a.ClientSecret, envAzureClientSecret)
+		a.ClientCertificatePath = stringFromEnv(a.ClientCertificatePath, envAzureClientCertificatePath)
+		a.ClientCertificatePassword = stringFromEnv(a.ClientCertificatePassword, envAzureClientCertificatePassword)
+		a.FederatedTokenFile = stringFromEnv(a.FederatedTokenFile, envAzureFederatedTokenFile)
+		a.AuthorityHost = stringFromEnv(a.AuthorityHost, envAzureAuthorityHost)
+	}
+}
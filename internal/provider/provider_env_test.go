@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func nullProviderModel() MssqlProviderModel {
+	return MssqlProviderModel{
+		Host:     types.StringNull(),
+		Port:     types.Int64Null(),
+		Database: types.StringNull(),
+		ServerId: types.StringNull(),
+	}
+}
+
+func Test_applyEnvironment_FillsUnsetAttributes(t *testing.T) {
+	t.Setenv(envHost, "sql.example.com")
+	t.Setenv(envPort, "14330")
+	t.Setenv(envDatabase, "appdb")
+	t.Setenv(envServerId, "sql-prod")
+	t.Setenv(envUsername, "deployer")
+	t.Setenv(envPassword, "s3cret")
+
+	data := nullProviderModel()
+	var diags diag.Diagnostics
+	applyEnvironment(&data, &diags)
+
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if data.Host.ValueString() != "sql.example.com" || data.Port.ValueInt64() != 14330 || data.Database.ValueString() != "appdb" {
+		t.Fatalf("unexpected connection values: host=%s port=%d database=%s", data.Host, data.Port.ValueInt64(), data.Database)
+	}
+	if data.ServerId.ValueString() != "sql-prod" {
+		t.Fatalf("server_id = %s, want sql-prod", data.ServerId)
+	}
+	if data.SqlAuth == nil || data.SqlAuth.Username.ValueString() != "deployer" || data.SqlAuth.Password.ValueString() != "s3cret" {
+		t.Fatalf("expected sql_auth from environment, got %+v", data.SqlAuth)
+	}
+	if data.AzureAuth != nil {
+		t.Fatalf("azure_auth should not be synthesized, got %+v", data.AzureAuth)
+	}
+}
+
+func Test_applyEnvironment_ConfigTakesPrecedence(t *testing.T) {
+	t.Setenv(envHost, "env-host")
+	t.Setenv(envPassword, "env-password")
+
+	data := nullProviderModel()
+	data.Host = types.StringValue("config-host")
+	data.SqlAuth = &SqlAuth{Username: types.StringValue("sa"), Password: types.StringNull()}
+
+	var diags diag.Diagnostics
+	applyEnvironment(&data, &diags)
+
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if data.Host.ValueString() != "config-host" {
+		t.Fatalf("host = %s, want config-host", data.Host)
+	}
+	if data.SqlAuth.Username.ValueString() != "sa" || data.SqlAuth.Password.ValueString() != "env-password" {
+		t.Fatalf("unexpected sql_auth: %+v", data.SqlAuth)
+	}
+}
+
+func Test_applyEnvironment_AzureAuth(t *testing.T) {
+	t.Setenv(envAzureMode, "service_principal")
+	t.Setenv(envAzureTenantID, "tenant")
+	t.Setenv(envAzureClientID, "client")
+	t.Setenv(envAzureClientSecret, "secret")
+
+	data := nullProviderModel()
+	var diags diag.Diagnostics
+	applyEnvironment(&data, &diags)
+
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if data.SqlAuth != nil {
+		t.Fatalf("sql_auth should not be synthesized, got %+v", data.SqlAuth)
+	}
+	if err := toAzureAuth(data.AzureAuth).Validate(); err != nil {
+		t.Fatalf("azure_auth from environment is invalid: %v", err)
+	}
+}
+
+func Test_applyEnvironment_Errors(t *testing.T) {
+	t.Run("invalid port", func(t *testing.T) {
+		t.Setenv(envPort, "not-a-port")
+		data := nullProviderModel()
+		var diags diag.Diagnostics
+		applyEnvironment(&data, &diags)
+		if !diags.HasError() {
+			t.Fatalf("expected error for invalid %s", envPort)
+		}
+	})
+
+	t.Run("conflicting auth", func(t *testing.T) {
+		t.Setenv(envUsername, "sa")
+		t.Setenv(envAzureMode, "default")
+		data := nullProviderModel()
+		var diags diag.Diagnostics
+		applyEnvironment(&data, &diags)
+		if !diags.HasError() {
+			t.Fatalf("expected conflicting authentication error")
+		}
+	})
+}
+
+func Test_applyEnvironment_PasswordSourceIgnoresEnvPassword(t *testing.T) {
+	t.Setenv(envPassword, "env-password")
+
+	data := nullProviderModel()
+	data.SqlAuth = &SqlAuth{
+		Username:          types.StringValue("sa"),
+		Password:          types.StringNull(),
+		PasswordFile:      types.StringValue("/run/secrets/sql"),
+		CredentialCommand: types.ListNull(types.StringType),
+	}
+
+	var diags diag.Diagnostics
+	applyEnvironment(&data, &diags)
+	if !data.SqlAuth.Password.IsNull() {
+		t.Fatalf("password = %s, want %s ignored when password_file is set", data.SqlAuth.Password, envPassword)
+	}
+}
@@ -9,13 +9,20 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
 	"time"
 
 	_ "github.com/microsoft/go-mssqldb"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
 )
 
 const (
@@ -95,3 +102,199 @@ func cleanupTestDatabases(saPassword string) error {
 	}
 	return nil
 }
+
+// testAccServerCertificate returns the self-signed certificate generated by
+// docker_compose/gen_cert.sh and presented by the test SQL Server.
+func testAccServerCertificate(t *testing.T) string {
+	path, err := filepath.Abs(filepath.Join("..", "..", "docker_compose", "certs", "mssql.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Skipf("server certificate not found (%v); start the stack with docker_compose/start.sh", err)
+	}
+	return path
+}
+
+func testAccProviderConfigWithTLS(tls string) string {
+	return fmt.Sprintf(`
+provider "mssql" {
+  host     = "127.0.0.1"
+  database = "testdb"
+  sql_auth = {
+    username = "sa"
+    password = "Testing@6CD21E2E-7028-4AE0-923E-B11288822489"
+  }
+  tls = {
+%s
+  }
+}
+`, tls)
+}
+
+func TestAccProvider_TLS(t *testing.T) {
+	testAccPreCheck(t)
+	cert := testAccServerCertificate(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				// Pinning the self-signed certificate validates the server.
+				Config: testAccProviderConfigWithTLS(fmt.Sprintf(`
+    encrypt                  = "mandatory"
+    certificate              = %q
+    host_name_in_certificate = "localhost"
+`, cert)) + `
+resource "mssql_role" "test" {
+  name = "test_role_tls"
+}
+`,
+				Check: resource.TestCheckResourceAttr("mssql_role.test", "name", "test_role_tls"),
+			},
+			{
+				// Without the pinned certificate, the self-signed certificate is rejected.
+				Config: testAccProviderConfigWithTLS(`
+    encrypt = "mandatory"
+`) + `
+resource "mssql_role" "test" {
+  name = "test_role_tls"
+}
+`,
+				ExpectError: regexp.MustCompile(`certificate`),
+			},
+			{
+				Config: testAccProviderConfigWithTLS(`
+    trust_server_certificate = true
+`) + `
+resource "mssql_role" "test" {
+  name = "test_role_tls"
+}
+`,
+				ExpectError: regexp.MustCompile(`allow_insecure`),
+			},
+			{
+				Config: testAccProviderConfigWithTLS(`
+    trust_server_certificate = true
+    allow_insecure           = true
+`) + `
+resource "mssql_role" "test" {
+  name = "test_role_tls"
+}
+`,
+				Check: resource.TestCheckResourceAttr("mssql_role.test", "name", "test_role_tls"),
+			},
+		},
+	})
+}
+
+func Test_toRetry(t *testing.T) {
+	var diags diag.Diagnostics
+	got := toRetry(&Retry{
+		MaxAttempts:    types.Int64Value(6),
+		InitialBackoff: types.StringNull(),
+		MaxBackoff:     types.StringValue("1m"),
+	}, &diags)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	want := mssql.Retry{MaxAttempts: 6, InitialBackoff: mssql.DefaultRetry.InitialBackoff, MaxBackoff: time.Minute}
+	if *got != want {
+		t.Fatalf("toRetry() = %+v, want %+v", *got, want)
+	}
+
+	toRetry(&Retry{
+		MaxAttempts:    types.Int64Value(0),
+		InitialBackoff: types.StringNull(),
+		MaxBackoff:     types.StringNull(),
+	}, &diags)
+	if !diags.HasError() {
+		t.Fatalf("expected error for max_attempts = 0")
+	}
+}
+
+func Test_configUnknown(t *testing.T) {
+	data := nullProviderModel()
+	data.Host = types.StringValue("sql.example.com")
+	data.SqlAuth = &SqlAuth{Username: types.StringValue("sa"), Password: types.StringValue("pw")}
+	if configUnknown(&data) {
+		t.Fatalf("configUnknown() = true for a known configuration")
+	}
+
+	data.SqlAuth.Password = types.StringUnknown()
+	if !configUnknown(&data) {
+		t.Fatalf("configUnknown() = false with an unknown sql_auth.password")
+	}
+
+	data = nullProviderModel()
+	data.Host = types.StringUnknown()
+	if !configUnknown(&data) {
+		t.Fatalf("configUnknown() = false with an unknown host")
+	}
+}
+
+func Test_providerServerId(t *testing.T) {
+	data := nullProviderModel()
+	data.Host = types.StringValue("sql.example.com")
+	data.Database = types.StringUnknown()
+	if got, ok := providerServerId(&data); !ok || got != "sql.example.com:1433" {
+		t.Fatalf("providerServerId() = %q, %v, want sql.example.com:1433", got, ok)
+	}
+
+	data.Port = types.Int64Unknown()
+	if got, ok := providerServerId(&data); ok || got != "" {
+		t.Fatalf("providerServerId() with an unknown port = %q, %v, want unknown", got, ok)
+	}
+
+	data.ServerId = types.StringValue("primary")
+	if got, ok := providerServerId(&data); !ok || got != "primary" {
+		t.Fatalf("providerServerId() = %q, %v, want primary", got, ok)
+	}
+
+	data = nullProviderModel()
+	data.Host = types.StringUnknown()
+	if _, ok := providerServerId(&data); ok {
+		t.Fatal("providerServerId() with an unknown host reported a server ID")
+	}
+}
+
+func Test_validateSqlAuth(t *testing.T) {
+	command := types.ListValueMust(types.StringType, []attr.Value{types.StringValue("vault-creds")})
+	tests := []struct {
+		name    string
+		auth    SqlAuth
+		wantErr string
+	}{
+		{name: "password", auth: SqlAuth{Username: types.StringValue("sa"), Password: types.StringValue("pw")}},
+		{name: "password file", auth: SqlAuth{Username: types.StringValue("sa"), PasswordFile: types.StringValue("/run/secrets/sql")}},
+		{name: "command without username", auth: SqlAuth{CredentialCommand: command}},
+		{name: "missing password", auth: SqlAuth{Username: types.StringValue("sa")}, wantErr: "Missing SQL Password"},
+		{name: "file without username", auth: SqlAuth{PasswordFile: types.StringValue("/run/secrets/sql")}, wantErr: "Missing SQL Username"},
+		{
+			name:    "password and command",
+			auth:    SqlAuth{Username: types.StringValue("sa"), Password: types.StringValue("pw"), CredentialCommand: command},
+			wantErr: "Conflicting SQL Password Sources",
+		},
+		{
+			name:    "empty command",
+			auth:    SqlAuth{CredentialCommand: types.ListValueMust(types.StringType, nil)},
+			wantErr: "Invalid SQL Credential Command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var diags diag.Diagnostics
+			validateSqlAuth(&tt.auth, &diags)
+			if tt.wantErr == "" {
+				if diags.HasError() {
+					t.Fatalf("unexpected diagnostics: %v", diags)
+				}
+				return
+			}
+			if !diags.HasError() || diags.Errors()[0].Summary() != tt.wantErr {
+				t.Fatalf("diagnostics = %v, want %q", diags, tt.wantErr)
+			}
+		})
+	}
+}
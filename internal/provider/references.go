@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/vsabella/terraform-provider-mssql/internal/core"
+)
+
+// references checks during plan that the databases, principals and
+// securables a resource refers to exist, so that a typo fails the plan
+// instead of the apply. References that are unknown, or that name objects
+// other resources plan to create (see core.Planned), are skipped.
+type references struct {
+	ctx   context.Context
+	data  core.ProviderData
+	diags *diag.Diagnostics
+}
+
+// checkReferences returns the checker for the references of a planned
+// resource. It reports false on destroy, for resources whose plan is
+// unchanged, and when the provider cannot reach the server.
+func checkReferences(ctx context.Context, data core.ProviderData, req resource.ModifyPlanRequest, diags *diag.Diagnostics) (references, bool) {
+	if req.Plan.Raw.IsNull() || data.Client == nil || data.Unconfigured {
+		return references{}, false
+	}
+	if !req.State.Raw.IsNull() && req.Plan.Raw.Equal(req.State.Raw) {
+		return references{}, false
+	}
+	return references{ctx: ctx, data: data, diags: diags}, true
+}
+
+// plannedDatabase returns the database of a resource with an optional
+// database attribute, which defaults to the provider's, and whether it is
+// known.
+func plannedDatabase(data core.ProviderData, database types.String) (string, bool) {
+	if database.IsUnknown() {
+		return "", false
+	}
+	if database.IsNull() || database.ValueString() == "" {
+		return data.Database, true
+	}
+	return database.ValueString(), true
+}
+
+// known returns the value of a reference, and false when it is unknown or
+// unset and so cannot be checked.
+func known(v types.String) (string, bool) {
+	if v.IsUnknown() || v.IsNull() || v.ValueString() == "" {
+		return "", false
+	}
+	return v.ValueString(), true
+}
+
+// found adds an error when a lookup of a referenced object found nothing,
+// unless a planned script may create it. Other lookup errors only warn,
+// since the check is advisory.
+func (c references) found(attr path.Path, what string, name string, err error, scripted bool) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		if scripted {
+			return false
+		}
+		c.diags.AddAttributeError(attr, fmt.Sprintf("%s Not Found", what),
+			fmt.Sprintf("%s %q does not exist on the server. If it is created in the same run, refer to the resource that creates it, so that Terraform plans that resource first.", what, name))
+		return false
+	}
+	c.diags.AddAttributeWarning(attr, "Unable to Check Reference",
+		fmt.Sprintf("Unable to check that %s %q exists, got error: %s", what, name, err))
+	return false
+}
+
+// database checks a referenced database and reports whether objects in it
+// should be checked too: not when it is missing or planned.
+func (c references) database(attr path.Path, name string) bool {
+	if c.data.Planned.Database(name) {
+		return false
+	}
+	_, err := c.data.Client.GetDatabase(c.ctx, name)
+	return c.found(attr, "Database", name, err, c.data.Planned.AnyScript())
+}
+
+// serverPrincipal checks a referenced login or server role.
+func (c references) serverPrincipal(attr path.Path, what string, v types.String) {
+	name, ok := known(v)
+	if !ok || c.data.Planned.Login(name) {
+		return
+	}
+	_, err := c.data.Client.GetServerPrincipalId(c.ctx, name)
+	c.found(attr, what, name, err, c.data.Planned.AnyScript())
+}
+
+// databasePrincipal checks a referenced user or role in a database.
+func (c references) databasePrincipal(attr path.Path, database string, v types.String) {
+	name, ok := known(v)
+	if !ok || c.data.Planned.Principal(database, name) {
+		return
+	}
+	_, err := c.data.Client.GetDatabasePrincipalId(c.ctx, database, name)
+	c.found(attr, "Database Principal", name, err, c.data.Planned.Script(database))
+}
+
+// databaseRole checks a referenced role in a database.
+func (c references) databaseRole(attr path.Path, database string, v types.String) {
+	name, ok := known(v)
+	if !ok || c.data.Planned.Principal(database, name) {
+		return
+	}
+	_, err := c.data.Client.GetRole(c.ctx, database, name)
+	c.found(attr, "Database Role", name, err, c.data.Planned.Script(database))
+}
+
+// securable checks a referenced securable, such as a schema or table, in a
+// database. Users and roles may be planned like other principals.
+func (c references) securable(attr path.Path, database string, objectType types.String, objectName types.String) {
+	typ, ok := known(objectType)
+	if !ok {
+		return
+	}
+	name, ok := known(objectName)
+	if !ok {
+		return
+	}
+	switch strings.ToUpper(strings.TrimSpace(typ)) {
+	case "USER", "ROLE", "APPLICATION ROLE":
+		if c.data.Planned.Principal(database, name) {
+			return
+		}
+	}
+	_, err := c.data.Client.GetSecurableId(c.ctx, database, typ, name)
+	c.found(attr, "Securable", name, err, c.data.Planned.Script(database))
+}
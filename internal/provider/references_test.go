@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/vsabella/terraform-provider-mssql/internal/core"
+)
+
+// planCreate runs ModifyPlan for the creation of a resource with the given
+// configured attributes; the others are null.
+func planCreate(t *testing.T, r resource.ResourceWithModifyPlan, attrs map[string]tftypes.Value) diag.Diagnostics {
+	t.Helper()
+	ctx := context.Background()
+
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	typ := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := map[string]tftypes.Value{}
+	for name, attrType := range typ.AttributeTypes {
+		values[name] = tftypes.NewValue(attrType, nil)
+		if v, ok := attrs[name]; ok {
+			values[name] = v
+		}
+	}
+	raw := tftypes.NewValue(typ, values)
+
+	req := resource.ModifyPlanRequest{
+		Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: raw},
+		Plan:   tfsdk.Plan{Schema: schemaResp.Schema, Raw: raw},
+		State:  tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(typ, nil)},
+	}
+	resp := resource.ModifyPlanResponse{Plan: req.Plan}
+	r.ModifyPlan(ctx, req, &resp)
+	return resp.Diagnostics
+}
+
+func str(s string) tftypes.Value {
+	return tftypes.NewValue(tftypes.String, s)
+}
+
+func Test_ModifyPlan_References(t *testing.T) {
+	_, data := newMoveTestServer(t)
+	data.Planned = core.NewPlanned()
+	unknown := tftypes.NewValue(tftypes.String, tftypes.UnknownValue)
+
+	tests := []struct {
+		name    string
+		r       resource.ResourceWithModifyPlan
+		attrs   map[string]tftypes.Value
+		wantErr bool
+	}{
+		{"user with login", &MssqlUserResource{ctx: data},
+			map[string]tftypes.Value{"database": str("app"), "username": str("svc"), "login_name": str("app")}, false},
+		{"user with missing login", &MssqlUserResource{ctx: data},
+			map[string]tftypes.Value{"database": str("app"), "username": str("svc"), "login_name": str("missing")}, true},
+		{"user with unknown login", &MssqlUserResource{ctx: data},
+			map[string]tftypes.Value{"database": str("app"), "username": str("svc"), "login_name": unknown}, false},
+		{"user in missing database", &MssqlUserResource{ctx: data},
+			map[string]tftypes.Value{"database": str("missing"), "username": str("svc"), "login_name": str("app")}, true},
+		{"login with missing default database", &MssqlLoginResource{ctx: data},
+			map[string]tftypes.Value{"name": str("svc"), "password": str("s3cret!Pass"), "default_database": str("missing")}, true},
+		{"grant to user", &MssqlGrantResource{ctx: data},
+			map[string]tftypes.Value{"database": str("app"), "permission": str("SELECT"), "principal": str("app_user"), "object_type": str("SCHEMA"), "object_name": str("sales")}, false},
+		{"grant to missing principal", &MssqlGrantResource{ctx: data},
+			map[string]tftypes.Value{"database": str("app"), "permission": str("CONNECT"), "principal": str("missing")}, true},
+		{"grant on missing schema", &MssqlGrantResource{ctx: data},
+			map[string]tftypes.Value{"database": str("app"), "permission": str("SELECT"), "principal": str("app_user"), "object_type": str("SCHEMA"), "object_name": str("missing")}, true},
+		{"grant on missing table", &MssqlGrantResource{ctx: data},
+			map[string]tftypes.Value{"database": str("app"), "permission": str("SELECT"), "principal": str("app_user"), "object_type": str("TABLE"), "object_name": str("sales.orders")}, true},
+		{"grant on role", &MssqlGrantResource{ctx: data},
+			map[string]tftypes.Value{"database": str("app"), "permission": str("ALTER"), "principal": str("app_user"), "object_type": str("ROLE"), "object_name": str("readers")}, false},
+		{"grant on user as role", &MssqlGrantResource{ctx: data},
+			map[string]tftypes.Value{"database": str("app"), "permission": str("ALTER"), "principal": str("app_user"), "object_type": str("ROLE"), "object_name": str("app_user")}, true},
+		{"grant on missing certificate", &MssqlGrantResource{ctx: data},
+			map[string]tftypes.Value{"database": str("app"), "permission": str("CONTROL"), "principal": str("app_user"), "object_type": str("CERTIFICATE"), "object_name": str("CodeSigning")}, true},
+		{"role assignment", &MssqlRoleAssignmentResource{ctx: data},
+			map[string]tftypes.Value{"database": str("app"), "role": str("readers"), "principal": str("app_user")}, false},
+		{"role assignment to missing role", &MssqlRoleAssignmentResource{ctx: data},
+			map[string]tftypes.Value{"database": str("app"), "role": str("writers"), "principal": str("app_user")}, true},
+		{"role assignment of a user as role", &MssqlRoleAssignmentResource{ctx: data},
+			map[string]tftypes.Value{"database": str("app"), "role": str("app_user"), "principal": str("readers")}, true},
+		{"server role assignment", &MssqlRoleAssignmentResource{ctx: data},
+			map[string]tftypes.Value{"role": str("sysadmin"), "principal": str("app"), "server_role": tftypes.NewValue(tftypes.Bool, true)}, false},
+		{"server role assignment of missing login", &MssqlRoleAssignmentResource{ctx: data},
+			map[string]tftypes.Value{"role": str("sysadmin"), "principal": str("missing"), "server_role": tftypes.NewValue(tftypes.Bool, true)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := planCreate(t, tt.r, tt.attrs)
+			if diags.HasError() != tt.wantErr {
+				t.Fatalf("ModifyPlan() diagnostics = %v, want error %v", diags, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Test_ModifyPlan_PlannedReferences plans resources in dependency order, as
+// Terraform does, and checks that references to objects planned earlier in
+// the run are not reported as missing.
+func Test_ModifyPlan_PlannedReferences(t *testing.T) {
+	_, data := newMoveTestServer(t)
+	data.Planned = core.NewPlanned()
+
+	steps := []struct {
+		r     resource.ResourceWithModifyPlan
+		attrs map[string]tftypes.Value
+	}{
+		{&MssqlDatabaseResource{ctx: data}, map[string]tftypes.Value{"name": str("reporting")}},
+		{&MssqlLoginResource{ctx: data}, map[string]tftypes.Value{"name": str("etl"), "password": str("s3cret!Pass"), "default_database": str("reporting")}},
+		{&MssqlUserResource{ctx: data}, map[string]tftypes.Value{"database": str("app"), "username": str("etl"), "login_name": str("etl")}},
+		{&MssqlRoleResource{ctx: data}, map[string]tftypes.Value{"database": str("app"), "name": str("loaders")}},
+		{&MssqlRoleAssignmentResource{ctx: data}, map[string]tftypes.Value{"database": str("app"), "role": str("loaders"), "principal": str("etl")}},
+		{&MssqlRoleAssignmentResource{ctx: data}, map[string]tftypes.Value{"role": str("dbcreator"), "principal": str("etl"), "server_role": tftypes.NewValue(tftypes.Bool, true)}},
+		{&MssqlScriptResource{ctx: data}, map[string]tftypes.Value{"database_name": str("app"), "name": str("staging"), "create_script": str("CREATE TABLE sales.staging (id int)"), "delete_script": str("DROP TABLE sales.staging")}},
+		{&MssqlGrantResource{ctx: data}, map[string]tftypes.Value{"database": str("app"), "permission": str("INSERT"), "principal": str("loaders"), "object_type": str("TABLE"), "object_name": str("sales.staging")}},
+		{&MssqlGrantResource{ctx: data}, map[string]tftypes.Value{"database": str("reporting"), "permission": str("CONNECT"), "principal": str("etl")}},
+	}
+	for i, step := range steps {
+		if diags := planCreate(t, step.r, step.attrs); diags.HasError() {
+			t.Fatalf("step %d: ModifyPlan() diagnostics = %v", i, diags)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// resourceState returns the state of a resource holding the model data.
+func resourceState(t *testing.T, r resource.Resource, data any) tfsdk.State {
+	t.Helper()
+	ctx := context.Background()
+
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	state := tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)}
+	if diags := state.Set(ctx, data); diags.HasError() {
+		t.Fatalf("State.Set() diagnostics = %v", diags)
+	}
+	return state
+}
+
+// createResource runs Create for a planned model and returns the state.
+func createResource(t *testing.T, r resource.Resource, planned any) tfsdk.State {
+	t.Helper()
+	ctx := context.Background()
+
+	plan := resourceState(t, r, planned)
+	resp := resource.CreateResponse{State: tfsdk.State{Schema: plan.Schema, Raw: tftypes.NewValue(plan.Raw.Type(), nil)}}
+	r.Create(ctx, resource.CreateRequest{Plan: tfsdk.Plan{Schema: plan.Schema, Raw: plan.Raw}}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Create() diagnostics = %v", resp.Diagnostics)
+	}
+	return resp.State
+}
+
+// updateResource runs Update for a change of the current state, whose model
+// is M, and returns the new state.
+func updateResource[M any](t *testing.T, r resource.Resource, current tfsdk.State, change func(*M)) tfsdk.State {
+	t.Helper()
+	ctx := context.Background()
+
+	var planned M
+	current.Get(ctx, &planned)
+	change(&planned)
+	plan := resourceState(t, r, &planned)
+	resp := resource.UpdateResponse{State: current}
+	r.Update(ctx, resource.UpdateRequest{Plan: tfsdk.Plan{Schema: plan.Schema, Raw: plan.Raw}, State: current}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Update() diagnostics = %v", resp.Diagnostics)
+	}
+	return resp.State
+}
+
+// readResource runs Read for the current state and returns the refreshed
+// model, and false if Read removed the resource.
+func readResource[M any](t *testing.T, r resource.Resource, current tfsdk.State) (M, bool) {
+	t.Helper()
+	ctx := context.Background()
+
+	resp := resource.ReadResponse{State: current}
+	r.Read(ctx, resource.ReadRequest{State: current}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Read() diagnostics = %v", resp.Diagnostics)
+	}
+	var read M
+	if resp.State.Raw.IsNull() {
+		return read, false
+	}
+	resp.State.Get(ctx, &read)
+	return read, true
+}
+
+// deleteResource runs Delete for the current state and returns its
+// diagnostics.
+func deleteResource(t *testing.T, r resource.Resource, current tfsdk.State) diag.Diagnostics {
+	t.Helper()
+
+	resp := resource.DeleteResponse{State: current}
+	r.Delete(context.Background(), resource.DeleteRequest{State: current}, &resp)
+	return resp.Diagnostics
+}
@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/vsabella/terraform-provider-mssql/internal/core"
+)
+
+// Test_MssqlServerPermissionResource_Securables grants a permission on the
+// server and on each class of server securable, and checks the ID, the
+// securable read back and that Delete revokes the permission.
+func Test_MssqlServerPermissionResource_Securables(t *testing.T) {
+	tests := []struct {
+		name       string
+		permission string
+		objectType types.String
+		objectName types.String
+		wantId     string
+		wantObject string
+	}{
+		{
+			name:       "server",
+			permission: "VIEW SERVER STATE",
+			objectType: types.StringNull(),
+			objectName: types.StringNull(),
+			wantId:     "localhost:1433/app/VIEW%20SERVER%20STATE",
+		},
+		{
+			name:       "login",
+			permission: "IMPERSONATE",
+			objectType: types.StringValue("login"),
+			objectName: types.StringValue("SA"),
+			wantId:     "localhost:1433/app/IMPERSONATE/LOGIN/sa",
+			wantObject: "sa",
+		},
+		{
+			name:       "server role",
+			permission: "VIEW DEFINITION",
+			objectType: types.StringValue("SERVER ROLE"),
+			objectName: types.StringValue("public"),
+			wantId:     "localhost:1433/app/VIEW%20DEFINITION/SERVER%20ROLE/public",
+			wantObject: "public",
+		},
+		{
+			name:       "endpoint",
+			permission: "CONNECT",
+			objectType: types.StringValue("ENDPOINT"),
+			objectName: types.StringValue("tsql default tcp"),
+			wantId:     "localhost:1433/app/CONNECT/ENDPOINT/TSQL%20Default%20TCP",
+			wantObject: "TSQL Default TCP",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, data := newMoveTestServer(t)
+			data.Locks = core.NewLocks()
+			r := &MssqlServerPermissionResource{ctx: data}
+			ctx := context.Background()
+
+			current := createResource(t, r, MssqlServerPermissionResourceModel{
+				Id:              types.StringUnknown(),
+				Permission:      types.StringValue(tt.permission),
+				Principal:       types.StringValue("app"),
+				ObjectType:      tt.objectType,
+				ObjectName:      tt.objectName,
+				State:           types.StringValue("GRANT"),
+				WithGrantOption: types.BoolValue(false),
+			})
+
+			// The ID names the securable as the server does, so that Read
+			// settles it.
+			read, ok := readResource[MssqlServerPermissionResourceModel](t, r, current)
+			if !ok || read.Id.ValueString() != tt.wantId {
+				t.Fatalf("Read() id = %s, want %s", read.Id.ValueString(), tt.wantId)
+			}
+			if read.ObjectName.ValueString() != tt.wantObject || read.ObjectType.IsNull() != tt.objectType.IsNull() {
+				t.Fatalf("Read() securable = %s %s, want %s", read.ObjectType, read.ObjectName, tt.wantObject)
+			}
+
+			if diags := deleteResource(t, r, current); diags.HasError() {
+				t.Fatalf("Delete() diagnostics = %v", diags)
+			}
+			permission := serverPermission(read)
+			if _, err := c.ReadServerPermission(ctx, permission); !errors.Is(err, sql.ErrNoRows) {
+				t.Fatalf("ReadServerPermission() after Delete() error = %v, want sql.ErrNoRows", err)
+			}
+		})
+	}
+}
+
+func Test_decodeServerPermissionId(t *testing.T) {
+	permission, err := decodeServerPermissionId("localhost:1433/dba/IMPERSONATE/LOGIN/etl")
+	if err != nil {
+		t.Fatalf("decodeServerPermissionId() error = %v", err)
+	}
+	if permission.Principal != "dba" || permission.ObjectType != "LOGIN" || permission.ObjectName != "etl" {
+		t.Fatalf("decodeServerPermissionId() = %+v, want IMPERSONATE on LOGIN etl", permission)
+	}
+	if permission, err := decodeServerPermissionId("localhost:1433/%23%23MS_ServerStateReader%23%23/VIEW%20SERVER%20STATE"); err != nil || permission.Principal != "##MS_ServerStateReader##" || permission.ObjectType != "" {
+		t.Fatalf("decodeServerPermissionId() = %+v, %v, want VIEW SERVER STATE on the server", permission, err)
+	}
+	if _, err := decodeServerPermissionId("localhost:1433/dba/IMPERSONATE/LOGIN"); err == nil {
+		t.Fatal("decodeServerPermissionId() of 4 segments error = nil")
+	}
+}
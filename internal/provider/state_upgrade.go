@@ -0,0 +1,136 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/vsabella/terraform-provider-mssql/internal/core"
+)
+
+// schemaVersion is the schema version of every resource. Bump it when an ID
+// format changes or attributes are renamed or removed, and give UpgradeState
+// an upgrader from each prior version. Added attributes need no bump, but
+// the upgraders from earlier versions then decode with the schema of those
+// versions and set the added attributes, as mssql_grant does.
+//
+//   - 0: IDs joined names unescaped, except mssql_grant, which query-escaped
+//     every segment including the server ID.
+//   - 1: IDs path-escape every segment (see formatId), so that names
+//     containing "/", "%" or "+" round-trip.
+//   - 2: IDs start with the provider's server_id, which defaults to the
+//     host:port of earlier versions.
+const schemaVersion = 2
+
+// formatId joins the segments of a resource ID, escaping each one.
+func formatId(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, s := range segments {
+		escaped[i] = url.PathEscape(s)
+	}
+	return strings.Join(escaped, "/")
+}
+
+// parseId splits a resource ID into its n unescaped segments, none of which
+// may be empty. format describes the ID in errors. The server segment is not
+// checked, so IDs starting with host:port and with a server_id both parse.
+func parseId(id string, n int, format string) ([]string, error) {
+	parts := strings.Split(id, "/")
+	if len(parts) != n {
+		return nil, fmt.Errorf("expected id in format %s, got %q", format, id)
+	}
+	for i, part := range parts {
+		s, err := url.PathUnescape(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q: %v", id, err)
+		}
+		if s == "" {
+			return nil, fmt.Errorf("expected id in format %s, got %q", format, id)
+		}
+		parts[i] = s
+	}
+	return parts, nil
+}
+
+// upgradeFromV0 returns the upgrader from schema version 0 for a resource
+// whose attributes have not changed since. upgrade rewrites the decoded
+// prior state, typically its ID. Once the attributes of the resource change,
+// use upgradeModel with a copy of the version 0 schema instead.
+func upgradeFromV0[M any](priorSchema schema.Schema, upgrade func(*M) error) resource.StateUpgrader {
+	return upgradeModel(priorSchema, func(data *M) (M, error) {
+		err := upgrade(data)
+		return *data, err
+	})
+}
+
+// upgradeModel returns an upgrader that decodes prior state with the schema
+// it was written with and converts it to the model of the current schema.
+func upgradeModel[P, M any](priorSchema schema.Schema, convert func(*P) (M, error)) resource.StateUpgrader {
+	return resource.StateUpgrader{
+		PriorSchema: &priorSchema,
+		StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+			var prior P
+			resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+			if resp.Diagnostics.HasError() {
+				return
+			}
+			data, err := convert(&prior)
+			if err != nil {
+				resp.Diagnostics.AddError("Unable to Upgrade Resource State", err.Error())
+				return
+			}
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		},
+	}
+}
+
+// currentSchema returns the schema of a resource.
+func currentSchema(ctx context.Context, r resource.Resource) schema.Schema {
+	var resp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &resp)
+	return resp.Schema
+}
+
+// upgradeServerId returns the server ID of upgraded state: the provider's,
+// or, while the provider configuration is unknown, the server ID at the
+// start of the prior id. Version 0 grant IDs query-escaped it.
+func upgradeServerId(data core.ProviderData, id string, escaped bool) (string, error) {
+	server, _, found := strings.Cut(id, "/")
+	if !found || server == "" {
+		return "", fmt.Errorf("expected id in format <server_id>/..., got %q", id)
+	}
+	if data.ServerID != "" {
+		return data.ServerID, nil
+	}
+	if escaped {
+		return url.QueryUnescape(server)
+	}
+	return url.PathUnescape(server)
+}
+
+// upgradeFromV1 returns the upgrader from schema version 1, which replaces
+// the server segment of the ID with the provider's server_id.
+func upgradeFromV1(priorSchema schema.Schema, data core.ProviderData) resource.StateUpgrader {
+	return resource.StateUpgrader{
+		PriorSchema: &priorSchema,
+		StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+			var id string
+			resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("id"), &id)...)
+			if resp.Diagnostics.HasError() {
+				return
+			}
+			server, err := upgradeServerId(data, id, false)
+			if err != nil {
+				resp.Diagnostics.AddError("Unable to Upgrade Resource State", err.Error())
+				return
+			}
+			_, rest, _ := strings.Cut(id, "/")
+			resp.State.Raw = req.State.Raw
+			resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), formatId(server)+"/"+rest)...)
+		},
+	}
+}
@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/vsabella/terraform-provider-mssql/internal/core"
+	"github.com/vsabella/terraform-provider-mssql/mssql/mssqlfake"
+)
+
+func Test_parseId(t *testing.T) {
+	id := formatId("localhost:1433", "app", "svc/batch", "50% off+more")
+	if id != "localhost:1433/app/svc%2Fbatch/50%25%20off+more" {
+		t.Fatalf("formatId() = %q", id)
+	}
+	parts, err := parseId(id, 4, "<server_id>/<database>/<name>/<other>")
+	if err != nil {
+		t.Fatalf("parseId() error = %v", err)
+	}
+	if parts[2] != "svc/batch" || parts[3] != "50% off+more" {
+		t.Fatalf("parseId() = %q", parts)
+	}
+
+	for _, bad := range []string{"localhost:1433/app", "localhost:1433//x/y", "localhost:1433/app/%zz/y"} {
+		if _, err := parseId(bad, 4, "<server_id>/<database>/<name>/<other>"); err == nil {
+			t.Errorf("parseId(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+// upgradeState upgrades raw state of a resource type through the provider
+// server, as Terraform does on refresh, and returns its attributes.
+func upgradeState(t *testing.T, p provider.Provider, typeName string, version int64, raw []byte) map[string]tftypes.Value {
+	t.Helper()
+	ctx := context.Background()
+	server, err := providerserver.NewProtocol6WithError(p)()
+	if err != nil {
+		t.Fatalf("creating provider server: %v", err)
+	}
+	schemas, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("GetProviderSchema() error = %v", err)
+	}
+	resp, err := server.UpgradeResourceState(ctx, &tfprotov6.UpgradeResourceStateRequest{
+		TypeName: typeName,
+		Version:  version,
+		RawState: &tfprotov6.RawState{JSON: raw},
+	})
+	if err != nil {
+		t.Fatalf("UpgradeResourceState() error = %v", err)
+	}
+	for _, d := range resp.Diagnostics {
+		if d.Severity == tfprotov6.DiagnosticSeverityError {
+			t.Fatalf("UpgradeResourceState() diagnostic: %s: %s", d.Summary, d.Detail)
+		}
+	}
+
+	schema := schemas.ResourceSchemas[typeName]
+	if schema.Version != schemaVersion {
+		t.Errorf("schema version = %d, want %d", schema.Version, schemaVersion)
+	}
+	state, err := resp.UpgradedState.Unmarshal(schema.ValueType())
+	if err != nil {
+		t.Fatalf("decoding upgraded state: %v", err)
+	}
+	var attrs map[string]tftypes.Value
+	if err := state.As(&attrs); err != nil {
+		t.Fatalf("decoding upgraded state: %v", err)
+	}
+	return attrs
+}
+
+func checkAttributes(t *testing.T, attrs map[string]tftypes.Value, want map[string]string) {
+	t.Helper()
+	for name, want := range want {
+		var got string
+		if err := attrs[name].As(&got); err != nil {
+			t.Fatalf("decoding %s: %v", name, err)
+		}
+		if got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// Test_UpgradeState_V0 upgrades state captured from version 0 of each
+// resource with an unconfigured provider, which keeps the server segment.
+func Test_UpgradeState_V0(t *testing.T) {
+	tests := []struct {
+		typeName string
+		want     map[string]string
+	}{
+		{"mssql_user", map[string]string{"id": "localhost:1433/app/svc%2Fbatch", "username": "svc/batch", "login_name": "svc_batch"}},
+		{"mssql_login", map[string]string{"id": "localhost:1433/svc_batch", "password": "Sup3r-Secret!"}},
+		{"mssql_role", map[string]string{"id": "localhost:1433/app/report%20readers"}},
+		{"mssql_role_assignment", map[string]string{"id": "localhost:1433/server/%23%23MS_ServerStateReader%23%23/telemetry"}},
+		{"mssql_grant", map[string]string{"id": "localhost:1433/app/svc%2Fbatch/SELECT/OBJECT/sales.orders", "principal": "svc/batch"}},
+		{"mssql_database", map[string]string{"id": "localhost:1433/app", "recovery_model": "FULL"}},
+		{"mssql_script", map[string]string{"id": "localhost:1433/app/widgets", "version": "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typeName, func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", "state_v0", tt.typeName+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkAttributes(t, upgradeState(t, New("test")(), tt.typeName, 0, raw), tt.want)
+		})
+	}
+}
+
+// Test_UpgradeState_ServerId upgrades version 0 and 1 state with a provider
+// configured with a server_id, which replaces host:port in the IDs.
+func Test_UpgradeState_ServerId(t *testing.T) {
+	p := New("test")().(*MssqlProvider)
+	p.setData(&core.ProviderData{
+		Client:   mssqlfake.New(mssqlfake.Config{}),
+		ServerID: "sql-prod/eu",
+		Locks:    core.NewLocks(),
+	})
+
+	raw, err := os.ReadFile(filepath.Join("testdata", "state_v0", "mssql_grant.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAttributes(t, upgradeState(t, p, "mssql_grant", 0, raw), map[string]string{
+		"id": "sql-prod%2Feu/app/svc%2Fbatch/SELECT/OBJECT/sales.orders",
+	})
+
+	v1 := []byte(`{"id":"10.0.0.5:1433/app/svc%2Fbatch","database":"app","username":"svc/batch","login_name":"svc_batch","sid":"0x01","default_schema":"dbo","external":false,"password":null}`)
+	checkAttributes(t, upgradeState(t, p, "mssql_user", 1, v1), map[string]string{
+		"id":       "sql-prod%2Feu/app/svc%2Fbatch",
+		"username": "svc/batch",
+	})
+
+	database, name, err := parseScriptId("sql-prod%2Feu/app/widgets")
+	if err != nil || database != "app" || name != "widgets" {
+		t.Errorf("parseScriptId() = %q, %q, %v", database, name, err)
+	}
+	database, err = parseDatabaseId("10.0.0.5:1433/app")
+	if err != nil || database != "app" {
+		t.Errorf("parseDatabaseId() = %q, %v", database, err)
+	}
+}
+
+// Test_UpgradeState_GrantDefaults checks that grants upgraded from before
+// state, with_grant_option and columns existed get their defaults, so that
+// a plan without a refresh shows no changes.
+func Test_UpgradeState_GrantDefaults(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "state_v0", "mssql_grant.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1 := []byte(`{"id":"10.0.0.5:1433/app/svc%2Fbatch/CONNECT","database":"app","permission":"CONNECT","principal":"svc/batch","object_type":null,"object_name":null}`)
+
+	for version, raw := range map[int64][]byte{0: raw, 1: v1} {
+		attrs := upgradeState(t, New("test")(), "mssql_grant", version, raw)
+		checkAttributes(t, attrs, map[string]string{"state": "GRANT"})
+		var withGrantOption bool
+		if err := attrs["with_grant_option"].As(&withGrantOption); err != nil || withGrantOption {
+			t.Errorf("version %d: with_grant_option = %v, %v, want false", version, withGrantOption, err)
+		}
+		if !attrs["columns"].IsNull() {
+			t.Errorf("version %d: columns = %v, want null", version, attrs["columns"])
+		}
+	}
+	checkAttributes(t, upgradeState(t, New("test")(), "mssql_grant", 1, v1), map[string]string{
+		"id": "10.0.0.5:1433/app/svc%2Fbatch/CONNECT",
+	})
+}
@@ -3,7 +3,9 @@ package provider
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 )
@@ -69,10 +71,14 @@ func (v databasePermissionValidator) ValidateString(ctx context.Context, req val
 	}
 }
 
-type objectTypeValidator struct{}
+// objectTypeValidator validates an object_type against types, such as
+// mssql.ObjectTypes. Case and surrounding spaces do not matter.
+type objectTypeValidator struct {
+	types []string
+}
 
 func (v objectTypeValidator) Description(ctx context.Context) string {
-	return "Validates that object_type is one of SCHEMA, OBJECT, TABLE, VIEW, PROCEDURE, FUNCTION, or PROC."
+	return fmt.Sprintf("Validates that object_type is one of %s.", strings.Join(v.types, ", "))
 }
 
 func (v objectTypeValidator) MarkdownDescription(ctx context.Context) string {
@@ -89,14 +95,65 @@ func (v objectTypeValidator) ValidateString(ctx context.Context, req validator.S
 		return
 	}
 
-	switch raw {
-	case "SCHEMA", "OBJECT", "TABLE", "VIEW", "PROCEDURE", "FUNCTION", "PROC":
-		return
-	default:
+	if !slices.Contains(v.types, raw) {
 		resp.Diagnostics.AddAttributeError(
 			req.Path,
 			"Invalid object type",
-			fmt.Sprintf("object_type must be one of SCHEMA, OBJECT, TABLE, VIEW, PROCEDURE, FUNCTION, or PROC; got %q", req.ConfigValue.ValueString()),
+			fmt.Sprintf("object_type must be one of %s; got %q", strings.Join(v.types, ", "), req.ConfigValue.ValueString()),
+		)
+	}
+}
+
+type stringOneOfValidator struct {
+	values []string
+}
+
+func (v stringOneOfValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("Validates that the value is one of %s.", strings.Join(v.values, ", "))
+}
+
+func (v stringOneOfValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v stringOneOfValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsUnknown() || req.ConfigValue.IsNull() {
+		return
+	}
+
+	for _, allowed := range v.values {
+		if req.ConfigValue.ValueString() == allowed {
+			return
+		}
+	}
+	resp.Diagnostics.AddAttributeError(
+		req.Path,
+		"Invalid value",
+		fmt.Sprintf("value must be one of %s; got %q", strings.Join(v.values, ", "), req.ConfigValue.ValueString()),
+	)
+}
+
+type durationValidator struct{}
+
+func (v durationValidator) Description(ctx context.Context) string {
+	return "Validates that the value is a non-negative Go duration such as `500ms` or `30s`."
+}
+
+func (v durationValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v durationValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsUnknown() || req.ConfigValue.IsNull() {
+		return
+	}
+
+	d, err := time.ParseDuration(req.ConfigValue.ValueString())
+	if err != nil || d < 0 {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid duration",
+			fmt.Sprintf("value must be a non-negative duration such as \"500ms\" or \"30s\"; got %q", req.ConfigValue.ValueString()),
 		)
 	}
 }
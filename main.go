@@ -44,6 +44,9 @@ func main() {
 
 	err := providerserver.Serve(context.Background(), provider.New(version), opts)
 
+	// Serve returns once Terraform stops the plugin; close any open connections.
+	provider.Shutdown()
+
 	if err != nil {
 		log.Fatal(err.Error())
 	}
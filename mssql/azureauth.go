@@ -0,0 +1,175 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	mssqldb "github.com/microsoft/go-mssqldb"
+)
+
+// Azure (Microsoft Entra ID) authentication modes.
+const (
+	AzureAuthServicePrincipal  = "service_principal"
+	AzureAuthClientCertificate = "client_certificate"
+	AzureAuthManagedIdentity   = "managed_identity"
+	AzureAuthWorkloadIdentity  = "workload_identity"
+	AzureAuthDefault           = "default"
+)
+
+// AzureAuthModes lists the supported values for AzureAuth.Mode.
+var AzureAuthModes = []string{
+	AzureAuthServicePrincipal,
+	AzureAuthClientCertificate,
+	AzureAuthManagedIdentity,
+	AzureAuthWorkloadIdentity,
+	AzureAuthDefault,
+}
+
+// AzureAuth configures Microsoft Entra ID (Azure AD) authentication.
+// Which fields are used depends on Mode.
+type AzureAuth struct {
+	Mode                      string
+	TenantID                  string
+	ClientID                  string
+	ClientSecret              string
+	ClientCertificatePath     string
+	ClientCertificatePassword string
+	FederatedTokenFile        string
+	// AuthorityHost overrides the Entra ID authority, e.g. for sovereign clouds.
+	AuthorityHost string
+}
+
+// azureClientOptions is applied to every credential; tests override it to
+// route token requests to a local stand-in endpoint.
+var azureClientOptions azcore.ClientOptions
+
+// Validate checks that the fields required by the selected mode are present.
+func (a AzureAuth) Validate() error {
+	switch a.Mode {
+	case AzureAuthServicePrincipal:
+		if a.TenantID == "" || a.ClientID == "" || a.ClientSecret == "" {
+			return fmt.Errorf("azure_auth mode %q requires tenant_id, client_id and client_secret", a.Mode)
+		}
+	case AzureAuthClientCertificate:
+		if a.TenantID == "" || a.ClientID == "" || a.ClientCertificatePath == "" {
+			return fmt.Errorf("azure_auth mode %q requires tenant_id, client_id and client_certificate_path", a.Mode)
+		}
+	case AzureAuthManagedIdentity, AzureAuthWorkloadIdentity, AzureAuthDefault:
+		// All fields are optional; azidentity falls back to the environment.
+	default:
+		return fmt.Errorf("azure_auth mode must be one of %s; got %q", strings.Join(AzureAuthModes, ", "), a.Mode)
+	}
+	return nil
+}
+
+// adalWorkflow returns the FEDAUTH workflow advertised to the server during login.
+func (a AzureAuth) adalWorkflow() byte {
+	if a.Mode == AzureAuthManagedIdentity {
+		return mssqldb.FedAuthADALWorkflowMSI
+	}
+	return mssqldb.FedAuthADALWorkflowPassword
+}
+
+func (a AzureAuth) clientOptions() azcore.ClientOptions {
+	opts := azureClientOptions
+	if a.AuthorityHost != "" {
+		opts.Cloud = cloud.Configuration{ActiveDirectoryAuthorityHost: a.AuthorityHost}
+	}
+	return opts
+}
+
+func newAzureCredential(a AzureAuth) (azcore.TokenCredential, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+
+	opts := a.clientOptions()
+	// A custom authority (Azure Stack, local stand-ins) is not known to the
+	// public instance discovery endpoint.
+	disableDiscovery := a.AuthorityHost != ""
+
+	switch a.Mode {
+	case AzureAuthServicePrincipal:
+		return azidentity.NewClientSecretCredential(a.TenantID, a.ClientID, a.ClientSecret, &azidentity.ClientSecretCredentialOptions{
+			ClientOptions:            opts,
+			DisableInstanceDiscovery: disableDiscovery,
+		})
+	case AzureAuthClientCertificate:
+		data, err := os.ReadFile(a.ClientCertificatePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client certificate %s: %v", a.ClientCertificatePath, err)
+		}
+		var password []byte
+		if a.ClientCertificatePassword != "" {
+			password = []byte(a.ClientCertificatePassword)
+		}
+		certs, key, err := azidentity.ParseCertificates(data, password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client certificate %s: %v", a.ClientCertificatePath, err)
+		}
+		return azidentity.NewClientCertificateCredential(a.TenantID, a.ClientID, certs, key, &azidentity.ClientCertificateCredentialOptions{
+			ClientOptions:            opts,
+			DisableInstanceDiscovery: disableDiscovery,
+		})
+	case AzureAuthManagedIdentity:
+		miOpts := &azidentity.ManagedIdentityCredentialOptions{ClientOptions: opts}
+		if a.ClientID != "" {
+			miOpts.ID = azidentity.ClientID(a.ClientID)
+		}
+		return azidentity.NewManagedIdentityCredential(miOpts)
+	case AzureAuthWorkloadIdentity:
+		return azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+			ClientOptions:            opts,
+			TenantID:                 a.TenantID,
+			ClientID:                 a.ClientID,
+			TokenFilePath:            a.FederatedTokenFile,
+			DisableInstanceDiscovery: disableDiscovery,
+		})
+	default:
+		return azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{
+			ClientOptions:            opts,
+			TenantID:                 a.TenantID,
+			DisableInstanceDiscovery: disableDiscovery,
+		})
+	}
+}
+
+// azureTokenProvider returns a token callback for go-mssqldb's Active Directory
+// token connector. The credential is created on first use and then reused so
+// that azidentity's token cache is shared by every pooled connection.
+func azureTokenProvider(a AzureAuth) func(ctx context.Context, serverSPN, stsURL string) (string, error) {
+	var (
+		mu   sync.Mutex
+		cred azcore.TokenCredential
+	)
+
+	return func(ctx context.Context, serverSPN, stsURL string) (string, error) {
+		mu.Lock()
+		if cred == nil {
+			c, err := newAzureCredential(a)
+			if err != nil {
+				mu.Unlock()
+				return "", err
+			}
+			cred = c
+		}
+		mu.Unlock()
+
+		scope := serverSPN
+		if !strings.HasSuffix(scope, "/.default") {
+			scope = strings.TrimRight(scope, "/") + "/.default"
+		}
+		tk, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{scope}})
+		if err != nil {
+			return "", fmt.Errorf("failed to acquire Entra ID token for %s: %v", scope, err)
+		}
+		return tk.Token, nil
+	}
+}
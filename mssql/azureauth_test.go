@@ -0,0 +1,205 @@
+package mssql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	mssqldb "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+const testSqlSPN = "https://database.windows.net/"
+
+// newTokenEndpoint starts a local stand-in for the Entra ID authority. It serves
+// OpenID discovery metadata and issues a fixed token from its token endpoint.
+func newTokenEndpoint(t *testing.T, tenant string, token string, gotForm chan<- map[string]string) *httptest.Server {
+	t.Helper()
+
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/.well-known/openid-configuration"):
+			base := fmt.Sprintf("%s/%s", srv.URL, tenant)
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"token_endpoint":         base + "/oauth2/v2.0/token",
+				"authorization_endpoint": base + "/oauth2/v2.0/authorize",
+				"issuer":                 base + "/v2.0",
+			})
+		case strings.HasSuffix(r.URL.Path, "/oauth2/v2.0/token"):
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if gotForm != nil {
+				form := map[string]string{}
+				for k := range r.PostForm {
+					form[k] = r.PostForm.Get(k)
+				}
+				gotForm <- form
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"access_token": token,
+				"token_type":   "Bearer",
+				"expires_in":   3600,
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	prev := azureClientOptions
+	azureClientOptions = azcore.ClientOptions{Transport: srv.Client()}
+	t.Cleanup(func() { azureClientOptions = prev })
+
+	return srv
+}
+
+func Test_AzureAuth_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    AzureAuth
+		wantErr bool
+	}{
+		{name: "service principal", auth: AzureAuth{Mode: AzureAuthServicePrincipal, TenantID: "t", ClientID: "c", ClientSecret: "s"}},
+		{name: "service principal missing secret", auth: AzureAuth{Mode: AzureAuthServicePrincipal, TenantID: "t", ClientID: "c"}, wantErr: true},
+		{name: "client certificate", auth: AzureAuth{Mode: AzureAuthClientCertificate, TenantID: "t", ClientID: "c", ClientCertificatePath: "/tmp/cert.pem"}},
+		{name: "client certificate missing path", auth: AzureAuth{Mode: AzureAuthClientCertificate, TenantID: "t", ClientID: "c"}, wantErr: true},
+		{name: "managed identity", auth: AzureAuth{Mode: AzureAuthManagedIdentity}},
+		{name: "workload identity", auth: AzureAuth{Mode: AzureAuthWorkloadIdentity}},
+		{name: "default", auth: AzureAuth{Mode: AzureAuthDefault}},
+		{name: "unknown mode", auth: AzureAuth{Mode: "password"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.auth.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() err=%v wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_azureTokenProvider_ServicePrincipal(t *testing.T) {
+	forms := make(chan map[string]string, 1)
+	srv := newTokenEndpoint(t, "tenant-1", "sp-token", forms)
+
+	provider := azureTokenProvider(AzureAuth{
+		Mode:          AzureAuthServicePrincipal,
+		TenantID:      "tenant-1",
+		ClientID:      "client-1",
+		ClientSecret:  "secret-1",
+		AuthorityHost: srv.URL,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, err := provider(ctx, testSqlSPN, srv.URL+"/tenant-1")
+	if err != nil {
+		t.Fatalf("token provider error = %v", err)
+	}
+	if token != "sp-token" {
+		t.Fatalf("token = %q, want %q", token, "sp-token")
+	}
+
+	form := <-forms
+	if form["client_id"] != "client-1" || form["client_secret"] != "secret-1" {
+		t.Fatalf("unexpected token request form: %v", form)
+	}
+	if !strings.Contains(form["scope"], "https://database.windows.net/.default") {
+		t.Fatalf("scope = %q, want database.windows.net/.default", form["scope"])
+	}
+
+	// A second call is served from the credential's token cache.
+	if _, err := provider(ctx, testSqlSPN, srv.URL+"/tenant-1"); err != nil {
+		t.Fatalf("cached token provider error = %v", err)
+	}
+	select {
+	case form := <-forms:
+		t.Fatalf("expected cached token, got second token request: %v", form)
+	default:
+	}
+}
+
+func Test_azureTokenProvider_ManagedIdentity(t *testing.T) {
+	var gotResource, gotClientID, gotHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotResource = r.URL.Query().Get("resource")
+		gotClientID = r.URL.Query().Get("client_id")
+		gotHeader = r.Header.Get("X-IDENTITY-HEADER")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "mi-token",
+			"expires_on":   fmt.Sprintf("%d", time.Now().Add(time.Hour).Unix()),
+			"resource":     gotResource,
+			"token_type":   "Bearer",
+		})
+	}))
+	defer srv.Close()
+
+	// App Service style managed identity endpoint.
+	t.Setenv("IDENTITY_ENDPOINT", srv.URL)
+	t.Setenv("IDENTITY_HEADER", "header-secret")
+
+	provider := azureTokenProvider(AzureAuth{Mode: AzureAuthManagedIdentity, ClientID: "mi-client"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, err := provider(ctx, testSqlSPN, "")
+	if err != nil {
+		t.Fatalf("token provider error = %v", err)
+	}
+	if token != "mi-token" {
+		t.Fatalf("token = %q, want %q", token, "mi-token")
+	}
+	if gotResource != "https://database.windows.net" {
+		t.Fatalf("resource = %q, want https://database.windows.net", gotResource)
+	}
+	if gotClientID != "mi-client" {
+		t.Fatalf("client_id = %q, want mi-client", gotClientID)
+	}
+	if gotHeader != "header-secret" {
+		t.Fatalf("X-IDENTITY-HEADER = %q, want header-secret", gotHeader)
+	}
+}
+
+func Test_azureTokenProvider_InvalidConfig(t *testing.T) {
+	provider := azureTokenProvider(AzureAuth{Mode: AzureAuthServicePrincipal, ClientID: "c"})
+	if _, err := provider(context.Background(), testSqlSPN, ""); err == nil {
+		t.Fatalf("expected error for incomplete service principal config")
+	}
+}
+
+func Test_AzureAuth_adalWorkflow(t *testing.T) {
+	if got := (AzureAuth{Mode: AzureAuthManagedIdentity}).adalWorkflow(); got != mssqldb.FedAuthADALWorkflowMSI {
+		t.Fatalf("managed identity workflow = %d, want MSI", got)
+	}
+	if got := (AzureAuth{Mode: AzureAuthServicePrincipal}).adalWorkflow(); got != mssqldb.FedAuthADALWorkflowPassword {
+		t.Fatalf("service principal workflow = %d, want password", got)
+	}
+}
+
+func Test_buildConnString_AzureAuthOmitsCredentials(t *testing.T) {
+	cfg := Config{Host: "srv.database.windows.net", Port: 1433, AzureAuth: &AzureAuth{Mode: AzureAuthDefault}}
+	params, err := msdsn.Parse(buildConnString(cfg, "appdb"))
+	if err != nil {
+		t.Fatalf("msdsn.Parse() error = %v", err)
+	}
+	if params.Host != "srv.database.windows.net" || params.Port != 1433 || params.Database != "appdb" {
+		t.Fatalf("buildConnString() settings: host=%s port=%d database=%s", params.Host, params.Port, params.Database)
+	}
+	if params.User != "" || params.Password != "" {
+		t.Fatalf("buildConnString() has credentials: user=%q", params.User)
+	}
+}
@@ -0,0 +1,269 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// catalogCache holds a snapshot of the permissions and role memberships of
+// each database the client reads, so that refreshing many grants and role
+// assignments costs one query per database instead of one per resource.
+//
+// A statement the client executes in a database drops its snapshot, and
+// reads of that database then go to the server. They mostly read back what
+// was just written, and reloading the whole catalog for each of them would
+// make applying many grants quadratic.
+type catalogCache struct {
+	mu        sync.Mutex
+	snapshots map[string]*catalogEntry
+	// written holds the databases the client has executed statements in.
+	written map[string]bool
+}
+
+// catalogEntry is loaded once; callers that need it meanwhile wait.
+type catalogEntry struct {
+	once     sync.Once
+	snapshot *catalogSnapshot
+	err      error
+}
+
+// catalogSnapshot is the catalog of one database. Names are compared
+// case-insensitively, like the default SQL Server collations do.
+type catalogSnapshot struct {
+	permissions []catalogPermission
+	memberships []RoleMembership
+}
+
+type catalogPermission struct {
+	principal  string
+	permission string
+	// class is sys.database_permissions.class: 0 for the database, or the
+	// class of a securable.
+	class        int
+	state        string
+	objectSchema string
+	objectName   string
+	// column is set for permissions on a column of an object.
+	column string
+}
+
+func newCatalogCache() *catalogCache {
+	return &catalogCache{snapshots: map[string]*catalogEntry{}, written: map[string]bool{}}
+}
+
+// invalidate drops the snapshot of a database the client has written to and
+// stops serving its reads. An empty database, for statements whose database
+// is not known, drops every snapshot. A nil cache does nothing.
+func (c *catalogCache) invalidate(database string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if database == "" {
+		c.snapshots = map[string]*catalogEntry{}
+		return
+	}
+	key := strings.ToLower(database)
+	delete(c.snapshots, key)
+	c.written[key] = true
+}
+
+// useCatalog reports whether reads of database are served from its snapshot:
+// the cache is enabled and the client has not written to the database.
+func (m *client) useCatalog(database string) bool {
+	if m.catalogCache == nil {
+		return false
+	}
+	if database == "" {
+		database = m.database
+	}
+	m.catalogCache.mu.Lock()
+	defer m.catalogCache.mu.Unlock()
+	return !m.catalogCache.written[strings.ToLower(database)]
+}
+
+// catalog returns the snapshot of database, loading it on first use.
+func (m *client) catalog(ctx context.Context, database string) (*catalogSnapshot, error) {
+	if database == "" {
+		database = m.database
+	}
+	key := strings.ToLower(database)
+
+	m.catalogCache.mu.Lock()
+	e := m.catalogCache.snapshots[key]
+	if e == nil {
+		e = &catalogEntry{}
+		m.catalogCache.snapshots[key] = e
+	}
+	m.catalogCache.mu.Unlock()
+
+	e.once.Do(func() {
+		e.snapshot, e.err = m.loadCatalog(ctx, database)
+		if e.err != nil {
+			// Let the next caller retry instead of caching the failure.
+			m.catalogCache.mu.Lock()
+			if m.catalogCache.snapshots[key] == e {
+				delete(m.catalogCache.snapshots, key)
+			}
+			m.catalogCache.mu.Unlock()
+		}
+	})
+	return e.snapshot, e.err
+}
+
+func (m *client) loadCatalog(ctx context.Context, database string) (*catalogSnapshot, error) {
+	conn, release, err := m.getConnForDatabase(database)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	tflog.Debug(ctx, fmt.Sprintf("Loading catalog snapshot of database %s", database))
+
+	var snapshot catalogSnapshot
+	permissionsCmd := `SELECT
+	dp.[name],
+	sdp.[permission_name],
+	sdp.[class],
+	sdp.[state],
+	COALESCE(` + securableSchemaSQL + `, ''),
+	COALESCE(` + securableNameSQL + `, ''),
+	CASE WHEN sdp.[class] = 1 AND sdp.[minor_id] <> 0 THEN COALESCE(COL_NAME(sdp.[major_id], sdp.[minor_id]), '') ELSE '' END
+FROM sys.database_permissions AS sdp
+JOIN sys.database_principals AS dp ON sdp.grantee_principal_id = dp.principal_id
+WHERE sdp.[class] IN (` + securableClassList + `)`
+	err = m.query(ctx, conn, permissionsCmd, nil, func(rows *sql.Rows) error {
+		snapshot.permissions = nil
+		for rows.Next() {
+			var p catalogPermission
+			if err := rows.Scan(&p.principal, &p.permission, &p.class, &p.state, &p.objectSchema, &p.objectName, &p.column); err != nil {
+				return err
+			}
+			snapshot.permissions = append(snapshot.permissions, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load permissions of database %s: %v", database, err)
+	}
+
+	membershipsCmd := `SELECT r.[name], m.[name]
+FROM sys.database_role_members AS rm
+JOIN sys.database_principals AS r ON rm.role_principal_id = r.principal_id
+JOIN sys.database_principals AS m ON rm.member_principal_id = m.principal_id
+WHERE r.[type] = 'R'`
+	err = m.query(ctx, conn, membershipsCmd, nil, func(rows *sql.Rows) error {
+		snapshot.memberships = nil
+		for rows.Next() {
+			var rm RoleMembership
+			if err := rows.Scan(&rm.Role, &rm.Member); err != nil {
+				return err
+			}
+			rm.Id = encodeRoleMembershipId(rm.Role, rm.Member)
+			snapshot.memberships = append(snapshot.memberships, rm)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load role memberships of database %s: %v", database, err)
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("Loaded catalog snapshot of database %s: %d permissions, %d role memberships",
+		database, len(snapshot.permissions), len(snapshot.memberships)))
+	return &snapshot, nil
+}
+
+// roleMembership looks up a membership like ReadRoleMembership does.
+func (s *catalogSnapshot) roleMembership(role string, member string) (RoleMembership, error) {
+	for _, rm := range s.memberships {
+		if strings.EqualFold(rm.Role, role) && strings.EqualFold(rm.Member, member) {
+			return rm, nil
+		}
+	}
+	return RoleMembership{}, sql.ErrNoRows
+}
+
+// readPermissionFromCatalog is ReadPermission served from the snapshot of the
+// grant's database. class is the class of the securable of the grant, zero
+// for database permissions.
+func (m *client) readPermissionFromCatalog(ctx context.Context, class securableClass, grant GrantPermission) (GrantPermission, error) {
+	snapshot, err := m.catalog(ctx, grant.Database)
+	if err != nil {
+		return grant, err
+	}
+
+	if len(grant.Columns) > 0 {
+		return snapshot.columnPermission(grant)
+	}
+
+	p, err := snapshot.permission(class, grant)
+	if err != nil {
+		return grant, err
+	}
+	grant.Principal, grant.Permission = p.principal, p.permission
+	grant.State = grantState(p.state)
+	grant.WithGrantOption = p.state == "W"
+	if p.class != 0 {
+		setGrantObject(&grant, class, p.objectSchema, p.objectName)
+	}
+	return grant, nil
+}
+
+// permission looks up a granted or denied permission like ReadPermission
+// does, on the database if class is zero or on a securable of the class.
+func (s *catalogSnapshot) permission(class securableClass, grant GrantPermission) (catalogPermission, error) {
+	objSchema, objName := class.splitName(grant.ObjectName)
+
+	for _, p := range s.permissions {
+		if p.state != "G" && p.state != "W" && p.state != "D" {
+			continue
+		}
+		if !strings.EqualFold(p.principal, grant.Principal) || !strings.EqualFold(p.permission, grant.Permission) || p.column != "" {
+			continue
+		}
+		if p.class != class.class {
+			continue
+		}
+		if class.class == 0 || strings.EqualFold(p.objectName, objName) &&
+			(objSchema == "" || strings.EqualFold(p.objectSchema, objSchema)) {
+			return p, nil
+		}
+	}
+	return catalogPermission{}, sql.ErrNoRows
+}
+
+// columnPermission looks up the columns of a permission on an object like
+// readColumnPermission does.
+func (s *catalogSnapshot) columnPermission(grant GrantPermission) (GrantPermission, error) {
+	objSchema, objName := splitSchemaObject(grant.ObjectName)
+
+	var columns, states []string
+	var found catalogPermission
+	for _, p := range s.permissions {
+		if p.class != 1 || p.column == "" || (p.state != "G" && p.state != "W" && p.state != "D") {
+			continue
+		}
+		if !strings.EqualFold(p.principal, grant.Principal) || !strings.EqualFold(p.permission, grant.Permission) ||
+			!strings.EqualFold(p.objectName, objName) || (objSchema != "" && !strings.EqualFold(p.objectSchema, objSchema)) {
+			continue
+		}
+		found = p
+		columns = append(columns, p.column)
+		states = append(states, p.state)
+	}
+	if len(columns) == 0 {
+		return grant, sql.ErrNoRows
+	}
+	sort.Strings(columns)
+	grant.Principal, grant.Permission = found.principal, found.permission
+	setGrantObject(&grant, objectClass, found.objectSchema, found.objectName)
+	setGrantColumns(&grant, columns, states)
+	return grant, nil
+}
@@ -0,0 +1,207 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newCatalogTestClient(t *testing.T) (*client, sqlmock.Sqlmock) {
+	t.Helper()
+
+	c, mock := newRetryTestClient(t)
+	c.database = "app"
+	c.catalogCache = newCatalogCache()
+	return c, mock
+}
+
+func expectCatalog(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("FROM sys.database_permissions").WillReturnRows(
+		sqlmock.NewRows([]string{"principal", "permission", "class", "state", "object_schema", "object_name", "column"}).
+			AddRow("reader", "SELECT", 1, "G", "dbo", "orders", "").
+			AddRow("reader", "CONNECT", 0, "G", "", "", "").
+			AddRow("writer", "INSERT", 3, "W", "", "sales", "").
+			AddRow("denied", "DELETE", 0, "D", "", "", "").
+			AddRow("analyst", "SELECT", 1, "W", "dbo", "customers", "region").
+			AddRow("analyst", "SELECT", 1, "G", "dbo", "customers", "id").
+			AddRow("signer", "CONTROL", 25, "G", "", "CodeSigning.v2", "").
+			AddRow("reader", "REFERENCES", 6, "G", "dbo", "Money", ""),
+	)
+	mock.ExpectQuery("FROM sys.database_role_members").WillReturnRows(
+		sqlmock.NewRows([]string{"role", "member"}).
+			AddRow("db_datareader", "reader").
+			AddRow("db_datawriter", "writer"),
+	)
+}
+
+func Test_catalogCache_ServesReads(t *testing.T) {
+	c, mock := newCatalogTestClient(t)
+	expectCatalog(mock)
+	ctx := context.Background()
+
+	grant, err := c.ReadPermission(ctx, GrantPermission{Principal: "READER", Permission: "select", ObjectType: "TABLE", ObjectName: "orders"})
+	if err != nil {
+		t.Fatalf("ReadPermission() error = %v", err)
+	}
+	if grant.Principal != "reader" || grant.Permission != "SELECT" || grant.ObjectType != "TABLE" || grant.ObjectName != "dbo.orders" {
+		t.Fatalf("ReadPermission() = %+v, want SELECT on TABLE dbo.orders for reader", grant)
+	}
+
+	grant, err = c.ReadPermission(ctx, GrantPermission{Principal: "writer", Permission: "INSERT", ObjectType: "SCHEMA", ObjectName: "sales"})
+	if err != nil {
+		t.Fatalf("ReadPermission() error = %v", err)
+	}
+	if grant.ObjectType != "SCHEMA" || grant.ObjectName != "sales" || grant.State != "GRANT" || !grant.WithGrantOption {
+		t.Fatalf("ReadPermission() = %+v, want INSERT on SCHEMA sales with the grant option", grant)
+	}
+
+	if _, err := c.ReadPermission(ctx, GrantPermission{Principal: "reader", Permission: "CONNECT"}); err != nil {
+		t.Fatalf("ReadPermission() error = %v", err)
+	}
+	grant, err = c.ReadPermission(ctx, GrantPermission{Principal: "denied", Permission: "DELETE"})
+	if err != nil || grant.State != "DENY" {
+		t.Fatalf("ReadPermission() of a denied permission = %+v, %v, want state DENY", grant, err)
+	}
+	if _, err := c.ReadPermission(ctx, GrantPermission{Principal: "reader", Permission: "SELECT", ObjectType: "TABLE", ObjectName: "other.orders"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("ReadPermission() in another schema error = %v, want sql.ErrNoRows", err)
+	}
+
+	grant, err = c.ReadPermission(ctx, GrantPermission{Principal: "analyst", Permission: "SELECT", ObjectType: "TABLE", ObjectName: "customers", Columns: []string{"id"}})
+	if err != nil {
+		t.Fatalf("ReadPermission() of a column grant error = %v", err)
+	}
+	if strings.Join(grant.Columns, ",") != "id,region" || grant.ObjectName != "dbo.customers" || grant.WithGrantOption {
+		t.Fatalf("ReadPermission() = %+v, want SELECT on dbo.customers (id, region) without the grant option", grant)
+	}
+	if _, err := c.ReadPermission(ctx, GrantPermission{Principal: "analyst", Permission: "SELECT", ObjectType: "TABLE", ObjectName: "customers"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("ReadPermission() of a column grant without columns error = %v, want sql.ErrNoRows", err)
+	}
+
+	grant, err = c.ReadPermission(ctx, GrantPermission{Principal: "signer", Permission: "CONTROL", ObjectType: "certificate", ObjectName: "codesigning.v2"})
+	if err != nil || grant.ObjectName != "CodeSigning.v2" {
+		t.Fatalf("ReadPermission() on a certificate = %+v, %v, want CONTROL on CodeSigning.v2", grant, err)
+	}
+	grant, err = c.ReadPermission(ctx, GrantPermission{Principal: "reader", Permission: "REFERENCES", ObjectType: "TYPE", ObjectName: "Money"})
+	if err != nil || grant.ObjectName != "dbo.Money" {
+		t.Fatalf("ReadPermission() on a type = %+v, %v, want REFERENCES on dbo.Money", grant, err)
+	}
+	// Names resolve within the class of the object type only.
+	if _, err := c.ReadPermission(ctx, GrantPermission{Principal: "reader", Permission: "SELECT", ObjectType: "SCHEMA", ObjectName: "orders"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("ReadPermission() on a schema named like a table error = %v, want sql.ErrNoRows", err)
+	}
+
+	rm, err := c.ReadRoleMembership(ctx, "", encodeRoleMembershipId("db_datareader", "reader"))
+	if err != nil {
+		t.Fatalf("ReadRoleMembership() error = %v", err)
+	}
+	if rm.Id != encodeRoleMembershipId("db_datareader", "reader") {
+		t.Fatalf("ReadRoleMembership() = %+v", rm)
+	}
+	if _, err := c.ReadRoleMembership(ctx, "APP", encodeRoleMembershipId("db_datareader", "writer")); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("ReadRoleMembership() of a missing membership error = %v, want sql.ErrNoRows", err)
+	}
+
+	// Every read above was served by the two snapshot queries.
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func Test_catalogCache_InvalidatedByExec(t *testing.T) {
+	c, mock := newCatalogTestClient(t)
+	ctx := context.Background()
+	id := encodeRoleMembershipId("db_datareader", "reader")
+
+	expectCatalog(mock)
+	mock.ExpectExec("ALTER ROLE").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("AND M.name = @p2").WithArgs("db_datareader", "reader").WillReturnRows(sqlmock.NewRows([]string{"role", "member"}))
+
+	if _, err := c.ReadRoleMembership(ctx, "", id); err != nil {
+		t.Fatalf("ReadRoleMembership() error = %v", err)
+	}
+	if _, err := c.exec(ctx, c.conn, idempotent, "ALTER ROLE [db_datareader] DROP MEMBER [reader]"); err != nil {
+		t.Fatalf("exec() error = %v", err)
+	}
+	if _, err := c.ReadRoleMembership(ctx, "", id); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("ReadRoleMembership() after exec error = %v, want sql.ErrNoRows", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func Test_catalogCache_LoadsOncePerDatabase(t *testing.T) {
+	c, mock := newCatalogTestClient(t)
+	ctx := context.Background()
+
+	sales, salesMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() err = %v", err)
+	}
+	t.Cleanup(func() { sales.Close() })
+	c.open = func(string) (*sql.DB, error) { return sales, nil }
+
+	expectCatalog(mock)
+	expectCatalog(salesMock)
+	members := []string{"alice", "bob", "carol"}
+	for _, member := range members {
+		mock.ExpectExec("ALTER ROLE").WithArgs("db_datawriter", member).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("AND M.name = @p2").WithArgs("db_datawriter", member).
+			WillReturnRows(sqlmock.NewRows([]string{"role", "member"}).AddRow("db_datawriter", member))
+	}
+
+	if _, err := c.ReadRoleMembership(ctx, "app", encodeRoleMembershipId("db_datareader", "reader")); err != nil {
+		t.Fatalf("ReadRoleMembership() error = %v", err)
+	}
+	if _, err := c.ReadRoleMembership(ctx, "sales", encodeRoleMembershipId("db_datareader", "reader")); err != nil {
+		t.Fatalf("ReadRoleMembership() in sales error = %v", err)
+	}
+
+	// Each write is read back from the server instead of reloading the
+	// catalog of app.
+	for _, member := range members {
+		rm, err := c.AssignRole(ctx, "app", "db_datawriter", member)
+		if err != nil || rm.Member != member {
+			t.Fatalf("AssignRole(%s) = %+v, %v", member, rm, err)
+		}
+	}
+
+	// The snapshot of sales is still served.
+	if _, err := c.ReadRoleMembership(ctx, "SALES", encodeRoleMembershipId("db_datawriter", "writer")); err != nil {
+		t.Fatalf("ReadRoleMembership() in sales after writes to app error = %v", err)
+	}
+	if _, err := c.ReadPermission(ctx, GrantPermission{Database: "sales", Principal: "reader", Permission: "CONNECT"}); err != nil {
+		t.Fatalf("ReadPermission() in sales after writes to app error = %v", err)
+	}
+
+	// One catalog load per database; any other would be an unexpected query.
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations in app: %v", err)
+	}
+	if err := salesMock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations in sales: %v", err)
+	}
+}
+
+func Test_catalogCache_RetriesFailedLoad(t *testing.T) {
+	c, mock := newCatalogTestClient(t)
+	ctx := context.Background()
+	id := encodeRoleMembershipId("db_datareader", "reader")
+
+	mock.ExpectQuery("FROM sys.database_permissions").WillReturnError(errors.New("connection reset"))
+	expectCatalog(mock)
+
+	if _, err := c.ReadRoleMembership(ctx, "", id); err == nil {
+		t.Fatal("ReadRoleMembership() error = nil, want the load error")
+	}
+	if _, err := c.ReadRoleMembership(ctx, "", id); err != nil {
+		t.Fatalf("ReadRoleMembership() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
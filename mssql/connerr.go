@@ -0,0 +1,143 @@
+package mssql
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+	"golang.org/x/crypto/ssh"
+)
+
+// ConnectError is returned by Ping when the client cannot connect. Summary
+// names the failing stage (DNS, TCP, TLS, login or database) and Error
+// explains it together with the underlying driver error.
+type ConnectError struct {
+	Summary string
+	Detail  string
+	Err     error
+}
+
+func (e *ConnectError) Error() string {
+	return fmt.Sprintf("%s\n\n%v", e.Detail, e.Err)
+}
+
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+// Error numbers reported when the database requested by the login cannot be
+// used.
+var databaseUnavailableNumbers = map[int32]bool{
+	4060:  true, // cannot open database requested by the login
+	40613: true, // database not currently available (Azure SQL)
+}
+
+// loginFailedStates explains the states of error 18456. SQL Server normally
+// reports state 1 to clients and logs the real state, but Azure SQL and some
+// configurations return it directly.
+var loginFailedStates = map[uint8]string{
+	1:  "the server did not disclose the reason; the SQL Server error log records the detailed state",
+	2:  "the login does not exist",
+	5:  "the login does not exist",
+	6:  "a Windows login was used with SQL authentication",
+	7:  "the login is disabled",
+	8:  "the password is incorrect",
+	9:  "the password is not valid",
+	11: "the login is valid but has no access to the server",
+	12: "the login is valid but has no access to the server",
+	18: "the password must be changed",
+	58: "the server does not allow SQL authentication",
+}
+
+// newConnectError classifies err, returned while connecting to the server
+// described by cfg.
+func newConnectError(cfg Config, err error) *ConnectError {
+	server := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+
+	var sqlErr mssqldb.Error
+	if errors.As(err, &sqlErr) {
+		switch {
+		case sqlErr.Number == 18456 && (sqlErr.State == 38 || sqlErr.State == 40):
+			fallthrough
+		case databaseUnavailableNumbers[sqlErr.Number]:
+			return &ConnectError{
+				Summary: "Sql Server Database Unavailable",
+				Detail:  fmt.Sprintf("Connected to %s, but database %q could not be opened. Check that it exists, is online and that the login may access it.", server, cfg.Database),
+				Err:     err,
+			}
+		case sqlErr.Number == 18456:
+			reason, ok := loginFailedStates[sqlErr.State]
+			if !ok {
+				reason = "see the SQL Server error log for this state"
+			}
+			return &ConnectError{
+				Summary: "Sql Server Login Failed",
+				Detail:  fmt.Sprintf("%s rejected the login (error 18456, state %d): %s.", server, sqlErr.State, reason),
+				Err:     err,
+			}
+		}
+	}
+
+	var tunnelErr *sshTunnelError
+	if errors.As(err, &tunnelErr) {
+		return &ConnectError{
+			Summary: "SSH Tunnel Failed",
+			Detail:  fmt.Sprintf("Could not open the SSH tunnel through %s. Check the `ssh_tunnel` host, user, credentials and host key.", tunnelErr.addr),
+			Err:     err,
+		}
+	}
+
+	if isTLSError(err) {
+		return &ConnectError{
+			Summary: "Sql Server TLS Handshake Failed",
+			Detail:  fmt.Sprintf("Reached %s, but the TLS handshake failed. Check that the server certificate is trusted (`tls.certificate`) and matches `host` or `tls.host_name_in_certificate`.", server),
+			Err:     err,
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return &ConnectError{
+			Summary: "Sql Server Host Not Found",
+			Detail:  fmt.Sprintf("The host name %q could not be resolved. Check `host`.", cfg.Host),
+			Err:     err,
+		}
+	}
+
+	var opErr *net.OpError
+	var channelErr *ssh.OpenChannelError
+	if errors.As(err, &opErr) || errors.As(err, &channelErr) {
+		return &ConnectError{
+			Summary: "Sql Server Unreachable",
+			Detail:  fmt.Sprintf("Could not open a TCP connection to %s. Check `host` and `port`, and that the server accepts TCP connections through any firewall.", server),
+			Err:     err,
+		}
+	}
+
+	return &ConnectError{
+		Summary: "Unable to Connect to Sql Server",
+		Detail:  fmt.Sprintf("Could not connect to %s.", server),
+		Err:     err,
+	}
+}
+
+func isTLSError(err error) bool {
+	var (
+		verifyErr    *tls.CertificateVerificationError
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		hostErr      x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	if errors.As(err, &verifyErr) || errors.As(err, &recordErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostErr) || errors.As(err, &invalidErr) {
+		return true
+	}
+	// go-mssqldb formats handshake errors without wrapping them unless
+	// encryption is strict.
+	return strings.Contains(err.Error(), "TLS Handshake failed")
+}
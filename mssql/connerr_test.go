@@ -0,0 +1,90 @@
+package mssql
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+)
+
+func Test_newConnectError(t *testing.T) {
+	cfg := Config{Host: "sql.example.com", Port: 1433, Database: "appdb"}
+
+	tests := []struct {
+		name        string
+		err         error
+		wantSummary string
+		wantDetail  string
+	}{
+		{
+			name:        "dns",
+			err:         fmt.Errorf("unable to open tcp connection with host 'sql.example.com:1433': %w", &net.DNSError{Err: "no such host", Name: "sql.example.com", IsNotFound: true}),
+			wantSummary: "Sql Server Host Not Found",
+			wantDetail:  `"sql.example.com"`,
+		},
+		{
+			name:        "connection refused",
+			err:         fmt.Errorf("unable to open tcp connection with host '10.0.0.1:1433': %w", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}),
+			wantSummary: "Sql Server Unreachable",
+			wantDetail:  "sql.example.com:1433",
+		},
+		{
+			name:        "tls unwrapped",
+			err:         errors.New("TLS Handshake failed: tls: failed to verify certificate: x509: certificate signed by unknown authority"),
+			wantSummary: "Sql Server TLS Handshake Failed",
+		},
+		{
+			name:        "tls wrapped",
+			err:         fmt.Errorf("TLS Handshake failed: %w", x509.UnknownAuthorityError{}),
+			wantSummary: "Sql Server TLS Handshake Failed",
+		},
+		{
+			name:        "wrong password",
+			err:         mssqldb.Error{Number: 18456, State: 8, Message: "Login failed for user 'sa'."},
+			wantSummary: "Sql Server Login Failed",
+			wantDetail:  "state 8): the password is incorrect",
+		},
+		{
+			name:        "undisclosed login failure",
+			err:         mssqldb.Error{Number: 18456, State: 1, Message: "Login failed for user 'sa'."},
+			wantSummary: "Sql Server Login Failed",
+			wantDetail:  "error log",
+		},
+		{
+			name:        "default database",
+			err:         mssqldb.Error{Number: 18456, State: 40, Message: "Login failed for user 'sa'."},
+			wantSummary: "Sql Server Database Unavailable",
+			wantDetail:  `"appdb"`,
+		},
+		{
+			name:        "cannot open database",
+			err:         mssqldb.Error{Number: 4060, Message: "Cannot open database \"appdb\" requested by the login."},
+			wantSummary: "Sql Server Database Unavailable",
+		},
+		{
+			name:        "other",
+			err:         errors.New("something else"),
+			wantSummary: "Unable to Connect to Sql Server",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newConnectError(cfg, tt.err)
+			if got.Summary != tt.wantSummary {
+				t.Fatalf("Summary = %q, want %q", got.Summary, tt.wantSummary)
+			}
+			if !strings.Contains(got.Error(), tt.wantDetail) {
+				t.Fatalf("Error() = %q, want it to contain %q", got.Error(), tt.wantDetail)
+			}
+			if !errors.Is(got, tt.err) && sqlErrorNumber(got) == 0 {
+				t.Fatalf("ConnectError does not unwrap to %v", tt.err)
+			}
+		})
+	}
+}
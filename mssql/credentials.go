@@ -0,0 +1,224 @@
+package mssql
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+const (
+	// credentialRefreshMargin renews command credentials this long before
+	// they expire, so that a connection opened just before expiry still logs in.
+	credentialRefreshMargin = time.Minute
+	// credentialCommandTimeout bounds a single run of the credential command.
+	credentialCommandTimeout = time.Minute
+)
+
+// Credential is a set of SQL credentials read from a password file or a
+// credential command.
+type Credential struct {
+	Username string
+	Password string
+	// Token is an access token used instead of Username and Password.
+	Token string
+	// Expiry is when the credential stops working; zero means never.
+	Expiry time.Time
+}
+
+// credentialSource supplies the credentials for new connections.
+type credentialSource interface {
+	credential(ctx context.Context) (Credential, error)
+	// invalidate drops a cached credential that the server rejected.
+	invalidate()
+}
+
+// credentialSource returns the source of the credentials, or nil when the
+// password is configured directly.
+func (a SqlAuth) credentialSource() credentialSource {
+	switch {
+	case len(a.CredentialCommand) > 0:
+		return &commandSource{argv: a.CredentialCommand, username: a.Username, now: time.Now}
+	case a.PasswordFile != "":
+		return passwordFileSource{username: a.Username, path: a.PasswordFile}
+	}
+	return nil
+}
+
+// passwordFileSource reads the password file for every new connection, so a
+// rotated password is picked up without restarting.
+type passwordFileSource struct {
+	username string
+	path     string
+}
+
+func (s passwordFileSource) credential(context.Context) (Credential, error) {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return Credential{}, fmt.Errorf("failed to read password file: %v", err)
+	}
+	password := strings.TrimRight(string(b), "\r\n")
+	if password == "" {
+		return Credential{}, fmt.Errorf("password file %s is empty", s.path)
+	}
+	return Credential{Username: s.username, Password: password}, nil
+}
+
+func (passwordFileSource) invalidate() {}
+
+// commandSource runs the credential command and caches its result until
+// shortly before it expires.
+type commandSource struct {
+	argv     []string
+	username string
+	now      func() time.Time
+
+	mu     sync.Mutex
+	cached *Credential
+}
+
+func (s *commandSource) credential(ctx context.Context) (Credential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c := s.cached; c != nil && (c.Expiry.IsZero() || s.now().Add(credentialRefreshMargin).Before(c.Expiry)) {
+		return *c, nil
+	}
+
+	cred, err := runCredentialCommand(ctx, s.argv)
+	if err != nil {
+		return Credential{}, err
+	}
+	if cred.Username == "" {
+		cred.Username = s.username
+	}
+	if cred.Token == "" && cred.Username == "" {
+		return Credential{}, fmt.Errorf("credential command %s returned a password but no username, and sql_auth.username is not set", s.argv[0])
+	}
+	s.cached = &cred
+	return cred, nil
+}
+
+func (s *commandSource) invalidate() {
+	s.mu.Lock()
+	s.cached = nil
+	s.mu.Unlock()
+}
+
+// credentialOutput is the JSON document printed by a credential command.
+type credentialOutput struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Token    string `json:"token"`
+	// Expiry is an RFC 3339 timestamp.
+	Expiry string `json:"expiry"`
+}
+
+func runCredentialCommand(ctx context.Context, argv []string) (Credential, error) {
+	ctx, cancel := context.WithTimeout(ctx, credentialCommandTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return Credential{}, fmt.Errorf("credential command %s failed: %v: %s", argv[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	// The output holds secrets, so it is never included in errors.
+	var o credentialOutput
+	if err := json.Unmarshal(out, &o); err != nil {
+		return Credential{}, fmt.Errorf("credential command %s did not print a JSON object: %v", argv[0], err)
+	}
+	if o.Password == "" && o.Token == "" {
+		return Credential{}, fmt.Errorf("credential command %s returned neither a password nor a token", argv[0])
+	}
+
+	cred := Credential{Username: o.Username, Password: o.Password, Token: o.Token}
+	if o.Expiry != "" {
+		cred.Expiry, err = time.Parse(time.RFC3339, o.Expiry)
+		if err != nil {
+			return Credential{}, fmt.Errorf("credential command %s returned an invalid expiry: %v", argv[0], err)
+		}
+	}
+	return cred, nil
+}
+
+// credentialConnector logs in with the current credentials of the client's
+// source. database/sql calls it for every new connection, so rotated
+// credentials apply to new connections while the pools stay open.
+type credentialConnector struct {
+	m        *client
+	database string
+	driver   driver.Driver
+}
+
+func (c *credentialConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connect(ctx)
+	var sqlErr mssqldb.Error
+	if errors.As(err, &sqlErr) && sqlErr.Number == 18456 {
+		// The credential may have been rotated before its advertised expiry.
+		c.m.credentials.invalidate()
+		conn, err = c.connect(ctx)
+	}
+	return conn, err
+}
+
+func (c *credentialConnector) connect(ctx context.Context) (driver.Conn, error) {
+	cred, err := c.m.credentials.credential(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := credentialParams(c.m.cfg, c.database, cred)
+	if err != nil {
+		return nil, err
+	}
+	var connector *mssqldb.Connector
+	if cred.Token != "" {
+		connector, err = mssqldb.NewSecurityTokenConnector(params, func(context.Context) (string, error) {
+			return cred.Token, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		connector = mssqldb.NewConnectorConfig(params)
+	}
+
+	if c.m.dialer != nil {
+		connector.Dialer = c.m.dialer
+	}
+	return connector.Connect(ctx)
+}
+
+// credentialParams returns the connection settings of a database for a
+// credential. The username and password are set on the parsed settings, so a
+// rotated secret is used verbatim whatever characters it contains.
+func credentialParams(cfg Config, database string, cred Credential) (msdsn.Config, error) {
+	cfg.SqlAuth = nil
+	params, err := msdsn.Parse(buildConnString(cfg, database))
+	if err != nil {
+		return params, err
+	}
+	if cred.Token == "" {
+		params.User = cred.Username
+		params.Password = cred.Password
+	}
+	return params, nil
+}
+
+func (c *credentialConnector) Driver() driver.Driver {
+	return c.driver
+}
@@ -0,0 +1,168 @@
+package mssql
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+func Test_passwordFileSource_ReadsRotatedPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	src := SqlAuth{Username: "app", PasswordFile: path}.credentialSource()
+
+	for _, password := range []string{"first", "second"} {
+		if err := os.WriteFile(path, []byte(password+"\n"), 0o600); err != nil {
+			t.Fatalf("write password file: %v", err)
+		}
+		cred, err := src.credential(context.Background())
+		if err != nil {
+			t.Fatalf("credential() error = %v", err)
+		}
+		if cred.Username != "app" || cred.Password != password {
+			t.Fatalf("credential() = %+v, want app/%s", cred, password)
+		}
+	}
+}
+
+func Test_credentialParams_PasswordWithSeparators(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	password := "p;w=rd;encrypt=disable"
+	if err := os.WriteFile(path, []byte(password+"\n"), 0o600); err != nil {
+		t.Fatalf("write password file: %v", err)
+	}
+	cred, err := SqlAuth{Username: "app", PasswordFile: path}.credentialSource().credential(context.Background())
+	if err != nil {
+		t.Fatalf("credential() error = %v", err)
+	}
+
+	cfg := Config{Host: "sql.example.com", Port: 1433, TLS: &TLS{}}
+	params, err := credentialParams(cfg, "app", cred)
+	if err != nil {
+		t.Fatalf("credentialParams() error = %v", err)
+	}
+	if params.User != "app" || params.Password != password {
+		t.Fatalf("credentialParams() user=%q password=%q, want app/%s", params.User, params.Password, password)
+	}
+	if params.Encryption == msdsn.EncryptionDisabled || params.Database != "app" {
+		t.Fatalf("the password changed other settings: encryption=%v database=%q", params.Encryption, params.Database)
+	}
+}
+
+// credentialScript returns a credential command that counts its runs in a
+// file and prints a password numbered by the run.
+func credentialScript(t *testing.T, expiry time.Time) ([]string, func() int) {
+	t.Helper()
+
+	counter := filepath.Join(t.TempDir(), "runs")
+	script := `n=$(cat "$0" 2>/dev/null || echo 0); n=$((n+1)); echo $n > "$0"; ` +
+		`printf '{"username":"rotating","password":"pw-%s","expiry":"%s"}' "$n" "$1"`
+	argv := []string{"sh", "-c", script, counter, expiry.Format(time.RFC3339)}
+
+	runs := func() int {
+		b, err := os.ReadFile(counter)
+		if err != nil {
+			return 0
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(string(b)))
+		return n
+	}
+	return argv, runs
+}
+
+func Test_commandSource_RefreshesBeforeExpiry(t *testing.T) {
+	expiry := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	argv, runs := credentialScript(t, expiry)
+
+	now := expiry.Add(-10 * time.Minute)
+	src := &commandSource{argv: argv, now: func() time.Time { return now }}
+
+	for i := 0; i < 2; i++ {
+		cred, err := src.credential(context.Background())
+		if err != nil {
+			t.Fatalf("credential() error = %v", err)
+		}
+		if cred.Username != "rotating" || cred.Password != "pw-1" || !cred.Expiry.Equal(expiry) {
+			t.Fatalf("credential() = %+v, want the first cached credential", cred)
+		}
+	}
+	if got := runs(); got != 1 {
+		t.Fatalf("command ran %d times, want 1", got)
+	}
+
+	now = expiry.Add(-30 * time.Second)
+	cred, err := src.credential(context.Background())
+	if err != nil {
+		t.Fatalf("credential() error = %v", err)
+	}
+	if cred.Password != "pw-2" {
+		t.Fatalf("credential() password = %q, want the command to run again near expiry", cred.Password)
+	}
+
+	src.invalidate()
+	if cred, _ := src.credential(context.Background()); cred.Password != "pw-3" {
+		t.Fatalf("credential() password = %q after invalidate, want pw-3", cred.Password)
+	}
+}
+
+func Test_runCredentialCommand_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string
+	}{
+		{name: "failure", script: `echo "vault: permission denied" >&2; exit 2`, wantErr: "permission denied"},
+		{name: "not json", script: `echo "s3cret"`, wantErr: "JSON"},
+		{name: "no secret", script: `echo '{"username":"app"}'`, wantErr: "neither a password nor a token"},
+		{name: "bad expiry", script: `echo '{"password":"pw","expiry":"tomorrow"}'`, wantErr: "expiry"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runCredentialCommand(context.Background(), []string{"sh", "-c", tt.script})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("runCredentialCommand() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if strings.Contains(err.Error(), "s3cret") {
+				t.Fatalf("error leaks the command output: %v", err)
+			}
+		})
+	}
+}
+
+func Test_Ping_RunsCredentialCommand(t *testing.T) {
+	argv, runs := credentialScript(t, time.Now().Add(time.Hour))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	l.Close()
+	p, _ := strconv.ParseInt(port, 10, 64)
+
+	c, err := NewClient(Config{
+		Host:     "127.0.0.1",
+		Port:     p,
+		Database: "master",
+		SqlAuth:  &SqlAuth{CredentialCommand: argv},
+		Retry:    &Retry{MaxAttempts: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	if err := c.Ping(context.Background()); err == nil {
+		t.Fatalf("expected Ping() to fail against a closed port")
+	}
+	if got := runs(); got != 1 {
+		t.Fatalf("command ran %d times, want 1 before dialing", got)
+	}
+}
@@ -0,0 +1,236 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dryRunRecorder writes the statements a dry run would have executed to a
+// file, as a script a reviewer can read or run by hand.
+type dryRunRecorder struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func openDryRunRecorder(path string) (*dryRunRecorder, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	r := &dryRunRecorder{file: f}
+	if err := r.write(fmt.Sprintf("-- Dry run started %s\n\n", time.Now().UTC().Format(time.RFC3339))); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *dryRunRecorder) write(s string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := r.file.WriteString(s)
+	return err
+}
+
+func (r *dryRunRecorder) Close() error {
+	return r.file.Close()
+}
+
+// capture collects the statements recorded with a context. Captures nest:
+// a statement is added to every capture up the chain.
+type capture struct {
+	parent *capture
+
+	mu         sync.Mutex
+	statements []string
+}
+
+type captureKey struct{}
+
+func withCapture(ctx context.Context) (context.Context, *capture) {
+	parent, _ := ctx.Value(captureKey{}).(*capture)
+	c := &capture{parent: parent}
+	return context.WithValue(ctx, captureKey{}, c), c
+}
+
+func (c *capture) add(statement string) {
+	for ; c != nil; c = c.parent {
+		c.mu.Lock()
+		c.statements = append(c.statements, statement)
+		c.mu.Unlock()
+	}
+}
+
+func (c *capture) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.statements)
+}
+
+// RecordedStatements returns the statements a dry run recorded instead of
+// executing them, since ctx was labeled with WithResource.
+func RecordedStatements(ctx context.Context) []string {
+	c, _ := ctx.Value(captureKey{}).(*capture)
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.statements...)
+}
+
+// renderStatement formats a recorded statement with its database and bind
+// parameters as comments. Secret parameters are redacted.
+func renderStatement(ctx context.Context, database string, query string, args []any) string {
+	var b strings.Builder
+	if label, ok := ctx.Value(resourceKey{}).(resourceLabel); ok {
+		fmt.Fprintf(&b, "-- %s %s\n", label.typeName, label.id)
+	}
+	if database != "" {
+		fmt.Fprintf(&b, "USE %s;\n", quoteName(database))
+	}
+
+	params := logParameters(args)
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := params[name]
+		if s, ok := value.(string); ok && s != redacted {
+			value = "N'" + strings.ReplaceAll(s, "'", "''") + "'"
+		}
+		fmt.Fprintf(&b, "-- @%s = %v\n", name, value)
+	}
+
+	b.WriteString(strings.TrimSpace(query))
+	b.WriteString("\nGO\n")
+	return b.String()
+}
+
+func quoteName(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+// recordStatement records a statement instead of executing it.
+func (m *client) recordStatement(ctx context.Context, conn *sql.DB, query string, args []any) (sql.Result, error) {
+	statement := renderStatement(ctx, m.databaseOf(conn), query, args)
+	if err := m.recorder.write(statement + "\n"); err != nil {
+		return nil, fmt.Errorf("failed to write the dry run file: %v", err)
+	}
+	if c, _ := ctx.Value(captureKey{}).(*capture); c != nil {
+		c.add(statement)
+	}
+	return driver.RowsAffected(0), nil
+}
+
+// recordingClient is the client used for dry runs. Reads run against the
+// server; statements that change it are recorded by the embedded client
+// instead of executed.
+//
+// Writes that read the object back once it is changed would fail or return
+// stale data when nothing was executed, so once such a write has recorded
+// its statements its result is derived from the request instead. Other
+// writes need no override.
+type recordingClient struct {
+	*client
+}
+
+// record runs op and, if it recorded any statement, returns fallback in
+// place of its result.
+func record[T any](ctx context.Context, fallback T, op func(context.Context) (T, error)) (T, error) {
+	ctx, c := withCapture(ctx)
+	v, err := op(ctx)
+	if c.len() > 0 {
+		return fallback, nil
+	}
+	return v, err
+}
+
+func (r *recordingClient) CreateUser(ctx context.Context, database string, create CreateUser) (User, error) {
+	fallback := User{
+		Username:      create.Username,
+		Sid:           create.Sid,
+		External:      create.External,
+		DefaultSchema: create.DefaultSchema,
+		LoginName:     create.LoginName,
+	}
+	return record(ctx, fallback, func(ctx context.Context) (User, error) {
+		return r.client.CreateUser(ctx, database, create)
+	})
+}
+
+func (r *recordingClient) UpdateUser(ctx context.Context, database string, update UpdateUser) (User, error) {
+	fallback := User{Username: update.Id, DefaultSchema: update.DefaultSchema}
+	return record(ctx, fallback, func(ctx context.Context) (User, error) {
+		return r.client.UpdateUser(ctx, database, update)
+	})
+}
+
+func (r *recordingClient) AssignRole(ctx context.Context, database string, role string, principal string) (RoleMembership, error) {
+	fallback := RoleMembership{Id: encodeRoleMembershipId(role, principal), Role: role, Member: principal}
+	return record(ctx, fallback, func(ctx context.Context) (RoleMembership, error) {
+		return r.client.AssignRole(ctx, database, role, principal)
+	})
+}
+
+func (r *recordingClient) AssignServerRole(ctx context.Context, role string, principal string) (RoleMembership, error) {
+	fallback := RoleMembership{Id: encodeRoleMembershipId(role, principal), Role: role, Member: principal}
+	return record(ctx, fallback, func(ctx context.Context) (RoleMembership, error) {
+		return r.client.AssignServerRole(ctx, role, principal)
+	})
+}
+
+func (r *recordingClient) GrantDatabasePermission(ctx context.Context, database string, principal string, permission string) (DatabaseGrantPermission, error) {
+	fallback := DatabaseGrantPermission{
+		Id:         fmt.Sprintf("%s/%s", principal, strings.ToLower(permission)),
+		Principal:  principal,
+		Permission: permission,
+	}
+	return record(ctx, fallback, func(ctx context.Context) (DatabaseGrantPermission, error) {
+		return r.client.GrantDatabasePermission(ctx, database, principal, permission)
+	})
+}
+
+func (r *recordingClient) CreateRole(ctx context.Context, database string, name string) (Role, error) {
+	return record(ctx, Role{Id: name, Name: name}, func(ctx context.Context) (Role, error) {
+		return r.client.CreateRole(ctx, database, name)
+	})
+}
+
+func (r *recordingClient) CreateDatabase(ctx context.Context, name string) (Database, error) {
+	return record(ctx, Database{Name: name}, func(ctx context.Context) (Database, error) {
+		return r.client.CreateDatabase(ctx, name)
+	})
+}
+
+func (r *recordingClient) CreateLogin(ctx context.Context, create CreateLogin) (Login, error) {
+	fallback := Login{
+		Name:            create.Name,
+		DefaultDatabase: create.DefaultDatabase,
+		DefaultLanguage: create.DefaultLanguage,
+		Sid:             create.Sid,
+	}
+	return record(ctx, fallback, func(ctx context.Context) (Login, error) {
+		return r.client.CreateLogin(ctx, create)
+	})
+}
+
+func (r *recordingClient) UpdateLogin(ctx context.Context, update UpdateLogin) (Login, error) {
+	fallback := Login{
+		Name:            update.Name,
+		DefaultDatabase: update.DefaultDatabase,
+		DefaultLanguage: update.DefaultLanguage,
+	}
+	return record(ctx, fallback, func(ctx context.Context) (Login, error) {
+		return r.client.UpdateLogin(ctx, update)
+	})
+}
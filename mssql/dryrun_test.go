@@ -0,0 +1,95 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newDryRunTestClient(t *testing.T) (*recordingClient, sqlmock.Sqlmock, string) {
+	t.Helper()
+
+	c, mock := newRetryTestClient(t)
+	c.database = "master"
+	path := filepath.Join(t.TempDir(), "dry-run.sql")
+	recorder, err := openDryRunRecorder(path)
+	if err != nil {
+		t.Fatalf("openDryRunRecorder() error = %v", err)
+	}
+	t.Cleanup(func() { recorder.Close() })
+	c.recorder = recorder
+	return &recordingClient{client: c}, mock, path
+}
+
+func Test_recordingClient_RecordsWrites(t *testing.T) {
+	c, mock, path := newDryRunTestClient(t)
+
+	// Nothing is created, so reading the login back finds no rows.
+	mock.ExpectQuery("FROM sys.server_principals").WillReturnError(sql.ErrNoRows)
+
+	ctx := WithResource(context.Background(), "mssql_login", "")
+	login, err := c.CreateLogin(ctx, CreateLogin{Name: "app", Password: "s3cret", DefaultDatabase: "appdb"})
+	if err != nil {
+		t.Fatalf("CreateLogin() error = %v", err)
+	}
+	if login.Name != "app" || login.DefaultDatabase != "appdb" {
+		t.Fatalf("CreateLogin() = %+v, want the requested login", login)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+
+	statements := RecordedStatements(ctx)
+	if len(statements) != 1 || !strings.Contains(statements[0], "CREATE LOGIN") {
+		t.Fatalf("RecordedStatements() = %q, want the CREATE LOGIN statement", statements)
+	}
+	for _, want := range []string{"-- mssql_login", "USE [master];", "-- @name = N'app'", "-- @password = " + redacted, "GO"} {
+		if !strings.Contains(statements[0], want) {
+			t.Fatalf("statement %q does not contain %q", statements[0], want)
+		}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read dry run file: %v", err)
+	}
+	if !strings.Contains(string(b), statements[0]) {
+		t.Fatalf("dry run file does not contain the statement:\n%s", b)
+	}
+	if strings.Contains(string(b), "s3cret") {
+		t.Fatalf("dry run file contains the password:\n%s", b)
+	}
+}
+
+func Test_recordingClient_ReadsRunAgainstServer(t *testing.T) {
+	c, mock, _ := newDryRunTestClient(t)
+
+	mock.ExpectQuery("FROM sys.server_principals").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "default_database", "default_language", "is_disabled", "sid"}).
+			AddRow("app", "master", "", false, "0x01"))
+
+	login, err := c.GetLogin(context.Background(), "app")
+	if err != nil {
+		t.Fatalf("GetLogin() error = %v", err)
+	}
+	if login.Sid != "0x01" {
+		t.Fatalf("GetLogin() = %+v, want the row from the server", login)
+	}
+}
+
+func Test_recordingClient_ValidationErrors(t *testing.T) {
+	c, _, _ := newDryRunTestClient(t)
+
+	ctx := WithResource(context.Background(), "mssql_login", "")
+	if _, err := c.CreateLogin(ctx, CreateLogin{Name: "app"}); err == nil {
+		t.Fatalf("expected CreateLogin() without a password to fail")
+	}
+	if statements := RecordedStatements(ctx); len(statements) != 0 {
+		t.Fatalf("RecordedStatements() = %q, want none", statements)
+	}
+}
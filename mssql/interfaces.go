@@ -1,3 +1,6 @@
+// Package mssql is the SQL Server client behind the provider's resources.
+// SqlClient is the interface they use; NewClient returns the implementation
+// that talks to a server, and the mssqlfake package an in-memory one.
 package mssql
 
 import (
@@ -24,12 +27,34 @@ type SqlClient interface {
 	ReadPermission(ctx context.Context, grant GrantPermission) (GrantPermission, error)
 	GrantPermission(ctx context.Context, grant GrantPermission) (GrantPermission, error)
 	RevokePermission(ctx context.Context, grant GrantPermission) error
+	// RevokeGrantOption revokes the grant option of a permission, and
+	// whatever the principal granted with it, but keeps the permission.
+	RevokeGrantOption(ctx context.Context, grant GrantPermission) error
+	// Server permissions of logins and server roles, on the server or on a
+	// securable of the server such as a login.
+	ReadServerPermission(ctx context.Context, permission ServerPermission) (ServerPermission, error)
+	GrantServerPermission(ctx context.Context, permission ServerPermission) (ServerPermission, error)
+	RevokeServerPermission(ctx context.Context, permission ServerPermission) error
+	RevokeServerGrantOption(ctx context.Context, permission ServerPermission) error
 
 	GetRole(ctx context.Context, database string, name string) (Role, error)
 	CreateRole(ctx context.Context, database string, name string) (Role, error)
 	UpdateRole(ctx context.Context, database string, role Role) (Role, error)
 	DeleteRole(ctx context.Context, database string, name string) error
 
+	// Name lookups by catalog id, for state written by providers that
+	// identify principals and schemas by id.
+	GetDatabasePrincipalName(ctx context.Context, database string, id int64) (string, error)
+	GetSchemaName(ctx context.Context, database string, id int64) (string, error)
+	GetServerPrincipalName(ctx context.Context, id int64) (string, error)
+
+	// Catalog id lookups by name, to check that the principals and
+	// securables a resource refers to exist. They return sql.ErrNoRows for
+	// missing objects.
+	GetDatabasePrincipalId(ctx context.Context, database string, name string) (int64, error)
+	GetServerPrincipalId(ctx context.Context, name string) (int64, error)
+	GetSecurableId(ctx context.Context, database string, objectType string, objectName string) (int64, error)
+
 	// Server-scoped operations
 	GetDatabase(ctx context.Context, name string) (Database, error)
 	GetDatabaseById(ctx context.Context, id int64) (Database, error)
@@ -50,6 +75,13 @@ type SqlClient interface {
 	GetDatabaseScopedConfigurations(ctx context.Context, name string) ([]DatabaseScopedConfiguration, error)
 	SetDatabaseScopedConfiguration(ctx context.Context, name string, config DatabaseScopedConfiguration) error
 	ClearDatabaseScopedConfiguration(ctx context.Context, name string, configName string) error
+
+	// GetServerInfo reports the server version, edition and collation.
+	GetServerInfo(ctx context.Context) (ServerInfo, error)
+	// Ping verifies that the server is reachable and accepts the credentials.
+	Ping(ctx context.Context) error
+	// Close releases all connection pools held by the client.
+	Close() error
 }
 
 type User struct {
@@ -99,6 +131,30 @@ type GrantPermission struct {
 	Permission string
 	ObjectType string
 	ObjectName string
+	// State is GRANT or DENY. An empty State grants the permission.
+	State string
+	// WithGrantOption lets the principal grant the permission to others.
+	// It only applies to GRANT.
+	WithGrantOption bool
+	// Columns limits a permission on a table or view to these columns. A
+	// grant without columns is on the whole object.
+	Columns []string
+}
+
+// ServerPermission is a permission of a server principal, on the server or
+// on a securable of the server.
+type ServerPermission struct {
+	Principal  string
+	Permission string
+	// ObjectType and ObjectName name the securable, e.g. LOGIN and app.
+	// Both are empty for a permission on the server.
+	ObjectType string
+	ObjectName string
+	// State is GRANT or DENY. An empty State grants the permission.
+	State string
+	// WithGrantOption lets the principal grant the permission to others.
+	// It only applies to GRANT.
+	WithGrantOption bool
 }
 
 type Role struct {
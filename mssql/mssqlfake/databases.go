@@ -0,0 +1,444 @@
+package mssqlfake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vsabella/terraform-provider-mssql/mssql"
+)
+
+type database struct {
+	id        int64
+	name      string
+	contained bool
+
+	collation                   string
+	compatibilityLevel          int
+	recoveryModel               string
+	readCommittedSnapshot       bool
+	allowSnapshotIsolation      bool
+	acceleratedDatabaseRecovery bool
+	autoClose                   bool
+	autoShrink                  bool
+	autoCreateStats             bool
+	autoUpdateStats             bool
+	autoUpdateStatsAsync        bool
+
+	scoped map[string]*scopedConfiguration
+
+	principals map[string]*principal
+	schemas    map[string]*schema
+	objects    map[string]object
+	// securables holds the securables of the other classes, such as
+	// certificates and types, by securableKey.
+	securables  map[string]object
+	permissions permissionTable
+	// nextId allocates principal_id and schema_id values.
+	nextId int64
+}
+
+// schema is a row of sys.schemas.
+type schema struct {
+	id   int64
+	name string
+}
+
+type scopedConfiguration struct {
+	name  string
+	value string
+	// valueForSecondary is empty while secondaries use the primary's value.
+	valueForSecondary string
+	// secondary reports whether FOR SECONDARY may set the configuration.
+	secondary bool
+	// text configurations report ON and OFF as written.
+	text bool
+}
+
+// defaultScopedConfigurations are the values a new SQL Server 2022 database
+// reports in sys.database_scoped_configurations.
+var defaultScopedConfigurations = []scopedConfiguration{
+	{name: "ACCELERATED_PLAN_FORCING", value: "1"},
+	{name: "BATCH_MODE_ADAPTIVE_JOINS", value: "1"},
+	{name: "BATCH_MODE_MEMORY_GRANT_FEEDBACK", value: "1"},
+	{name: "BATCH_MODE_ON_ROWSTORE", value: "1"},
+	{name: "DEFERRED_COMPILATION_TV", value: "1"},
+	{name: "ELEVATE_ONLINE", value: "OFF", text: true},
+	{name: "ELEVATE_RESUMABLE", value: "OFF", text: true},
+	{name: "GLOBAL_TEMPORARY_TABLE_AUTO_DROP", value: "1"},
+	{name: "IDENTITY_CACHE", value: "1"},
+	{name: "INTERLEAVED_EXECUTION_TVF", value: "1"},
+	{name: "ISOLATE_SECURITY_POLICY_CARDINALITY", value: "0"},
+	{name: "LAST_QUERY_PLAN_STATS", value: "0"},
+	{name: "LEGACY_CARDINALITY_ESTIMATION", value: "0", secondary: true},
+	{name: "LIGHTWEIGHT_QUERY_PROFILING", value: "1"},
+	{name: "MAXDOP", value: "0", secondary: true},
+	{name: "OPTIMIZE_FOR_AD_HOC_WORKLOADS", value: "0"},
+	{name: "PARAMETER_SENSITIVE_PLAN_OPTIMIZATION", value: "1"},
+	{name: "PARAMETER_SNIFFING", value: "1", secondary: true},
+	{name: "QUERY_OPTIMIZER_HOTFIXES", value: "0", secondary: true},
+	{name: "ROW_MODE_MEMORY_GRANT_FEEDBACK", value: "1"},
+	{name: "TSQL_SCALAR_UDF_INLINING", value: "1"},
+	{name: "VERBOSE_TRUNCATION_WARNINGS", value: "1"},
+	{name: "XTP_PROCEDURE_EXECUTION_STATISTICS", value: "0"},
+	{name: "XTP_QUERY_EXECUTION_STATISTICS", value: "0"},
+}
+
+// fixedDatabaseRoles exist in every database.
+var fixedDatabaseRoles = []string{"public", "db_owner", "db_accessadmin", "db_securityadmin", "db_ddladmin",
+	"db_backupoperator", "db_datareader", "db_datawriter", "db_denydatareader", "db_denydatawriter"}
+
+func (c *Client) addDatabase(name string) *database {
+	db := &database{
+		id:                 c.nextDatabaseId,
+		name:               name,
+		collation:          c.info.Collation,
+		compatibilityLevel: int(c.info.MaxCompatibilityLevel()),
+		recoveryModel:      "FULL",
+		autoCreateStats:    true,
+		autoUpdateStats:    true,
+		scoped:             map[string]*scopedConfiguration{},
+		principals:         map[string]*principal{},
+		schemas:            map[string]*schema{},
+		objects:            map[string]object{},
+		securables:         map[string]object{},
+	}
+	c.nextDatabaseId++
+	if db.compatibilityLevel == 0 {
+		db.compatibilityLevel = 160
+	}
+	if c.info.IsAzureSQLDatabase() {
+		db.readCommittedSnapshot = true
+		db.allowSnapshotIsolation = true
+		db.acceleratedDatabaseRecovery = true
+	}
+	for _, cfg := range defaultScopedConfigurations {
+		cfg := cfg
+		db.scoped[key(cfg.name)] = &cfg
+	}
+
+	db.addPrincipal(&principal{name: "dbo", typ: "S", sid: c.logins["sa"].sid, defaultSchema: "dbo"})
+	db.addPrincipal(&principal{name: "guest", typ: "S", sid: "0x00", defaultSchema: "guest"})
+	db.addPrincipal(&principal{name: "INFORMATION_SCHEMA", typ: "S"})
+	db.addPrincipal(&principal{name: "sys", typ: "S"})
+	for _, role := range fixedDatabaseRoles {
+		db.addPrincipal(&principal{name: role, typ: "R", members: map[string]bool{}})
+	}
+	for _, name := range []string{"dbo", "guest", "INFORMATION_SCHEMA", "sys"} {
+		db.addSchema(name)
+	}
+
+	c.databases[key(name)] = db
+	return db
+}
+
+// addPrincipal adds a principal with the next free principal_id.
+func (db *database) addPrincipal(p *principal) {
+	db.nextId++
+	p.id = db.nextId
+	db.principals[key(p.name)] = p
+}
+
+// addSchema adds a schema with the next free schema_id.
+func (db *database) addSchema(name string) {
+	db.nextId++
+	db.schemas[key(name)] = &schema{id: db.nextId, name: name}
+}
+
+func (db *database) info() mssql.Database {
+	return mssql.Database{Id: db.id, Name: db.name}
+}
+
+// SetContained sets whether a database is a partially contained database,
+// which allows users with passwords.
+func (c *Client) SetContained(name string, contained bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	db, err := c.alterable(name)
+	if err != nil {
+		return err
+	}
+	db.contained = contained
+	return nil
+}
+
+// DropDatabase drops a database, e.g. to simulate drift.
+func (c *Client) DropDatabase(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	db := c.databases[key(name)]
+	if db == nil {
+		return sqlError(3701, "Cannot drop the database '%s', because it does not exist or you do not have permission.", name)
+	}
+	if db.id <= 4 {
+		return sqlError(3708, "Cannot drop the database '%s' because it is a system database.", db.name)
+	}
+	delete(c.databases, key(name))
+	return nil
+}
+
+func (c *Client) GetDatabase(ctx context.Context, name string) (mssql.Database, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.Database{}, err
+	}
+	defer unlock()
+
+	db := c.databases[key(name)]
+	if db == nil {
+		return mssql.Database{}, sql.ErrNoRows
+	}
+	return db.info(), nil
+}
+
+func (c *Client) GetDatabaseById(ctx context.Context, id int64) (mssql.Database, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.Database{}, err
+	}
+	defer unlock()
+
+	for _, db := range c.databases {
+		if db.id == id {
+			return db.info(), nil
+		}
+	}
+	return mssql.Database{}, sql.ErrNoRows
+}
+
+func (c *Client) CreateDatabase(ctx context.Context, name string) (mssql.Database, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.Database{}, err
+	}
+	defer unlock()
+
+	if c.databases[key(name)] != nil {
+		err := sqlError(1801, "Database '%s' already exists. Choose a different database name.", name)
+		return mssql.Database{}, fmt.Errorf("failed to create database: %v", err)
+	}
+	return c.addDatabase(name).info(), nil
+}
+
+// alterable returns a database that ALTER DATABASE may change.
+func (c *Client) alterable(name string) (*database, error) {
+	db := c.databases[key(name)]
+	if db == nil {
+		return nil, sqlError(5011, "User does not have permission to alter database '%s', the database does not exist, or the database is not in a state that allows access checks.", name)
+	}
+	return db, nil
+}
+
+func (c *Client) GetDatabaseOptions(ctx context.Context, name string) (mssql.DatabaseOptions, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.DatabaseOptions{}, err
+	}
+	defer unlock()
+
+	db := c.databases[key(name)]
+	if db == nil {
+		return mssql.DatabaseOptions{}, sql.ErrNoRows
+	}
+
+	compatibilityLevel := db.compatibilityLevel
+	recoveryModel := db.recoveryModel
+	readCommittedSnapshot := db.readCommittedSnapshot
+	allowSnapshotIsolation := db.allowSnapshotIsolation
+	acceleratedDatabaseRecovery := db.acceleratedDatabaseRecovery
+	autoClose := db.autoClose
+	autoShrink := db.autoShrink
+	autoCreateStats := db.autoCreateStats
+	autoUpdateStats := db.autoUpdateStats
+	autoUpdateStatsAsync := db.autoUpdateStatsAsync
+	return mssql.DatabaseOptions{
+		Collation:                   db.collation,
+		CompatibilityLevel:          &compatibilityLevel,
+		RecoveryModel:               &recoveryModel,
+		ReadCommittedSnapshot:       &readCommittedSnapshot,
+		AllowSnapshotIsolation:      &allowSnapshotIsolation,
+		AcceleratedDatabaseRecovery: &acceleratedDatabaseRecovery,
+		AutoClose:                   &autoClose,
+		AutoShrink:                  &autoShrink,
+		AutoCreateStats:             &autoCreateStats,
+		AutoUpdateStats:             &autoUpdateStats,
+		AutoUpdateStatsAsync:        &autoUpdateStatsAsync,
+	}, nil
+}
+
+// SetDatabaseOptions applies each option with its own ALTER DATABASE, like
+// the real client, and reports the options that failed together.
+func (c *Client) SetDatabaseOptions(ctx context.Context, name string, opts mssql.DatabaseOptions) error {
+	unlock, err := c.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if opts.RecoveryModel != nil && *opts.RecoveryModel != "" {
+		switch strings.ToUpper(strings.TrimSpace(*opts.RecoveryModel)) {
+		case "FULL", "BULK_LOGGED", "SIMPLE":
+		default:
+			return fmt.Errorf("invalid recovery_model %q", *opts.RecoveryModel)
+		}
+	}
+
+	var errs []string
+	alter := func(option string, set func(db *database) error) {
+		db, err := c.alterable(name)
+		if err == nil {
+			err = set(db)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", option, err))
+		}
+	}
+	setBool := func(option string, value *bool, field func(db *database) *bool) {
+		if value != nil {
+			alter(option, func(db *database) error {
+				*field(db) = *value
+				return nil
+			})
+		}
+	}
+
+	if opts.Collation != "" {
+		alter("COLLATE", func(db *database) error {
+			db.collation = opts.Collation
+			return nil
+		})
+	}
+	if opts.CompatibilityLevel != nil {
+		alter("COMPATIBILITY_LEVEL", func(db *database) error {
+			return c.setCompatibilityLevel(db, *opts.CompatibilityLevel)
+		})
+	}
+	if opts.RecoveryModel != nil && *opts.RecoveryModel != "" {
+		alter("RECOVERY", func(db *database) error {
+			db.recoveryModel = strings.ToUpper(strings.TrimSpace(*opts.RecoveryModel))
+			return nil
+		})
+	}
+	setBool("ALLOW_SNAPSHOT_ISOLATION", opts.AllowSnapshotIsolation, func(db *database) *bool { return &db.allowSnapshotIsolation })
+	setBool("READ_COMMITTED_SNAPSHOT", opts.ReadCommittedSnapshot, func(db *database) *bool { return &db.readCommittedSnapshot })
+	setBool("AUTO_CLOSE", opts.AutoClose, func(db *database) *bool { return &db.autoClose })
+	setBool("AUTO_SHRINK", opts.AutoShrink, func(db *database) *bool { return &db.autoShrink })
+	setBool("AUTO_CREATE_STATISTICS", opts.AutoCreateStats, func(db *database) *bool { return &db.autoCreateStats })
+	setBool("AUTO_UPDATE_STATISTICS", opts.AutoUpdateStats, func(db *database) *bool { return &db.autoUpdateStats })
+	setBool("AUTO_UPDATE_STATISTICS_ASYNC", opts.AutoUpdateStatsAsync, func(db *database) *bool { return &db.autoUpdateStatsAsync })
+	setBool("ACCELERATED_DATABASE_RECOVERY", opts.AcceleratedDatabaseRecovery, func(db *database) *bool { return &db.acceleratedDatabaseRecovery })
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to set database options: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// setCompatibilityLevel accepts the levels from SQL Server 2008 up to the
+// server's own version.
+func (c *Client) setCompatibilityLevel(db *database, level int) error {
+	max := int(c.info.MaxCompatibilityLevel())
+	if max == 0 {
+		max = 160
+	}
+	if level < 100 || level > max || level%10 != 0 {
+		var valid []string
+		for l := 100; l <= max; l += 10 {
+			valid = append(valid, fmt.Sprint(l))
+		}
+		return sqlError(15048, "Valid values of the database compatibility level are %s, or %s.",
+			strings.Join(valid[:len(valid)-1], ", "), valid[len(valid)-1])
+	}
+	db.compatibilityLevel = level
+	return nil
+}
+
+func (c *Client) GetDatabaseScopedConfigurations(ctx context.Context, name string) ([]mssql.DatabaseScopedConfiguration, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	db, err := c.open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []mssql.DatabaseScopedConfiguration
+	for _, cfg := range db.scoped {
+		configs = append(configs, mssql.DatabaseScopedConfiguration{
+			Name:              cfg.name,
+			Value:             cfg.value,
+			ValueForSecondary: cfg.valueForSecondary,
+		})
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+	return configs, nil
+}
+
+func (c *Client) SetDatabaseScopedConfiguration(ctx context.Context, name string, config mssql.DatabaseScopedConfiguration) error {
+	unlock, err := c.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db, err := c.open(name)
+	if err != nil {
+		return err
+	}
+	cfg := db.scoped[key(config.Name)]
+	if cfg == nil {
+		return sqlError(102, "Incorrect syntax near '%s'.", config.Name)
+	}
+	if config.ValueForSecondary != "" && !cfg.secondary {
+		return sqlError(102, "Incorrect syntax near '%s'.", config.Name)
+	}
+
+	cfg.value = cfg.reported(config.Value)
+	switch {
+	case strings.EqualFold(config.ValueForSecondary, "PRIMARY"):
+		cfg.valueForSecondary = ""
+	case config.ValueForSecondary != "":
+		cfg.valueForSecondary = cfg.reported(config.ValueForSecondary)
+	}
+	return nil
+}
+
+// reported is how sys.database_scoped_configurations reports a value: ON and
+// OFF are stored as 1 and 0 unless the configuration takes text values.
+func (cfg *scopedConfiguration) reported(value string) string {
+	if cfg.text {
+		return strings.ToUpper(value)
+	}
+	switch strings.ToUpper(value) {
+	case "ON":
+		return "1"
+	case "OFF":
+		return "0"
+	}
+	return value
+}
+
+// ClearDatabaseScopedConfiguration runs ALTER DATABASE SCOPED CONFIGURATION
+// CLEAR, which SQL Server only accepts for PROCEDURE_CACHE.
+func (c *Client) ClearDatabaseScopedConfiguration(ctx context.Context, name string, configName string) error {
+	unlock, err := c.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := c.open(name); err != nil {
+		return err
+	}
+	if !strings.EqualFold(configName, "PROCEDURE_CACHE") {
+		return sqlError(102, "Incorrect syntax near '%s'.", configName)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+// Package mssqlfake provides an in-memory mssql.SqlClient for tests that
+// should not need a SQL Server.
+//
+// The fake models a single server: databases with their options and scoped
+// configurations, logins, server roles and server permissions, and in each
+// database its users, roles, role memberships, schemas, objects and
+// permissions. Names are compared case-insensitively, as with the default
+// collations, and failing statements return mssqldb.Error values with the
+// numbers SQL Server uses.
+// Scripts passed to ExecScript are recorded, not parsed.
+package mssqlfake
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
+)
+
+var _ mssql.SqlClient = (*Client)(nil)
+
+// errClosed is returned once the client is closed, like database/sql does.
+var errClosed = errors.New("sql: database is closed")
+
+// DefaultServerInfo is the server a zero Config models: SQL Server 2022
+// Developer Edition.
+var DefaultServerInfo = mssql.ServerInfo{
+	ProductMajorVersion: 16,
+	EngineEdition:       mssql.EngineEditionEnterprise,
+	Edition:             "Developer Edition (64-bit)",
+	Collation:           "SQL_Latin1_General_CP1_CI_AS",
+}
+
+// Config configures a fake server.
+type Config struct {
+	// Database is the database the client connects to, used when a
+	// database argument is empty. Default: master.
+	Database string
+	// ServerInfo is reported by GetServerInfo and decides the defaults of new
+	// databases. DefaultServerInfo applies when nil.
+	ServerInfo *mssql.ServerInfo
+}
+
+// Client is an in-memory mssql.SqlClient. It is safe for concurrent use.
+type Client struct {
+	mu sync.Mutex
+
+	info     mssql.ServerInfo
+	database string
+	closed   bool
+
+	databases      map[string]*database
+	nextDatabaseId int64
+	logins         map[string]*login
+	serverRoles    map[string]*serverRole
+	// nextServerPrincipalId allocates the principal_id of logins and server
+	// roles.
+	nextServerPrincipalId int64
+	serverPermissions     permissionTable
+
+	scripts []Script
+}
+
+// Script is a script run with ExecScript.
+type Script struct {
+	Database string
+	Script   string
+}
+
+// New returns a fake server with the system databases, the sa login and the
+// fixed server roles.
+func New(cfg Config) *Client {
+	c := &Client{
+		info:           DefaultServerInfo,
+		database:       "master",
+		databases:      map[string]*database{},
+		nextDatabaseId: 1,
+		logins:         map[string]*login{},
+		serverRoles:    map[string]*serverRole{},
+	}
+	if cfg.ServerInfo != nil {
+		c.info = *cfg.ServerInfo
+	}
+	if cfg.Database != "" {
+		c.database = cfg.Database
+	}
+
+	c.addLogin(&login{name: "sa", sid: "0x01", defaultDatabase: "master", defaultLanguage: "us_english"})
+	for _, name := range []string{"sysadmin", "securityadmin", "serveradmin", "setupadmin", "processadmin", "diskadmin", "dbcreator", "bulkadmin", "public"} {
+		c.nextServerPrincipalId++
+		c.serverRoles[name] = &serverRole{id: c.nextServerPrincipalId, name: name, members: map[string]bool{}}
+	}
+	c.serverRoles["sysadmin"].members["sa"] = true
+
+	for _, name := range []string{"master", "tempdb", "model", "msdb"} {
+		c.addDatabase(name)
+	}
+	if c.databases[key(c.database)] == nil {
+		c.addDatabase(c.database)
+	}
+	return c
+}
+
+// key is the lookup key of a name.
+func key(name string) string {
+	return strings.ToLower(name)
+}
+
+// sqlError returns the error SQL Server reports for a failed statement.
+func sqlError(number int32, format string, args ...any) error {
+	return mssqldb.Error{Number: number, Class: 16, State: 1, Message: fmt.Sprintf(format, args...)}
+}
+
+// newSid returns a random SID in the form GetUser and GetLogin report.
+func newSid() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return "0x" + hex.EncodeToString(b)
+}
+
+// begin locks the client and returns the func that unlocks it.
+func (c *Client) begin() (func(), error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, errClosed
+	}
+	return c.mu.Unlock, nil
+}
+
+// open returns the named database, or the client's database for "", as
+// opening a connection to it would.
+func (c *Client) open(name string) (*database, error) {
+	if name == "" {
+		name = c.database
+	}
+	db := c.databases[key(name)]
+	if db == nil {
+		return nil, sqlError(4060, "Cannot open database \"%s\" requested by the login. The login failed.", name)
+	}
+	return db, nil
+}
+
+func (c *Client) GetServerInfo(ctx context.Context) (mssql.ServerInfo, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.ServerInfo{}, err
+	}
+	defer unlock()
+	return c.info, nil
+}
+
+func (c *Client) Ping(ctx context.Context) error {
+	unlock, err := c.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	_, err = c.open("")
+	return err
+}
+
+// Close makes every later call fail. The server state is kept.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *Client) ExecScript(ctx context.Context, database string, script string) error {
+	unlock, err := c.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return err
+	}
+	c.scripts = append(c.scripts, Script{Database: db.name, Script: script})
+	return nil
+}
+
+// Scripts returns the scripts run with ExecScript, oldest first.
+func (c *Client) Scripts() []Script {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Script(nil), c.scripts...)
+}
@@ -0,0 +1,468 @@
+package mssqlfake
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
+)
+
+func errorNumber(err error) int32 {
+	var sqlErr mssqldb.Error
+	if errors.As(err, &sqlErr) {
+		return sqlErr.Number
+	}
+	return 0
+}
+
+func Test_Client_Users(t *testing.T) {
+	ctx := context.Background()
+	c := New(Config{Database: "app"})
+
+	if _, err := c.CreateLogin(ctx, mssql.CreateLogin{Name: "app", Password: "s3cret", DefaultDatabase: "app"}); err != nil {
+		t.Fatalf("CreateLogin() error = %v", err)
+	}
+	user, err := c.CreateUser(ctx, "", mssql.CreateUser{Username: "app_user", LoginName: "APP", DefaultSchema: "dbo"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if user.LoginName != "app" || user.Type != "S" || user.Sid == "" {
+		t.Fatalf("CreateUser() = %+v, want a user mapped to login app", user)
+	}
+
+	_, err = c.CreateUser(ctx, "app", mssql.CreateUser{Username: "APP_USER", LoginName: "app", DefaultSchema: "dbo"})
+	if got := errorNumber(err); got != 15023 {
+		t.Fatalf("CreateUser() of an existing user error = %v, want 15023", err)
+	}
+	_, err = c.CreateUser(ctx, "app", mssql.CreateUser{Username: "other", LoginName: "app", DefaultSchema: "dbo"})
+	if got := errorNumber(err); got != 15063 {
+		t.Fatalf("CreateUser() for a mapped login error = %v, want 15063", err)
+	}
+	_, err = c.CreateUser(ctx, "app", mssql.CreateUser{Username: "contained", Password: "s3cret", DefaultSchema: "dbo"})
+	if got := errorNumber(err); got != 33233 {
+		t.Fatalf("CreateUser() with a password error = %v, want 33233", err)
+	}
+	if err := c.SetContained("app", true); err != nil {
+		t.Fatalf("SetContained() error = %v", err)
+	}
+	if _, err := c.CreateUser(ctx, "app", mssql.CreateUser{Username: "contained", Password: "s3cret", DefaultSchema: "dbo"}); err != nil {
+		t.Fatalf("CreateUser() in a contained database error = %v", err)
+	}
+
+	user, err = c.UpdateUser(ctx, "app", mssql.UpdateUser{Id: "app_user", DefaultSchema: "sales"})
+	if err != nil || user.DefaultSchema != "sales" {
+		t.Fatalf("UpdateUser() = %+v, %v, want default schema sales", user, err)
+	}
+
+	if err := c.DeleteUser(ctx, "app", "app_user"); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if err := c.DeleteUser(ctx, "app", "app_user"); err != nil {
+		t.Fatalf("DeleteUser() of a missing user error = %v", err)
+	}
+	if _, err := c.GetUser(ctx, "app", "app_user"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetUser() after DeleteUser() error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := c.GetUser(ctx, "missing", "dbo"); errorNumber(err) != 4060 {
+		t.Fatalf("GetUser() in a missing database error = %v, want 4060", err)
+	}
+}
+
+func Test_Client_Roles(t *testing.T) {
+	ctx := context.Background()
+	c := New(Config{})
+	if err := c.SetContained("master", true); err != nil {
+		t.Fatalf("SetContained() error = %v", err)
+	}
+	if _, err := c.CreateUser(ctx, "", mssql.CreateUser{Username: "reader", Password: "s3cret", DefaultSchema: "dbo"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	role, err := c.CreateRole(ctx, "", "Readers")
+	if err != nil || role.Id != "Readers" {
+		t.Fatalf("CreateRole() = %+v, %v", role, err)
+	}
+	rm, err := c.AssignRole(ctx, "", "readers", "READER")
+	if err != nil {
+		t.Fatalf("AssignRole() error = %v", err)
+	}
+	if rm.Role != "Readers" || rm.Member != "reader" {
+		t.Fatalf("AssignRole() = %+v, want the stored names", rm)
+	}
+	if _, err := c.ReadRoleMembership(ctx, "", rm.Id); err != nil {
+		t.Fatalf("ReadRoleMembership() error = %v", err)
+	}
+	if _, err := c.AssignRole(ctx, "", "missing", "reader"); errorNumber(err) != 15151 {
+		t.Fatalf("AssignRole() to a missing role error = %v, want 15151", err)
+	}
+
+	if err := c.DeleteRole(ctx, "", "Readers"); errorNumber(err) != 15144 {
+		t.Fatalf("DeleteRole() with members error = %v, want 15144", err)
+	}
+	if err := c.DeleteUser(ctx, "", "reader"); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	// Dropping the user removed its membership.
+	if _, err := c.ReadRoleMembership(ctx, "", rm.Id); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("ReadRoleMembership() error = %v, want sql.ErrNoRows", err)
+	}
+	if err := c.DeleteRole(ctx, "", "Readers"); err != nil {
+		t.Fatalf("DeleteRole() error = %v", err)
+	}
+	if _, err := c.GetRole(ctx, "", "Readers"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetRole() after DeleteRole() error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func Test_Client_Permissions(t *testing.T) {
+	ctx := context.Background()
+	c := New(Config{})
+	if _, err := c.CreateRole(ctx, "", "reader"); err != nil {
+		t.Fatalf("CreateRole() error = %v", err)
+	}
+	if err := c.AddSchema("", "sales"); err != nil {
+		t.Fatalf("AddSchema() error = %v", err)
+	}
+	if err := c.AddObject("", "sales", "Orders"); err != nil {
+		t.Fatalf("AddObject() error = %v", err)
+	}
+
+	grant := mssql.GrantPermission{Principal: "reader", Permission: "select", ObjectType: "TABLE", ObjectName: "sales.orders"}
+	if _, err := c.GrantPermission(ctx, grant); err != nil {
+		t.Fatalf("GrantPermission() error = %v", err)
+	}
+	got, err := c.ReadPermission(ctx, grant)
+	if err != nil {
+		t.Fatalf("ReadPermission() error = %v", err)
+	}
+	if got.Permission != "SELECT" || got.ObjectType != "TABLE" || got.ObjectName != "sales.Orders" {
+		t.Fatalf("ReadPermission() = %+v, want SELECT on TABLE sales.Orders", got)
+	}
+
+	_, err = c.GrantPermission(ctx, mssql.GrantPermission{Principal: "reader", Permission: "SELECT", ObjectType: "TABLE", ObjectName: "missing"})
+	if err == nil || !strings.Contains(err.Error(), "Cannot find the object 'missing'") {
+		t.Fatalf("GrantPermission() on a missing object error = %v", err)
+	}
+
+	schemaGrant := mssql.GrantPermission{Principal: "reader", Permission: "EXECUTE", ObjectType: "SCHEMA", ObjectName: "sales"}
+	if _, err := c.GrantPermission(ctx, schemaGrant); err != nil {
+		t.Fatalf("GrantPermission() error = %v", err)
+	}
+	if got, err := c.ReadPermission(ctx, schemaGrant); err != nil || got.ObjectType != "SCHEMA" {
+		t.Fatalf("ReadPermission() = %+v, %v, want EXECUTE on SCHEMA sales", got, err)
+	}
+
+	deny := schemaGrant
+	deny.State = "DENY"
+	if _, err := c.GrantPermission(ctx, deny); err != nil {
+		t.Fatalf("GrantPermission() of a deny error = %v", err)
+	}
+	if got, err := c.ReadPermission(ctx, schemaGrant); err != nil || got.State != "DENY" {
+		t.Fatalf("ReadPermission() = %+v, %v, want EXECUTE denied", got, err)
+	}
+	if _, err := c.GrantPermission(ctx, schemaGrant); err != nil {
+		t.Fatalf("GrantPermission() error = %v", err)
+	}
+	if got, err := c.ReadPermission(ctx, schemaGrant); err != nil || got.State != "GRANT" {
+		t.Fatalf("ReadPermission() = %+v, %v, want EXECUTE granted", got, err)
+	}
+
+	if err := c.AddColumns("", "sales", "Orders", "Id", "Region", "Email"); err != nil {
+		t.Fatalf("AddColumns() error = %v", err)
+	}
+	columnGrant := mssql.GrantPermission{Principal: "reader", Permission: "SELECT", ObjectType: "TABLE", ObjectName: "sales.Orders", Columns: []string{"region", "id"}}
+	if _, err := c.GrantPermission(ctx, columnGrant); err != nil {
+		t.Fatalf("GrantPermission() of columns error = %v", err)
+	}
+	if got, err := c.ReadPermission(ctx, columnGrant); err != nil || strings.Join(got.Columns, ",") != "Id,Region" {
+		t.Fatalf("ReadPermission() of columns = %+v, %v, want Id and Region", got, err)
+	}
+	_, err = c.GrantPermission(ctx, mssql.GrantPermission{Principal: "reader", Permission: "SELECT", ObjectType: "TABLE", ObjectName: "sales.Orders", Columns: []string{"missing"}})
+	if err == nil || !strings.Contains(err.Error(), "Invalid column name 'missing'") {
+		t.Fatalf("GrantPermission() on a missing column error = %v", err)
+	}
+	if err := c.RevokePermission(ctx, mssql.GrantPermission{Principal: "reader", Permission: "SELECT", ObjectType: "TABLE", ObjectName: "sales.Orders", Columns: []string{"Id"}}); err != nil {
+		t.Fatalf("RevokePermission() of a column error = %v", err)
+	}
+	if got, err := c.ReadPermission(ctx, columnGrant); err != nil || strings.Join(got.Columns, ",") != "Region" {
+		t.Fatalf("ReadPermission() of columns after RevokePermission() = %+v, %v, want Region", got, err)
+	}
+
+	if err := c.AddSecurable("", "CERTIFICATE", "CodeSigning.v2"); err != nil {
+		t.Fatalf("AddSecurable() error = %v", err)
+	}
+	certGrant := mssql.GrantPermission{Principal: "reader", Permission: "CONTROL", ObjectType: "CERTIFICATE", ObjectName: "codesigning.v2"}
+	if _, err := c.GrantPermission(ctx, certGrant); err != nil {
+		t.Fatalf("GrantPermission() on a certificate error = %v", err)
+	}
+	if got, err := c.ReadPermission(ctx, certGrant); err != nil || got.ObjectName != "CodeSigning.v2" {
+		t.Fatalf("ReadPermission() on a certificate = %+v, %v, want CONTROL on CodeSigning.v2", got, err)
+	}
+	_, err = c.GrantPermission(ctx, mssql.GrantPermission{Principal: "reader", Permission: "CONTROL", ObjectType: "ASYMMETRIC KEY", ObjectName: "CodeSigning.v2"})
+	if err == nil || !strings.Contains(err.Error(), "Cannot find the asymmetric key 'CodeSigning.v2'") {
+		t.Fatalf("GrantPermission() on a certificate as an asymmetric key error = %v", err)
+	}
+	if _, err := c.GrantPermission(ctx, mssql.GrantPermission{Principal: "reader", Permission: "ALTER", ObjectType: "ROLE", ObjectName: "reader"}); err != nil {
+		t.Fatalf("GrantPermission() on a role error = %v", err)
+	}
+	_, err = c.GrantPermission(ctx, mssql.GrantPermission{Principal: "reader", Permission: "IMPERSONATE", ObjectType: "USER", ObjectName: "reader"})
+	if err == nil || !strings.Contains(err.Error(), "Cannot find the user 'reader'") {
+		t.Fatalf("GrantPermission() on a role as a user error = %v", err)
+	}
+
+	if _, err := c.GrantDatabasePermission(ctx, "", "reader", "connect"); err != nil {
+		t.Fatalf("GrantDatabasePermission() error = %v", err)
+	}
+	if dp, err := c.ReadDatabasePermission(ctx, "", "reader/connect"); err != nil || dp.Id != "reader/connect" {
+		t.Fatalf("ReadDatabasePermission() = %+v, %v", dp, err)
+	}
+
+	if err := c.RevokePermission(ctx, grant); err != nil {
+		t.Fatalf("RevokePermission() error = %v", err)
+	}
+	if _, err := c.ReadPermission(ctx, grant); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("ReadPermission() after RevokePermission() error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := c.ReadPermission(ctx, schemaGrant); err != nil {
+		t.Fatalf("ReadPermission() of another grant error = %v", err)
+	}
+}
+
+func Test_Client_Databases(t *testing.T) {
+	ctx := context.Background()
+	c := New(Config{})
+
+	db, err := c.CreateDatabase(ctx, "app")
+	if err != nil {
+		t.Fatalf("CreateDatabase() error = %v", err)
+	}
+	if got, err := c.GetDatabaseById(ctx, db.Id); err != nil || got.Name != "app" {
+		t.Fatalf("GetDatabaseById() = %+v, %v", got, err)
+	}
+	if _, err := c.CreateDatabase(ctx, "APP"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("CreateDatabase() of an existing database error = %v", err)
+	}
+
+	level, model, rcsi := 150, "simple", true
+	if err := c.SetDatabaseOptions(ctx, "app", mssql.DatabaseOptions{CompatibilityLevel: &level, RecoveryModel: &model, ReadCommittedSnapshot: &rcsi}); err != nil {
+		t.Fatalf("SetDatabaseOptions() error = %v", err)
+	}
+	opts, err := c.GetDatabaseOptions(ctx, "app")
+	if err != nil {
+		t.Fatalf("GetDatabaseOptions() error = %v", err)
+	}
+	if *opts.CompatibilityLevel != 150 || *opts.RecoveryModel != "SIMPLE" || !*opts.ReadCommittedSnapshot || !*opts.AutoCreateStats {
+		t.Fatalf("GetDatabaseOptions() = %+v", opts)
+	}
+	level = 170
+	if err := c.SetDatabaseOptions(ctx, "app", mssql.DatabaseOptions{CompatibilityLevel: &level}); err == nil || !strings.Contains(err.Error(), "COMPATIBILITY_LEVEL") {
+		t.Fatalf("SetDatabaseOptions() with an unsupported level error = %v", err)
+	}
+
+	if err := c.SetDatabaseScopedConfiguration(ctx, "app", mssql.DatabaseScopedConfiguration{Name: "MAXDOP", Value: "4", ValueForSecondary: "2"}); err != nil {
+		t.Fatalf("SetDatabaseScopedConfiguration() error = %v", err)
+	}
+	configs, err := c.GetDatabaseScopedConfigurations(ctx, "app")
+	if err != nil {
+		t.Fatalf("GetDatabaseScopedConfigurations() error = %v", err)
+	}
+	found := false
+	for _, cfg := range configs {
+		if cfg.Name == "MAXDOP" {
+			found = cfg.Value == "4" && cfg.ValueForSecondary == "2"
+		}
+	}
+	if !found {
+		t.Fatalf("GetDatabaseScopedConfigurations() = %+v, want MAXDOP 4 and 2 for secondaries", configs)
+	}
+	if err := c.SetDatabaseScopedConfiguration(ctx, "app", mssql.DatabaseScopedConfiguration{Name: "NOPE", Value: "1"}); errorNumber(err) != 102 {
+		t.Fatalf("SetDatabaseScopedConfiguration() of an unknown configuration error = %v, want 102", err)
+	}
+
+	if err := c.DropDatabase("app"); err != nil {
+		t.Fatalf("DropDatabase() error = %v", err)
+	}
+	if _, err := c.GetDatabase(ctx, "app"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetDatabase() after DropDatabase() error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func Test_Client_Logins(t *testing.T) {
+	ctx := context.Background()
+	c := New(Config{})
+
+	login, err := c.CreateLogin(ctx, mssql.CreateLogin{Name: "app", Password: "s3cret", Sid: "0xABCD"})
+	if err != nil {
+		t.Fatalf("CreateLogin() error = %v", err)
+	}
+	if login.Sid != "0xabcd" || login.DefaultDatabase != "master" {
+		t.Fatalf("CreateLogin() = %+v", login)
+	}
+	if _, err := c.CreateLogin(ctx, mssql.CreateLogin{Name: "other", Password: "s3cret", Sid: "0xabcd"}); err == nil || !strings.Contains(err.Error(), "sid is in use") {
+		t.Fatalf("CreateLogin() with a used SID error = %v", err)
+	}
+	if _, err := c.UpdateLogin(ctx, mssql.UpdateLogin{Name: "app", DefaultDatabase: "missing"}); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("UpdateLogin() with a missing database error = %v", err)
+	}
+
+	if _, err := c.AssignServerRole(ctx, "dbcreator", "APP"); err != nil {
+		t.Fatalf("AssignServerRole() error = %v", err)
+	}
+	if rm, err := c.ReadServerRoleMembership(ctx, "dbcreator", "app"); err != nil || rm.Member != "app" {
+		t.Fatalf("ReadServerRoleMembership() = %+v, %v", rm, err)
+	}
+	if err := c.DeleteLogin(ctx, "app"); err != nil {
+		t.Fatalf("DeleteLogin() error = %v", err)
+	}
+	if _, err := c.ReadServerRoleMembership(ctx, "dbcreator", "app"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("ReadServerRoleMembership() after DeleteLogin() error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func Test_Client_ServerPermissions(t *testing.T) {
+	ctx := context.Background()
+	c := New(Config{})
+	for _, name := range []string{"monitor", "etl"} {
+		if _, err := c.CreateLogin(ctx, mssql.CreateLogin{Name: name, Password: "s3cret"}); err != nil {
+			t.Fatalf("CreateLogin(%s) error = %v", name, err)
+		}
+	}
+
+	state := mssql.ServerPermission{Principal: "monitor", Permission: "view server state", WithGrantOption: true}
+	got, err := c.GrantServerPermission(ctx, state)
+	if err != nil || got.Principal != "monitor" || got.Permission != "VIEW SERVER STATE" || got.State != "GRANT" {
+		t.Fatalf("GrantServerPermission() = %+v, %v", got, err)
+	}
+	if got, err := c.ReadServerPermission(ctx, mssql.ServerPermission{Principal: "monitor", Permission: "VIEW SERVER STATE"}); err != nil || !got.WithGrantOption {
+		t.Fatalf("ReadServerPermission() = %+v, %v, want the grant option", got, err)
+	}
+	if err := c.RevokeServerGrantOption(ctx, state); err != nil {
+		t.Fatalf("RevokeServerGrantOption() error = %v", err)
+	}
+	if got, err := c.ReadServerPermission(ctx, state); err != nil || got.State != "GRANT" || got.WithGrantOption {
+		t.Fatalf("ReadServerPermission() after RevokeServerGrantOption() = %+v, %v, want GRANT", got, err)
+	}
+
+	// A permission on a login is not the same permission on the server.
+	impersonate := mssql.ServerPermission{Principal: "monitor", Permission: "IMPERSONATE", ObjectType: "LOGIN", ObjectName: "ETL", State: "DENY"}
+	if _, err := c.GrantServerPermission(ctx, impersonate); err != nil {
+		t.Fatalf("GrantServerPermission() on a login error = %v", err)
+	}
+	if got, err := c.ReadServerPermission(ctx, impersonate); err != nil || got.State != "DENY" || got.ObjectName != "etl" {
+		t.Fatalf("ReadServerPermission() on a login = %+v, %v, want DENY on etl", got, err)
+	}
+	if _, err := c.ReadServerPermission(ctx, mssql.ServerPermission{Principal: "monitor", Permission: "IMPERSONATE"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("ReadServerPermission() on the server error = %v, want sql.ErrNoRows", err)
+	}
+	missing := impersonate
+	missing.ObjectName = "missing"
+	if _, err := c.GrantServerPermission(ctx, missing); err == nil || !strings.Contains(err.Error(), "Cannot find the login 'missing'") {
+		t.Fatalf("GrantServerPermission() on a missing login error = %v", err)
+	}
+	if _, err := c.GrantServerPermission(ctx, mssql.ServerPermission{Principal: "nobody", Permission: "VIEW SERVER STATE"}); err == nil || !strings.Contains(err.Error(), "Cannot find the login 'nobody'") {
+		t.Fatalf("GrantServerPermission() to a missing login error = %v", err)
+	}
+
+	if err := c.RevokeServerPermission(ctx, state); err != nil {
+		t.Fatalf("RevokeServerPermission() error = %v", err)
+	}
+	if _, err := c.ReadServerPermission(ctx, state); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("ReadServerPermission() after RevokeServerPermission() error = %v, want sql.ErrNoRows", err)
+	}
+
+	// Dropping a login drops the permissions on it.
+	if err := c.DeleteLogin(ctx, "etl"); err != nil {
+		t.Fatalf("DeleteLogin() error = %v", err)
+	}
+	if _, err := c.ReadServerPermission(ctx, impersonate); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("ReadServerPermission() after DeleteLogin() error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func Test_Client_NameLookups(t *testing.T) {
+	ctx := context.Background()
+	c := New(Config{Database: "app"})
+
+	if _, err := c.CreateRole(ctx, "app", "readers"); err != nil {
+		t.Fatalf("CreateRole() error = %v", err)
+	}
+	if err := c.AddSchema("app", "sales"); err != nil {
+		t.Fatalf("AddSchema() error = %v", err)
+	}
+	if _, err := c.CreateLogin(ctx, mssql.CreateLogin{Name: "app", Password: "s3cret"}); err != nil {
+		t.Fatalf("CreateLogin() error = %v", err)
+	}
+
+	id, err := c.GetDatabasePrincipalId(ctx, "app", "READERS")
+	if err != nil {
+		t.Fatalf("GetDatabasePrincipalId() error = %v", err)
+	}
+	if name, err := c.GetDatabasePrincipalName(ctx, "app", id); err != nil || name != "readers" {
+		t.Fatalf("GetDatabasePrincipalName(%d) = %q, %v, want readers", id, name, err)
+	}
+	id, err = c.GetSecurableId(ctx, "app", "SCHEMA", "sales")
+	if err != nil {
+		t.Fatalf("GetSecurableId() error = %v", err)
+	}
+	if name, err := c.GetSchemaName(ctx, "app", id); err != nil || name != "sales" {
+		t.Fatalf("GetSchemaName(%d) = %q, %v, want sales", id, name, err)
+	}
+	id, err = c.GetServerPrincipalId(ctx, "app")
+	if err != nil {
+		t.Fatalf("GetServerPrincipalId() error = %v", err)
+	}
+	if name, err := c.GetServerPrincipalName(ctx, id); err != nil || name != "app" {
+		t.Fatalf("GetServerPrincipalName(%d) = %q, %v, want app", id, name, err)
+	}
+
+	if _, err := c.GetDatabasePrincipalName(ctx, "app", 9999); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetDatabasePrincipalName() of a missing id error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := c.GetServerPrincipalName(ctx, 9999); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetServerPrincipalName() of a missing id error = %v, want sql.ErrNoRows", err)
+	}
+
+	if err := c.AddObject("app", "dbo", "orders"); err != nil {
+		t.Fatalf("AddObject() error = %v", err)
+	}
+	if _, err := c.GetSecurableId(ctx, "app", "TABLE", "orders"); err != nil {
+		t.Fatalf("GetSecurableId() of dbo.orders error = %v", err)
+	}
+	if _, err := c.GetSecurableId(ctx, "app", "TABLE", "sales.orders"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetSecurableId() of a missing object error = %v, want sql.ErrNoRows", err)
+	}
+	if err := c.AddSecurable("app", "TYPE", "Money"); err != nil {
+		t.Fatalf("AddSecurable() error = %v", err)
+	}
+	if _, err := c.GetSecurableId(ctx, "app", "TYPE", "dbo.Money"); err != nil {
+		t.Fatalf("GetSecurableId() of type dbo.Money error = %v", err)
+	}
+	if _, err := c.GetSecurableId(ctx, "app", "XML SCHEMA COLLECTION", "dbo.Money"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetSecurableId() of a type as an XML schema collection error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := c.GetDatabasePrincipalId(ctx, "app", "writers"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetDatabasePrincipalId() of a missing principal error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func Test_Client_Close(t *testing.T) {
+	ctx := context.Background()
+	c := New(Config{})
+
+	if err := c.ExecScript(ctx, "", "SELECT 1"); err != nil {
+		t.Fatalf("ExecScript() error = %v", err)
+	}
+	if scripts := c.Scripts(); len(scripts) != 1 || scripts[0].Database != "master" {
+		t.Fatalf("Scripts() = %+v", scripts)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := c.Ping(ctx); err == nil {
+		t.Fatal("Ping() after Close() error = nil")
+	}
+}
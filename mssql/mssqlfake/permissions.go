@@ -0,0 +1,830 @@
+package mssqlfake
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+	"github.com/vsabella/terraform-provider-mssql/mssql"
+)
+
+// Securable classes of sys.database_permissions.
+const (
+	classDatabase  = 0
+	classObject    = 1
+	classSchema    = 3
+	classPrincipal = 4
+)
+
+// securableClass is the class of the securables of an object_type.
+type securableClass struct {
+	// name is the securable in error messages.
+	name  string
+	class int
+	// schemaScoped securables are named schema.name.
+	schemaScoped bool
+	// principalTypes are the principal types of principal classes.
+	principalTypes string
+}
+
+var securableClasses = map[string]securableClass{
+	"OBJECT":                {name: "object", class: classObject, schemaScoped: true},
+	"TABLE":                 {name: "object", class: classObject, schemaScoped: true},
+	"VIEW":                  {name: "object", class: classObject, schemaScoped: true},
+	"PROCEDURE":             {name: "object", class: classObject, schemaScoped: true},
+	"PROC":                  {name: "object", class: classObject, schemaScoped: true},
+	"FUNCTION":              {name: "object", class: classObject, schemaScoped: true},
+	"SEQUENCE":              {name: "object", class: classObject, schemaScoped: true},
+	"SYNONYM":               {name: "object", class: classObject, schemaScoped: true},
+	"SCHEMA":                {name: "schema", class: classSchema},
+	"USER":                  {name: "user", class: classPrincipal, principalTypes: "SEX"},
+	"ROLE":                  {name: "role", class: classPrincipal, principalTypes: "R"},
+	"APPLICATION ROLE":      {name: "application role", class: classPrincipal, principalTypes: "A"},
+	"ASSEMBLY":              {name: "assembly", class: 5},
+	"TYPE":                  {name: "type", class: 6, schemaScoped: true},
+	"XML SCHEMA COLLECTION": {name: "xml schema collection", class: 10, schemaScoped: true},
+	"SERVICE":               {name: "service", class: 17},
+	"FULLTEXT CATALOG":      {name: "fulltext catalog", class: 23},
+	"SYMMETRIC KEY":         {name: "symmetric key", class: 24},
+	"CERTIFICATE":           {name: "certificate", class: 25},
+	"ASYMMETRIC KEY":        {name: "asymmetric key", class: 26},
+}
+
+// lookupSecurableClass returns the class of an object_type.
+func lookupSecurableClass(objectType string) (securableClass, error) {
+	c, ok := securableClasses[strings.Join(strings.Fields(strings.ToUpper(objectType)), " ")]
+	if !ok {
+		return c, fmt.Errorf("object_type must be one of %s; got %q", strings.Join(mssql.ObjectTypes, ", "), objectType)
+	}
+	return c, nil
+}
+
+// splitName splits the name of a securable of the class into its schema and
+// name.
+func (sc securableClass) splitName(name string) (string, string) {
+	if !sc.schemaScoped {
+		return "", name
+	}
+	return splitSchemaObject(name)
+}
+
+func securableKey(class int, schema string, name string) string {
+	return fmt.Sprintf("%d/%s", class, objectKey(schema, name))
+}
+
+// object is a schema-scoped object such as a table, view or procedure.
+type object struct {
+	id      int64
+	schema  string
+	name    string
+	columns []string
+}
+
+// permission is a row of sys.database_permissions.
+type permission struct {
+	grantee    string
+	permission string
+	class      int
+	// schema and name identify the securable; name is the schema for
+	// schema permissions.
+	schema string
+	name   string
+	// column is set for permissions on a column of an object.
+	column string
+	// state is G for GRANT, W for GRANT WITH GRANT OPTION and D for DENY.
+	state string
+}
+
+func objectKey(schema string, name string) string {
+	return key(schema) + "." + key(name)
+}
+
+// AddSchema creates a schema in a database.
+func (c *Client) AddSchema(database string, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return err
+	}
+	if _, ok := db.schemas[key(name)]; ok {
+		return sqlError(2714, "There is already an object named '%s' in the database.", name)
+	}
+	db.addSchema(name)
+	return nil
+}
+
+// AddObject creates an object, such as a table, in an existing schema, so
+// that permissions can be granted on it.
+func (c *Client) AddObject(database string, schema string, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return err
+	}
+	s, ok := db.schemas[key(schema)]
+	if !ok {
+		return sqlError(2760, "The specified schema name \"%s\" either does not exist or you do not have permission to use it.", schema)
+	}
+	if _, ok := db.objects[objectKey(schema, name)]; ok {
+		return sqlError(2714, "There is already an object named '%s' in the database.", name)
+	}
+	db.nextId++
+	db.objects[objectKey(schema, name)] = object{id: db.nextId, schema: s.name, name: name}
+	return nil
+}
+
+// AddColumns adds columns to an existing object, so that permissions can be
+// granted on them.
+func (c *Client) AddColumns(database string, schema string, name string, columns ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return err
+	}
+	o, ok := db.objects[objectKey(schema, name)]
+	if !ok {
+		return sqlError(208, "Invalid object name '%s.%s'.", schema, name)
+	}
+	o.columns = append(o.columns, columns...)
+	db.objects[objectKey(schema, name)] = o
+	return nil
+}
+
+// AddSecurable creates a securable of a class without a helper of its own,
+// such as a certificate or a type, so that permissions can be granted on it.
+// Unqualified names of schema-scoped securables are created in dbo.
+func (c *Client) AddSecurable(database string, objectType string, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return err
+	}
+	sc, err := lookupSecurableClass(objectType)
+	if err != nil {
+		return err
+	}
+	if sc.class == classObject || sc.class == classSchema || sc.class == classPrincipal {
+		return fmt.Errorf("use AddObject, AddSchema or CreateUser to create a %s", sc.name)
+	}
+	schema, name := sc.splitName(name)
+	if sc.schemaScoped {
+		if schema == "" {
+			schema = "dbo"
+		}
+		s, ok := db.schemas[key(schema)]
+		if !ok {
+			return sqlError(2760, "The specified schema name \"%s\" either does not exist or you do not have permission to use it.", schema)
+		}
+		schema = s.name
+	}
+	k := securableKey(sc.class, schema, name)
+	if _, ok := db.securables[k]; ok {
+		return sqlError(2714, "There is already an object named '%s' in the database.", name)
+	}
+	db.nextId++
+	db.securables[k] = object{id: db.nextId, schema: schema, name: name}
+	return nil
+}
+
+// GetSecurableId returns the id of a securable. Unqualified names of
+// schema-scoped securables resolve in dbo.
+func (c *Client) GetSecurableId(ctx context.Context, database string, objectType string, objectName string) (int64, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return 0, err
+	}
+	class, schema, name, err := db.securable(mssql.GrantPermission{ObjectType: objectType, ObjectName: objectName})
+	var sqlErr mssqldb.Error
+	if errors.As(err, &sqlErr) {
+		return 0, sql.ErrNoRows
+	}
+	if err != nil {
+		return 0, err
+	}
+	switch class {
+	case classSchema:
+		return db.schemas[key(name)].id, nil
+	case classObject:
+		return db.objects[objectKey(schema, name)].id, nil
+	case classPrincipal:
+		return db.principals[key(name)].id, nil
+	default:
+		return db.securables[securableKey(class, schema, name)].id, nil
+	}
+}
+
+func (c *Client) GetSchemaName(ctx context.Context, database string, id int64) (string, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return "", err
+	}
+	for _, s := range db.schemas {
+		if s.id == id {
+			return s.name, nil
+		}
+	}
+	return "", sql.ErrNoRows
+}
+
+// grantee returns the key of the principal a permission is granted to.
+func (db *database) grantee(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if db.principals[key(name)] == nil {
+		return "", sqlError(15151, "Cannot find the user '%s', because it does not exist or you do not have permission.", name)
+	}
+	return key(name), nil
+}
+
+// securable resolves the securable of a grant to the class, schema and name
+// of its permissions. Unqualified object names resolve in dbo.
+func (db *database) securable(grant mssql.GrantPermission) (int, string, string, error) {
+	hasObjectType := strings.TrimSpace(grant.ObjectType) != ""
+	hasObjectName := strings.TrimSpace(grant.ObjectName) != ""
+	if hasObjectType != hasObjectName {
+		return 0, "", "", fmt.Errorf("object_type and object_name must be set together")
+	}
+	if !hasObjectType {
+		return classDatabase, "", "", nil
+	}
+
+	sc, err := lookupSecurableClass(grant.ObjectType)
+	if err != nil {
+		return 0, "", "", err
+	}
+	schema, name := sc.splitName(grant.ObjectName)
+	notFound := sqlError(15151, "Cannot find the %s '%s', because it does not exist or you do not have permission.", sc.name, name)
+	if sc.schemaScoped && schema == "" {
+		schema = "dbo"
+	}
+	switch sc.class {
+	case classSchema:
+		s, ok := db.schemas[key(name)]
+		if !ok {
+			return 0, "", "", notFound
+		}
+		return classSchema, "", s.name, nil
+	case classObject:
+		o, ok := db.objects[objectKey(schema, name)]
+		if !ok {
+			return 0, "", "", notFound
+		}
+		return classObject, o.schema, o.name, nil
+	case classPrincipal:
+		p := db.principals[key(name)]
+		if p == nil || !strings.Contains(sc.principalTypes, p.typ) {
+			return 0, "", "", notFound
+		}
+		return classPrincipal, "", p.name, nil
+	default:
+		o, ok := db.securables[securableKey(sc.class, schema, name)]
+		if !ok {
+			return 0, "", "", notFound
+		}
+		return sc.class, o.schema, o.name, nil
+	}
+}
+
+// splitSchemaObject splits "schema.object" into schema + object.
+func splitSchemaObject(name string) (string, string) {
+	if schema, object, found := strings.Cut(name, "."); found {
+		return schema, object
+	}
+	return "", name
+}
+
+// targets returns the permission rows a grant applies to: one per column,
+// or the securable itself.
+func (db *database) targets(p permission, columns []string) ([]permission, error) {
+	if len(columns) == 0 {
+		return []permission{p}, nil
+	}
+	if p.class != classObject {
+		return nil, fmt.Errorf("columns require an object_type of TABLE, VIEW or OBJECT")
+	}
+	o := db.objects[objectKey(p.schema, p.name)]
+	var targets []permission
+	for _, column := range columns {
+		i := slices.IndexFunc(o.columns, func(c string) bool { return strings.EqualFold(c, strings.TrimSpace(column)) })
+		if i < 0 {
+			return nil, sqlError(207, "Invalid column name '%s'.", column)
+		}
+		t := p
+		t.column = o.columns[i]
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// permissionTable holds the rows of sys.database_permissions of a database,
+// or of sys.server_permissions.
+type permissionTable []permission
+
+// find returns the index of the permission on the securable of p, or -1.
+func (t permissionTable) find(p permission) int {
+	for i, q := range t {
+		if q.grantee == p.grantee && q.permission == p.permission && q.class == p.class &&
+			strings.EqualFold(q.schema, p.schema) && strings.EqualFold(q.name, p.name) && strings.EqualFold(q.column, p.column) {
+			return i
+		}
+	}
+	return -1
+}
+
+// grant records a GRANT, or a GRANT WITH GRANT OPTION if withOption is
+// set. A GRANT replaces a DENY and keeps an existing grant option.
+func (t *permissionTable) grant(p permission, withOption bool) {
+	p.state = "G"
+	if withOption {
+		p.state = "W"
+	}
+	if i := t.find(p); i >= 0 {
+		if (*t)[i].state == "D" || withOption {
+			(*t)[i].state = p.state
+		}
+		return
+	}
+	*t = append(*t, p)
+}
+
+// deny records a DENY, which replaces a grant and its grant option.
+func (t *permissionTable) deny(p permission) {
+	p.state = "D"
+	if i := t.find(p); i >= 0 {
+		(*t)[i].state = p.state
+		return
+	}
+	*t = append(*t, p)
+}
+
+// revokeGrantOption records a REVOKE GRANT OPTION FOR, which keeps the
+// permission.
+func (t *permissionTable) revokeGrantOption(p permission) {
+	if i := t.find(p); i >= 0 && (*t)[i].state == "W" {
+		(*t)[i].state = "G"
+	}
+}
+
+// revoke records a REVOKE, which removes a grant or a deny.
+func (t *permissionTable) revoke(p permission) {
+	if i := t.find(p); i >= 0 {
+		*t = append((*t)[:i], (*t)[i+1:]...)
+	}
+}
+
+func normalizePermission(permission string) (string, error) {
+	p := strings.ToUpper(strings.TrimSpace(permission))
+	if p == "" {
+		return "", fmt.Errorf("permission cannot be empty")
+	}
+	return p, nil
+}
+
+func (c *Client) ReadDatabasePermission(ctx context.Context, database string, id string) (mssql.DatabaseGrantPermission, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.DatabaseGrantPermission{}, err
+	}
+	defer unlock()
+
+	principal, perm, found := strings.Cut(id, "/")
+	if !found {
+		return mssql.DatabaseGrantPermission{}, sql.ErrNoRows
+	}
+	db, err := c.open(database)
+	if err != nil {
+		return mssql.DatabaseGrantPermission{}, err
+	}
+	return db.databasePermission(principal, perm)
+}
+
+func (db *database) databasePermission(principal string, perm string) (mssql.DatabaseGrantPermission, error) {
+	i := db.permissions.find(permission{grantee: key(principal), permission: strings.ToUpper(perm), class: classDatabase})
+	if i < 0 || db.permissions[i].state == "D" {
+		return mssql.DatabaseGrantPermission{}, sql.ErrNoRows
+	}
+	p := db.permissions[i]
+	name := db.principals[p.grantee].name
+	return mssql.DatabaseGrantPermission{
+		Id:         fmt.Sprintf("%s/%s", name, strings.ToLower(p.permission)),
+		Principal:  name,
+		Permission: p.permission,
+	}, nil
+}
+
+func (c *Client) GrantDatabasePermission(ctx context.Context, database string, principal string, permissionName string) (mssql.DatabaseGrantPermission, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.DatabaseGrantPermission{}, err
+	}
+	defer unlock()
+
+	perm, err := normalizePermission(permissionName)
+	if err != nil {
+		return mssql.DatabaseGrantPermission{}, err
+	}
+	db, err := c.open(database)
+	if err != nil {
+		return mssql.DatabaseGrantPermission{}, err
+	}
+	grantee, err := db.grantee(principal)
+	if err != nil {
+		return mssql.DatabaseGrantPermission{}, fmt.Errorf("failed to execute grant query: %v", err)
+	}
+	db.permissions.grant(permission{grantee: grantee, permission: perm, class: classDatabase}, false)
+	return db.databasePermission(grantee, perm)
+}
+
+func (c *Client) RevokeDatabasePermission(ctx context.Context, database string, principal string, permissionName string) error {
+	unlock, err := c.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	perm, err := normalizePermission(permissionName)
+	if err != nil {
+		return err
+	}
+	db, err := c.open(database)
+	if err != nil {
+		return err
+	}
+	grantee, err := db.grantee(principal)
+	if err != nil {
+		return fmt.Errorf("failed to execute revoke query: %v", err)
+	}
+	db.permissions.revoke(permission{grantee: grantee, permission: perm, class: classDatabase})
+	return nil
+}
+
+// ReadPermission finds a grant or deny like the real client: an object name
+// without a schema matches the object in any schema, and a grant with
+// columns reads every column the permission is on.
+func (c *Client) ReadPermission(ctx context.Context, grant mssql.GrantPermission) (mssql.GrantPermission, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return grant, err
+	}
+	defer unlock()
+
+	hasObjectType := strings.TrimSpace(grant.ObjectType) != ""
+	hasObjectName := strings.TrimSpace(grant.ObjectName) != ""
+	if hasObjectType != hasObjectName {
+		return grant, fmt.Errorf("object_type and object_name must be set together")
+	}
+	db, err := c.open(grant.Database)
+	if err != nil {
+		return grant, err
+	}
+
+	if len(grant.Columns) > 0 {
+		return db.columnPermission(grant)
+	}
+
+	var sc securableClass
+	if hasObjectType {
+		if sc, err = lookupSecurableClass(grant.ObjectType); err != nil {
+			return grant, err
+		}
+	}
+	schema, name := sc.splitName(grant.ObjectName)
+	for _, p := range db.permissions {
+		if p.grantee != key(grant.Principal) || !strings.EqualFold(p.permission, grant.Permission) || p.column != "" {
+			continue
+		}
+		switch {
+		case !hasObjectType && p.class == classDatabase:
+		case hasObjectType && p.class == sc.class && strings.EqualFold(p.name, name) &&
+			(schema == "" || strings.EqualFold(p.schema, schema)):
+			grant.ObjectName = p.name
+			if p.schema != "" {
+				grant.ObjectName = p.schema + "." + p.name
+			}
+		default:
+			continue
+		}
+		grant.Principal = db.principals[p.grantee].name
+		grant.Permission = p.permission
+		grant.State = "GRANT"
+		if p.state == "D" {
+			grant.State = "DENY"
+		}
+		grant.WithGrantOption = p.state == "W"
+		return grant, nil
+	}
+	return grant, sql.ErrNoRows
+}
+
+func (db *database) columnPermission(grant mssql.GrantPermission) (mssql.GrantPermission, error) {
+	schema, name := splitSchemaObject(grant.ObjectName)
+	var columns []string
+	for _, p := range db.permissions {
+		if p.grantee != key(grant.Principal) || !strings.EqualFold(p.permission, grant.Permission) || p.column == "" ||
+			!strings.EqualFold(p.name, name) || (schema != "" && !strings.EqualFold(p.schema, schema)) {
+			continue
+		}
+		if columns == nil {
+			grant.Principal = db.principals[p.grantee].name
+			grant.Permission = p.permission
+			grant.ObjectName = p.schema + "." + p.name
+			grant.State, grant.WithGrantOption = "GRANT", true
+		}
+		columns = append(columns, p.column)
+		if p.state == "D" {
+			grant.State = "DENY"
+		}
+		if p.state != "W" {
+			grant.WithGrantOption = false
+		}
+	}
+	if columns == nil {
+		return grant, sql.ErrNoRows
+	}
+	sort.Strings(columns)
+	grant.Columns = columns
+	return grant, nil
+}
+
+// GrantPermission grants or denies a permission without reading it back,
+// like the real client.
+func (c *Client) GrantPermission(ctx context.Context, grant mssql.GrantPermission) (mssql.GrantPermission, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return grant, err
+	}
+	defer unlock()
+
+	db, err := c.open(grant.Database)
+	if err != nil {
+		return grant, err
+	}
+	perm, err := normalizePermission(grant.Permission)
+	if err != nil {
+		return grant, err
+	}
+	grant.Permission = perm
+	grant.Principal = strings.TrimSpace(grant.Principal)
+	state := strings.ToUpper(strings.TrimSpace(grant.State))
+	if state == "" {
+		state = "GRANT"
+	}
+	if state != "GRANT" && state != "DENY" {
+		return grant, fmt.Errorf("state must be GRANT or DENY; got %q", grant.State)
+	}
+	if state == "DENY" && grant.WithGrantOption {
+		return grant, fmt.Errorf("with_grant_option only applies to GRANT")
+	}
+	grant.State = state
+
+	class, schema, name, err := db.securable(grant)
+	if err != nil {
+		return grant, fmt.Errorf("failed to execute grant: %v", err)
+	}
+	grantee, err := db.grantee(grant.Principal)
+	if err != nil {
+		return grant, fmt.Errorf("failed to execute grant: %v", err)
+	}
+	targets, err := db.targets(permission{grantee: grantee, permission: perm, class: class, schema: schema, name: name}, grant.Columns)
+	if err != nil {
+		return grant, fmt.Errorf("failed to execute grant: %v", err)
+	}
+	for _, t := range targets {
+		if state == "DENY" {
+			db.permissions.deny(t)
+		} else {
+			db.permissions.grant(t, grant.WithGrantOption)
+		}
+	}
+	return grant, nil
+}
+
+func (c *Client) RevokePermission(ctx context.Context, grant mssql.GrantPermission) error {
+	return c.revokePermission(grant, (*permissionTable).revoke)
+}
+
+func (c *Client) RevokeGrantOption(ctx context.Context, grant mssql.GrantPermission) error {
+	return c.revokePermission(grant, (*permissionTable).revokeGrantOption)
+}
+
+// revokePermission resolves the securable and grantee of a grant and runs
+// revoke for them.
+func (c *Client) revokePermission(grant mssql.GrantPermission, revoke func(t *permissionTable, p permission)) error {
+	unlock, err := c.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db, err := c.open(grant.Database)
+	if err != nil {
+		return err
+	}
+	perm, err := normalizePermission(grant.Permission)
+	if err != nil {
+		return err
+	}
+
+	class, schema, name, err := db.securable(grant)
+	if err != nil {
+		return err
+	}
+	grantee, err := db.grantee(grant.Principal)
+	if err != nil {
+		return err
+	}
+	targets, err := db.targets(permission{grantee: grantee, permission: perm, class: class, schema: schema, name: name}, grant.Columns)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		revoke(&db.permissions, t)
+	}
+	return nil
+}
+
+// Securable classes of sys.server_permissions.
+const (
+	classServer          = 100
+	classServerPrincipal = 101
+	classEndpoint        = 105
+)
+
+// endpoints are the system endpoints of a server.
+var endpoints = []string{"Dedicated Admin Connection", "TSQL Local Machine", "TSQL Named Pipes", "TSQL Default TCP", "TSQL Default VIA"}
+
+// serverSecurableClass returns the class of the object_type of a server
+// permission.
+func serverSecurableClass(objectType string) (int, error) {
+	switch strings.Join(strings.Fields(strings.ToUpper(objectType)), " ") {
+	case "":
+		return classServer, nil
+	case "LOGIN", "SERVER ROLE":
+		return classServerPrincipal, nil
+	case "ENDPOINT":
+		return classEndpoint, nil
+	default:
+		return 0, fmt.Errorf("object_type must be one of %s; got %q", strings.Join(mssql.ServerObjectTypes, ", "), objectType)
+	}
+}
+
+// serverSecurable resolves the securable of a server permission to its class
+// and name.
+func (c *Client) serverSecurable(perm mssql.ServerPermission) (int, string, error) {
+	if (strings.TrimSpace(perm.ObjectType) == "") != (strings.TrimSpace(perm.ObjectName) == "") {
+		return 0, "", fmt.Errorf("object_type and object_name must be set together")
+	}
+	class, err := serverSecurableClass(perm.ObjectType)
+	if err != nil || class == classServer {
+		return class, "", err
+	}
+	typ := strings.ToLower(strings.Join(strings.Fields(perm.ObjectType), " "))
+	notFound := sqlError(15151, "Cannot find the %s '%s', because it does not exist or you do not have permission.", typ, perm.ObjectName)
+	switch typ {
+	case "login":
+		if l := c.logins[key(perm.ObjectName)]; l != nil {
+			return class, l.name, nil
+		}
+	case "server role":
+		if r := c.serverRoles[key(perm.ObjectName)]; r != nil {
+			return class, r.name, nil
+		}
+	default:
+		if i := slices.IndexFunc(endpoints, func(e string) bool { return strings.EqualFold(e, perm.ObjectName) }); i >= 0 {
+			return class, endpoints[i], nil
+		}
+	}
+	return 0, "", notFound
+}
+
+// ReadServerPermission finds a grant or deny of a server permission like the
+// real client.
+func (c *Client) ReadServerPermission(ctx context.Context, perm mssql.ServerPermission) (mssql.ServerPermission, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return perm, err
+	}
+	defer unlock()
+
+	class, err := serverSecurableClass(perm.ObjectType)
+	if err != nil {
+		return perm, err
+	}
+	for _, p := range c.serverPermissions {
+		if p.grantee != key(perm.Principal) || !strings.EqualFold(p.permission, perm.Permission) || p.class != class ||
+			(class != classServer && !strings.EqualFold(p.name, perm.ObjectName)) {
+			continue
+		}
+		perm.Principal = c.serverPrincipalName(p.grantee)
+		perm.Permission = p.permission
+		if class != classServer {
+			perm.ObjectName = p.name
+		}
+		perm.State = "GRANT"
+		if p.state == "D" {
+			perm.State = "DENY"
+		}
+		perm.WithGrantOption = p.state == "W"
+		return perm, nil
+	}
+	return perm, sql.ErrNoRows
+}
+
+// GrantServerPermission grants or denies a server permission without reading
+// it back, like the real client.
+func (c *Client) GrantServerPermission(ctx context.Context, perm mssql.ServerPermission) (mssql.ServerPermission, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return perm, err
+	}
+	defer unlock()
+
+	state := strings.ToUpper(strings.TrimSpace(perm.State))
+	if state == "" {
+		state = "GRANT"
+	}
+	if state != "GRANT" && state != "DENY" {
+		return perm, fmt.Errorf("state must be GRANT or DENY; got %q", perm.State)
+	}
+	if state == "DENY" && perm.WithGrantOption {
+		return perm, fmt.Errorf("with_grant_option only applies to GRANT")
+	}
+	perm.State = state
+
+	p, err := c.serverPermission(&perm)
+	if err != nil {
+		return perm, fmt.Errorf("failed to execute grant: %v", err)
+	}
+	if state == "DENY" {
+		c.serverPermissions.deny(p)
+	} else {
+		c.serverPermissions.grant(p, perm.WithGrantOption)
+	}
+	return perm, nil
+}
+
+func (c *Client) RevokeServerPermission(ctx context.Context, perm mssql.ServerPermission) error {
+	return c.revokeServerPermission(perm, (*permissionTable).revoke)
+}
+
+func (c *Client) RevokeServerGrantOption(ctx context.Context, perm mssql.ServerPermission) error {
+	return c.revokeServerPermission(perm, (*permissionTable).revokeGrantOption)
+}
+
+func (c *Client) revokeServerPermission(perm mssql.ServerPermission, revoke func(t *permissionTable, p permission)) error {
+	unlock, err := c.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	p, err := c.serverPermission(&perm)
+	if err != nil {
+		return err
+	}
+	revoke(&c.serverPermissions, p)
+	return nil
+}
+
+// serverPermission resolves the grantee and securable of a server permission
+// to its row of sys.server_permissions, normalizing the permission.
+func (c *Client) serverPermission(perm *mssql.ServerPermission) (permission, error) {
+	name, err := normalizePermission(perm.Permission)
+	if err != nil {
+		return permission{}, err
+	}
+	perm.Permission = name
+	perm.Principal = strings.TrimSpace(perm.Principal)
+	class, securable, err := c.serverSecurable(*perm)
+	if err != nil {
+		return permission{}, err
+	}
+	if c.serverPrincipalName(perm.Principal) == "" {
+		return permission{}, sqlError(15151, "Cannot find the login '%s', because it does not exist or you do not have permission.", perm.Principal)
+	}
+	return permission{grantee: key(perm.Principal), permission: name, class: class, name: securable}, nil
+}
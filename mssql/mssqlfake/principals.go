@@ -0,0 +1,634 @@
+package mssqlfake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/vsabella/terraform-provider-mssql/mssql"
+)
+
+// principal is a row of sys.database_principals.
+type principal struct {
+	id   int64
+	name string
+	// typ is S for SQL users, E for external users and R for roles.
+	typ           string
+	sid           string
+	defaultSchema string
+	// members holds the keys of the members of a role.
+	members map[string]bool
+}
+
+// login is a row of sys.server_principals of type S.
+type login struct {
+	id              int64
+	name            string
+	sid             string
+	defaultDatabase string
+	defaultLanguage string
+	disabled        bool
+}
+
+type serverRole struct {
+	id      int64
+	name    string
+	members map[string]bool
+}
+
+var loginSidRe = regexp.MustCompile(`^0x[0-9A-Fa-f]+$`)
+
+func (db *database) user(p *principal, logins map[string]*login) mssql.User {
+	user := mssql.User{
+		Id:            p.name,
+		Username:      p.name,
+		Type:          p.typ,
+		Sid:           userSid(p.sid),
+		External:      p.typ == "E" || p.typ == "X",
+		DefaultSchema: p.defaultSchema,
+	}
+	if p.sid != "" {
+		for _, l := range logins {
+			if strings.EqualFold(l.sid, p.sid) {
+				user.LoginName = l.name
+			}
+		}
+	}
+	return user
+}
+
+// userSid formats a SID like CONVERT(varchar, sid, 1), which GetUser uses.
+func userSid(sid string) string {
+	if sid == "" {
+		return ""
+	}
+	return "0x" + strings.ToUpper(strings.TrimPrefix(sid, "0x"))
+}
+
+// drop removes a principal with its role memberships and permissions.
+func (db *database) drop(p *principal) {
+	k := key(p.name)
+	delete(db.principals, k)
+	for _, other := range db.principals {
+		delete(other.members, k)
+	}
+	permissions := db.permissions[:0]
+	for _, perm := range db.permissions {
+		if perm.grantee != k {
+			permissions = append(permissions, perm)
+		}
+	}
+	db.permissions = permissions
+}
+
+func (c *Client) GetUser(ctx context.Context, database string, username string) (mssql.User, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.User{}, err
+	}
+	defer unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return mssql.User{}, err
+	}
+	p := db.principals[key(username)]
+	if p == nil {
+		return mssql.User{}, sql.ErrNoRows
+	}
+	return db.user(p, c.logins), nil
+}
+
+func (c *Client) CreateUser(ctx context.Context, database string, create mssql.CreateUser) (mssql.User, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.User{}, err
+	}
+	defer unlock()
+
+	// Checked by the client before it runs CREATE USER.
+	switch {
+	case create.External && create.Password != "":
+		return mssql.User{}, fmt.Errorf("invalid user %s, external users may not have passwords", create.Username)
+	case create.External && create.LoginName != "":
+		return mssql.User{}, fmt.Errorf("invalid user %s, external users may not have login_name", create.Username)
+	case create.External && create.Sid != "":
+		return mssql.User{}, fmt.Errorf("invalid user %s, external users must not have a SID", create.Username)
+	case create.DefaultSchema == "":
+		return mssql.User{}, fmt.Errorf("invalid user %s, default schema must be specified", create.Username)
+	}
+
+	db, err := c.open(database)
+	if err != nil {
+		return mssql.User{}, err
+	}
+	if db.principals[key(create.Username)] != nil {
+		return mssql.User{}, sqlError(15023, "User, group, or role '%s' already exists in the current database.", create.Username)
+	}
+
+	p := &principal{name: create.Username, typ: "S", defaultSchema: create.DefaultSchema}
+	switch {
+	case create.External:
+		p.typ = "E"
+		p.sid = newSid()
+	case create.LoginName == "" && create.Password != "":
+		if !db.contained && !c.info.IsAzureSQLDatabase() {
+			return mssql.User{}, sqlError(33233, "You can only create a user with a password in a contained database.")
+		}
+		p.sid = strings.ToLower(create.Sid)
+		if p.sid == "" {
+			p.sid = newSid()
+		}
+	default:
+		// Without FOR LOGIN, a user maps to the login of the same name.
+		name := create.LoginName
+		if name == "" {
+			name = create.Username
+		}
+		l := c.logins[key(name)]
+		if l == nil {
+			return mssql.User{}, sqlError(15007, "'%s' is not a valid login or you do not have permission.", name)
+		}
+		for _, other := range db.principals {
+			if strings.EqualFold(other.sid, l.sid) {
+				return mssql.User{}, sqlError(15063, "The login already has an account under a different user name.")
+			}
+		}
+		p.sid = l.sid
+	}
+
+	db.addPrincipal(p)
+	return db.user(p, c.logins), nil
+}
+
+func (c *Client) UpdateUser(ctx context.Context, database string, update mssql.UpdateUser) (mssql.User, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.User{}, err
+	}
+	defer unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return mssql.User{}, err
+	}
+	p := db.principals[key(update.Id)]
+	if update.Password != "" || update.DefaultSchema != "" {
+		if p == nil || p.typ == "R" {
+			return mssql.User{}, sqlError(15151, "Cannot alter the user '%s', because it does not exist or you do not have permission.", update.Id)
+		}
+		if update.DefaultSchema != "" {
+			p.defaultSchema = update.DefaultSchema
+		}
+	}
+	if p == nil {
+		return mssql.User{}, sql.ErrNoRows
+	}
+	return db.user(p, c.logins), nil
+}
+
+// DeleteUser drops the user if it exists, like the real client.
+func (c *Client) DeleteUser(ctx context.Context, database string, username string) error {
+	unlock, err := c.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return err
+	}
+	if p := db.principals[key(username)]; p != nil && p.typ != "R" {
+		db.drop(p)
+	}
+	return nil
+}
+
+func encodeRoleMembershipId(role string, member string) string {
+	return fmt.Sprintf("%s/%s", url.QueryEscape(role), url.QueryEscape(member))
+}
+
+func (c *Client) ReadRoleMembership(ctx context.Context, database string, id string) (mssql.RoleMembership, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.RoleMembership{}, err
+	}
+	defer unlock()
+
+	re, me, found := strings.Cut(id, "/")
+	if !found {
+		return mssql.RoleMembership{}, sql.ErrNoRows
+	}
+	role, err := url.QueryUnescape(re)
+	if err != nil {
+		return mssql.RoleMembership{}, err
+	}
+	member, err := url.QueryUnescape(me)
+	if err != nil {
+		return mssql.RoleMembership{}, err
+	}
+
+	db, err := c.open(database)
+	if err != nil {
+		return mssql.RoleMembership{}, err
+	}
+	return db.roleMembership(role, member)
+}
+
+func (db *database) roleMembership(role string, member string) (mssql.RoleMembership, error) {
+	r, m := db.principals[key(role)], db.principals[key(member)]
+	if r == nil || r.typ != "R" || m == nil || !r.members[key(member)] {
+		return mssql.RoleMembership{}, sql.ErrNoRows
+	}
+	return mssql.RoleMembership{Id: encodeRoleMembershipId(r.name, m.name), Role: r.name, Member: m.name}, nil
+}
+
+func (c *Client) AssignRole(ctx context.Context, database string, role string, principal string) (mssql.RoleMembership, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.RoleMembership{}, err
+	}
+	defer unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return mssql.RoleMembership{}, err
+	}
+	r := db.principals[key(role)]
+	if r == nil || r.typ != "R" {
+		return mssql.RoleMembership{}, sqlError(15151, "Cannot alter the role '%s', because it does not exist or you do not have permission.", role)
+	}
+	if db.principals[key(principal)] == nil {
+		return mssql.RoleMembership{}, sqlError(15151, "Cannot add the principal '%s', because it does not exist or you do not have permission.", principal)
+	}
+	for _, special := range []string{r.name, principal} {
+		if strings.EqualFold(special, "public") || strings.EqualFold(special, "dbo") {
+			return mssql.RoleMembership{}, sqlError(15405, "Cannot use the special principal '%s'.", special)
+		}
+	}
+	r.members[key(principal)] = true
+	return db.roleMembership(role, principal)
+}
+
+func (c *Client) UnassignRole(ctx context.Context, database string, role string, principal string) error {
+	unlock, err := c.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return err
+	}
+	r := db.principals[key(role)]
+	if r == nil || r.typ != "R" {
+		return sqlError(15151, "Cannot alter the role '%s', because it does not exist or you do not have permission.", role)
+	}
+	if db.principals[key(principal)] == nil {
+		return sqlError(15151, "Cannot drop the principal '%s', because it does not exist or you do not have permission.", principal)
+	}
+	delete(r.members, key(principal))
+	return nil
+}
+
+func (c *Client) GetRole(ctx context.Context, database string, name string) (mssql.Role, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.Role{}, err
+	}
+	defer unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return mssql.Role{}, err
+	}
+	return db.role(name)
+}
+
+func (db *database) role(name string) (mssql.Role, error) {
+	role := mssql.Role{Id: name, Name: name}
+	p := db.principals[key(name)]
+	if p == nil || p.typ != "R" {
+		return role, sql.ErrNoRows
+	}
+	role.Id = p.name
+	return role, nil
+}
+
+// CreateRole creates a role and reads it back. Like the real client, it
+// ignores the CREATE ROLE error, so a name taken by a user is reported as
+// sql.ErrNoRows.
+func (c *Client) CreateRole(ctx context.Context, database string, name string) (mssql.Role, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.Role{}, err
+	}
+	defer unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return mssql.Role{}, err
+	}
+	if db.principals[key(name)] == nil {
+		db.addPrincipal(&principal{name: name, typ: "R", members: map[string]bool{}})
+	}
+	return db.role(name)
+}
+
+func (c *Client) UpdateRole(ctx context.Context, database string, role mssql.Role) (mssql.Role, error) {
+	return c.GetRole(ctx, database, role.Id)
+}
+
+func (c *Client) DeleteRole(ctx context.Context, database string, name string) error {
+	unlock, err := c.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return err
+	}
+	p := db.principals[key(name)]
+	if p == nil || p.typ != "R" {
+		return sqlError(15151, "Cannot drop the role '%s', because it does not exist or you do not have permission.", name)
+	}
+	if len(p.members) > 0 {
+		return sqlError(15144, "The role has members. It must be empty before it can be dropped.")
+	}
+	db.drop(p)
+	return nil
+}
+
+func (c *Client) GetDatabasePrincipalId(ctx context.Context, database string, name string) (int64, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return 0, err
+	}
+	p := db.principals[key(name)]
+	if p == nil {
+		return 0, sql.ErrNoRows
+	}
+	return p.id, nil
+}
+
+func (c *Client) GetDatabasePrincipalName(ctx context.Context, database string, id int64) (string, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	db, err := c.open(database)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range db.principals {
+		if p.id == id {
+			return p.name, nil
+		}
+	}
+	return "", sql.ErrNoRows
+}
+
+func (c *Client) ReadServerRoleMembership(ctx context.Context, role string, principal string) (mssql.RoleMembership, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.RoleMembership{}, err
+	}
+	defer unlock()
+	return c.serverRoleMembership(role, principal)
+}
+
+func (c *Client) serverRoleMembership(role string, principal string) (mssql.RoleMembership, error) {
+	r, m := c.serverRoles[key(role)], c.serverPrincipalName(principal)
+	if r == nil || m == "" || !r.members[key(principal)] {
+		return mssql.RoleMembership{}, sql.ErrNoRows
+	}
+	return mssql.RoleMembership{Id: encodeRoleMembershipId(r.name, m), Role: r.name, Member: m}, nil
+}
+
+// serverPrincipalName returns the name of a login or server role, or "".
+func (c *Client) serverPrincipalName(name string) string {
+	if l := c.logins[key(name)]; l != nil {
+		return l.name
+	}
+	if r := c.serverRoles[key(name)]; r != nil {
+		return r.name
+	}
+	return ""
+}
+
+// addLogin adds a login with the next free principal_id.
+func (c *Client) addLogin(l *login) {
+	c.nextServerPrincipalId++
+	l.id = c.nextServerPrincipalId
+	c.logins[key(l.name)] = l
+}
+
+func (c *Client) GetServerPrincipalId(ctx context.Context, name string) (int64, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	if l := c.logins[key(name)]; l != nil {
+		return l.id, nil
+	}
+	if r := c.serverRoles[key(name)]; r != nil {
+		return r.id, nil
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (c *Client) GetServerPrincipalName(ctx context.Context, id int64) (string, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	for _, l := range c.logins {
+		if l.id == id {
+			return l.name, nil
+		}
+	}
+	for _, r := range c.serverRoles {
+		if r.id == id {
+			return r.name, nil
+		}
+	}
+	return "", sql.ErrNoRows
+}
+
+func (c *Client) AssignServerRole(ctx context.Context, role string, principal string) (mssql.RoleMembership, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.RoleMembership{}, err
+	}
+	defer unlock()
+
+	r := c.serverRoles[key(role)]
+	if r == nil {
+		return mssql.RoleMembership{}, sqlError(15151, "Cannot alter the server role '%s', because it does not exist or you do not have permission.", role)
+	}
+	if c.serverPrincipalName(principal) == "" {
+		return mssql.RoleMembership{}, sqlError(15151, "Cannot add the principal '%s', because it does not exist or you do not have permission.", principal)
+	}
+	r.members[key(principal)] = true
+	return c.serverRoleMembership(role, principal)
+}
+
+func (c *Client) UnassignServerRole(ctx context.Context, role string, principal string) error {
+	unlock, err := c.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	r := c.serverRoles[key(role)]
+	if r == nil {
+		return sqlError(15151, "Cannot alter the server role '%s', because it does not exist or you do not have permission.", role)
+	}
+	if c.serverPrincipalName(principal) == "" {
+		return sqlError(15151, "Cannot drop the principal '%s', because it does not exist or you do not have permission.", principal)
+	}
+	delete(r.members, key(principal))
+	return nil
+}
+
+func (c *Client) GetLogin(ctx context.Context, name string) (mssql.Login, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.Login{}, err
+	}
+	defer unlock()
+	return c.login(name)
+}
+
+func (c *Client) login(name string) (mssql.Login, error) {
+	l := c.logins[key(name)]
+	if l == nil {
+		return mssql.Login{}, sql.ErrNoRows
+	}
+	return mssql.Login{
+		Name:            l.name,
+		DefaultDatabase: l.defaultDatabase,
+		DefaultLanguage: l.defaultLanguage,
+		IsDisabled:      l.disabled,
+		Sid:             l.sid,
+	}, nil
+}
+
+func (c *Client) CreateLogin(ctx context.Context, create mssql.CreateLogin) (mssql.Login, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.Login{}, err
+	}
+	defer unlock()
+
+	// Checked by the client before it runs CREATE LOGIN.
+	if create.Password == "" {
+		return mssql.Login{}, fmt.Errorf("invalid login password: must not be empty")
+	}
+	sid := strings.ToLower(strings.TrimSpace(create.Sid))
+	if sid != "" && (!loginSidRe.MatchString(sid) || len(sid)%2 != 0) {
+		return mssql.Login{}, fmt.Errorf("login sid must be a hex string like 0x010500000000000515000000...")
+	}
+
+	if c.serverPrincipalName(create.Name) != "" {
+		err := sqlError(15025, "The server principal '%s' already exists.", create.Name)
+		return mssql.Login{}, fmt.Errorf("failed to create login: %v", err)
+	}
+	if sid == "" {
+		sid = newSid()
+	}
+	for _, other := range c.logins {
+		if other.sid == sid {
+			err := sqlError(15433, "Supplied parameter sid is in use.")
+			return mssql.Login{}, fmt.Errorf("failed to create login: %v", err)
+		}
+	}
+
+	l := &login{name: create.Name, sid: sid, defaultDatabase: "master", defaultLanguage: "us_english"}
+	if create.DefaultDatabase != "" {
+		db := c.databases[key(create.DefaultDatabase)]
+		if db == nil {
+			err := sqlError(15010, "The database '%s' does not exist. Supply a valid database name. To see available databases, use sys.databases.", create.DefaultDatabase)
+			return mssql.Login{}, fmt.Errorf("failed to create login: %v", err)
+		}
+		l.defaultDatabase = db.name
+	}
+	if create.DefaultLanguage != "" {
+		l.defaultLanguage = create.DefaultLanguage
+	}
+
+	c.addLogin(l)
+	c.serverRoles["public"].members[key(l.name)] = true
+	return c.login(l.name)
+}
+
+func (c *Client) UpdateLogin(ctx context.Context, update mssql.UpdateLogin) (mssql.Login, error) {
+	unlock, err := c.begin()
+	if err != nil {
+		return mssql.Login{}, err
+	}
+	defer unlock()
+
+	if update.Password != "" || update.DefaultDatabase != "" || update.DefaultLanguage != "" {
+		l := c.logins[key(update.Name)]
+		if l == nil {
+			err := sqlError(15151, "Cannot alter the login '%s', because it does not exist or you do not have permission.", update.Name)
+			return mssql.Login{}, fmt.Errorf("failed to update login: %v", err)
+		}
+		if update.DefaultDatabase != "" {
+			db := c.databases[key(update.DefaultDatabase)]
+			if db == nil {
+				err := sqlError(15010, "The database '%s' does not exist. Supply a valid database name. To see available databases, use sys.databases.", update.DefaultDatabase)
+				return mssql.Login{}, fmt.Errorf("failed to update login: %v", err)
+			}
+			l.defaultDatabase = db.name
+		}
+		if update.DefaultLanguage != "" {
+			l.defaultLanguage = update.DefaultLanguage
+		}
+	}
+	return c.login(update.Name)
+}
+
+// DeleteLogin drops the login if it exists, like the real client. Users
+// mapped to it are left orphaned, as on SQL Server.
+func (c *Client) DeleteLogin(ctx context.Context, name string) error {
+	unlock, err := c.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if c.logins[key(name)] == nil {
+		return nil
+	}
+	delete(c.logins, key(name))
+	for _, r := range c.serverRoles {
+		delete(r.members, key(name))
+	}
+	// Permissions of the login and on it go with it.
+	c.serverPermissions = slices.DeleteFunc(c.serverPermissions, func(p permission) bool {
+		return p.grantee == key(name) || p.class == classServerPrincipal && strings.EqualFold(p.name, name)
+	})
+	return nil
+}
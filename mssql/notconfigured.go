@@ -0,0 +1,30 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+)
+
+// ErrNotConfigured is returned by a client whose connection settings are not
+// known yet, e.g. because the provider configuration references a server that
+// is created in the same apply.
+var ErrNotConfigured = errors.New("the mssql provider is not configured yet: its configuration depends on values that are only known after apply")
+
+// notConfigured is a connector whose connections all fail with
+// ErrNotConfigured.
+type notConfigured struct{}
+
+func (notConfigured) Connect(context.Context) (driver.Conn, error) { return nil, ErrNotConfigured }
+func (notConfigured) Open(string) (driver.Conn, error)             { return nil, ErrNotConfigured }
+func (c notConfigured) Driver() driver.Driver                      { return c }
+
+// NewNotConfiguredClient returns a client for a provider whose connection
+// settings are unknown. Every call that needs the server fails with
+// ErrNotConfigured.
+func NewNotConfiguredClient() SqlClient {
+	open := func(string) (*sql.DB, error) { return sql.OpenDB(notConfigured{}), nil }
+	db, _ := open("")
+	return &client{conn: db, open: open}
+}
@@ -0,0 +1,22 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_NewNotConfiguredClient(t *testing.T) {
+	c := NewNotConfiguredClient()
+	ctx := context.Background()
+
+	if _, err := c.GetRole(ctx, "master", "r"); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("GetRole() err = %v, want ErrNotConfigured", err)
+	}
+	if _, err := c.GetServerPrincipalId(ctx, "app"); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("GetServerPrincipalId() err = %v, want ErrNotConfigured", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() err = %v", err)
+	}
+}
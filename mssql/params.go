@@ -0,0 +1,77 @@
+package mssql
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// keySynonyms maps go-mssqldb connection string synonyms to the canonical
+// keys, so that Parameters and ConnectionString can be compared.
+var keySynonyms = map[string]string{
+	"application name": msdsn.AppName,
+	"data source":      msdsn.Server,
+	"address":          msdsn.Server,
+	"network address":  msdsn.Server,
+	"addr":             msdsn.Server,
+	"user":             msdsn.UserID,
+	"uid":              msdsn.UserID,
+	"pwd":              msdsn.Password,
+	"initial catalog":  msdsn.Database,
+}
+
+// NormalizeParameterKey returns the canonical, lower-case form of a
+// connection string key.
+func NormalizeParameterKey(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if canonical, ok := keySynonyms[key]; ok {
+		return canonical
+	}
+	return key
+}
+
+// ParseConnectionString splits a go-mssqldb connection string, in ADO,
+// ODBC or sqlserver:// URL form, into its parameters keyed by canonical key.
+func ParseConnectionString(s string) (map[string]string, error) {
+	// Parse fails on combinations that may be completed by other provider
+	// attributes, e.g. a read-only intent without a database. Only syntax
+	// errors, which leave no parameters, matter here; the final connection
+	// string is validated when the connector is built.
+	p, err := msdsn.Parse(s)
+	if p.Parameters == nil {
+		// url.Error repeats the URL, which may include a password.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("invalid connection string: %v", err)
+	}
+
+	params := make(map[string]string, len(p.Parameters))
+	for k, v := range p.Parameters {
+		params[NormalizeParameterKey(k)] = v
+	}
+	return params, nil
+}
+
+// mergedParams returns ConnectionString merged with Parameters, which take
+// precedence.
+func (cfg Config) mergedParams() (map[string]string, error) {
+	params := map[string]string{}
+	if cfg.ConnectionString != "" {
+		parsed, err := ParseConnectionString(cfg.ConnectionString)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range parsed {
+			params[k] = v
+		}
+	}
+	for k, v := range cfg.Parameters {
+		params[NormalizeParameterKey(k)] = v
+	}
+	return params, nil
+}
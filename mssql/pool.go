@@ -0,0 +1,216 @@
+package mssql
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Pool configures the database/sql connection pools. The client keeps one
+// pool for the provider's database plus one per additional database it
+// touches; each pool gets the same limits.
+type Pool struct {
+	// MaxOpenConns limits open connections per pool; 0 means unlimited.
+	MaxOpenConns int
+	// MaxIdleConns limits idle connections kept per pool; 0 uses the
+	// database/sql default of 2.
+	MaxIdleConns int
+	// ConnMaxLifetime closes connections older than this; 0 keeps them forever.
+	ConnMaxLifetime time.Duration
+	// MaxDatabasePools caps the cached per-database pools. The least recently
+	// used pool is closed when the cap is exceeded; 0 means unlimited.
+	MaxDatabasePools int
+}
+
+// DefaultPool is used when Config.Pool is nil.
+var DefaultPool = Pool{
+	MaxDatabasePools: 32,
+}
+
+// Validate checks that the pool settings are usable.
+func (p Pool) Validate() error {
+	if p.MaxOpenConns < 0 || p.MaxIdleConns < 0 || p.MaxDatabasePools < 0 {
+		return errors.New("pool max_open_conns, max_idle_conns and max_database_pools must not be negative")
+	}
+	if p.ConnMaxLifetime < 0 {
+		return errors.New("pool conn_max_lifetime must not be negative")
+	}
+	if p.MaxOpenConns > 0 && p.MaxIdleConns > p.MaxOpenConns {
+		return fmt.Errorf("pool max_idle_conns (%d) must not exceed max_open_conns (%d)", p.MaxIdleConns, p.MaxOpenConns)
+	}
+	return nil
+}
+
+func (p Pool) apply(db *sql.DB) {
+	db.SetMaxOpenConns(p.MaxOpenConns)
+	if p.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(p.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(p.ConnMaxLifetime)
+}
+
+// dbPool is a cached per-database pool. refs counts callers currently using
+// it, so that an evicted pool is only closed once the last of them is done.
+type dbPool struct {
+	database string
+	db       *sql.DB
+	refs     int
+	elem     *list.Element
+	evicted  bool
+}
+
+// openDatabase opens and pings a new pool for database.
+func (m *client) openDatabase(database string) (*sql.DB, error) {
+	connector, err := m.newConnector(database)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(connector)
+	m.pool.apply(db)
+
+	if err := m.withRetry(context.Background(), idempotent, db.Ping); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database %s: %v", database, err)
+	}
+	return db, nil
+}
+
+// getConnForDatabase returns a pooled connection for a database, and a
+// release func that must be called once the caller is done with it.
+//
+// Pools are cached per database and evicted least recently used first once
+// Pool.MaxDatabasePools is exceeded.
+func (m *client) getConnForDatabase(database string) (*sql.DB, func(), error) {
+	if database == "" || database == m.database {
+		return m.conn, func() {}, nil
+	}
+
+	if p := m.acquirePool(database); p != nil {
+		return p.db, func() { m.releasePool(p) }, nil
+	}
+
+	// Open outside the lock to avoid blocking concurrent callers.
+	open := m.open
+	if open == nil {
+		open = m.openDatabase
+	}
+	db, err := open(database)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database %s: %v", database, err)
+	}
+
+	m.connMu.Lock()
+	// Double-check to avoid duplicating pools in races.
+	if p := m.connByDatabase[database]; p != nil {
+		p.refs++
+		m.lru.MoveToFront(p.elem)
+		m.connMu.Unlock()
+		db.Close()
+		return p.db, func() { m.releasePool(p) }, nil
+	}
+
+	p := &dbPool{database: database, db: db, refs: 1}
+	if m.connByDatabase == nil {
+		m.connByDatabase = map[string]*dbPool{}
+		m.lru = list.New()
+	}
+	p.elem = m.lru.PushFront(p)
+	m.connByDatabase[database] = p
+	idle := m.evictLocked()
+	m.connMu.Unlock()
+
+	closePools(idle)
+	return db, func() { m.releasePool(p) }, nil
+}
+
+func (m *client) acquirePool(database string) *dbPool {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+
+	p := m.connByDatabase[database]
+	if p == nil {
+		return nil
+	}
+	p.refs++
+	m.lru.MoveToFront(p.elem)
+	return p
+}
+
+func (m *client) releasePool(p *dbPool) {
+	m.connMu.Lock()
+	p.refs--
+	closeNow := p.evicted && p.refs == 0
+	m.connMu.Unlock()
+
+	if closeNow {
+		closePools([]*dbPool{p})
+	}
+}
+
+// evictLocked drops least recently used pools beyond MaxDatabasePools and
+// returns the evicted pools that are not in use; pools still in use are
+// closed by their last release. connMu must be held.
+func (m *client) evictLocked() []*dbPool {
+	limit := m.pool.MaxDatabasePools
+	if limit <= 0 {
+		return nil
+	}
+
+	var idle []*dbPool
+	for m.lru.Len() > limit {
+		p := m.lru.Remove(m.lru.Back()).(*dbPool)
+		delete(m.connByDatabase, p.database)
+		p.evicted = true
+		if p.refs == 0 {
+			idle = append(idle, p)
+		}
+	}
+	return idle
+}
+
+func closePools(pools []*dbPool) {
+	for _, p := range pools {
+		p.db.Close()
+	}
+}
+
+// Close closes every connection pool. Pools still in use are closed when
+// they are released.
+func (m *client) Close() error {
+	m.connMu.Lock()
+	var idle []*dbPool
+	for _, p := range m.connByDatabase {
+		p.evicted = true
+		if p.refs == 0 {
+			idle = append(idle, p)
+		}
+	}
+	m.connByDatabase = nil
+	m.lru = nil
+	m.connMu.Unlock()
+
+	closePools(idle)
+	var err error
+	if m.conn != nil {
+		err = m.conn.Close()
+	}
+	if m.dialer != nil {
+		if dErr := m.dialer.Close(); err == nil {
+			err = dErr
+		}
+	}
+	if m.statements != nil {
+		if sErr := m.statements.Close(); err == nil {
+			err = sErr
+		}
+	}
+	if m.recorder != nil {
+		if rErr := m.recorder.Close(); err == nil {
+			err = rErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,134 @@
+package mssql
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// newPoolTestClient returns a client whose per-database pools are sqlmock
+// databases, recorded by name in dbs.
+func newPoolTestClient(t *testing.T, maxPools int) (*client, map[string]*sql.DB) {
+	t.Helper()
+
+	dbs := map[string]*sql.DB{}
+	c := &client{
+		database: "master",
+		pool:     Pool{MaxDatabasePools: maxPools},
+	}
+	c.open = func(database string) (*sql.DB, error) {
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New() err = %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		dbs[database] = db
+		return db, nil
+	}
+	return c, dbs
+}
+
+func isClosed(db *sql.DB) bool {
+	return db.Ping() != nil
+}
+
+func Test_getConnForDatabase_ReusesPool(t *testing.T) {
+	c, dbs := newPoolTestClient(t, 2)
+
+	first, release, err := c.getConnForDatabase("app")
+	if err != nil {
+		t.Fatalf("getConnForDatabase() err = %v", err)
+	}
+	release()
+
+	second, release, err := c.getConnForDatabase("app")
+	if err != nil {
+		t.Fatalf("getConnForDatabase() err = %v", err)
+	}
+	release()
+
+	if first != second || len(dbs) != 1 {
+		t.Fatalf("expected the cached pool to be reused, opened %d pools", len(dbs))
+	}
+}
+
+func Test_getConnForDatabase_EvictsLeastRecentlyUsed(t *testing.T) {
+	c, dbs := newPoolTestClient(t, 2)
+
+	for _, name := range []string{"one", "two", "one", "three"} {
+		_, release, err := c.getConnForDatabase(name)
+		if err != nil {
+			t.Fatalf("getConnForDatabase(%s) err = %v", name, err)
+		}
+		release()
+	}
+
+	// "two" was used least recently when "three" pushed the cache over its cap.
+	if !isClosed(dbs["two"]) {
+		t.Fatalf("expected pool for two to be closed")
+	}
+	if isClosed(dbs["one"]) || isClosed(dbs["three"]) {
+		t.Fatalf("expected pools for one and three to stay open")
+	}
+	if len(c.connByDatabase) != 2 {
+		t.Fatalf("cached pools = %d, want 2", len(c.connByDatabase))
+	}
+}
+
+func Test_getConnForDatabase_EvictedPoolClosedOnRelease(t *testing.T) {
+	c, dbs := newPoolTestClient(t, 1)
+
+	held, releaseHeld, err := c.getConnForDatabase("busy")
+	if err != nil {
+		t.Fatalf("getConnForDatabase() err = %v", err)
+	}
+
+	_, release, err := c.getConnForDatabase("other")
+	if err != nil {
+		t.Fatalf("getConnForDatabase() err = %v", err)
+	}
+	release()
+
+	if isClosed(held) {
+		t.Fatalf("evicted pool was closed while still in use")
+	}
+	releaseHeld()
+	if !isClosed(dbs["busy"]) {
+		t.Fatalf("evicted pool was not closed after its last release")
+	}
+}
+
+func Test_Close_ClosesAllPools(t *testing.T) {
+	c, dbs := newPoolTestClient(t, 0)
+
+	for _, name := range []string{"one", "two"} {
+		_, release, err := c.getConnForDatabase(name)
+		if err != nil {
+			t.Fatalf("getConnForDatabase(%s) err = %v", name, err)
+		}
+		release()
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() err = %v", err)
+	}
+	for name, db := range dbs {
+		if !isClosed(db) {
+			t.Fatalf("pool for %s was not closed", name)
+		}
+	}
+}
+
+func Test_Pool_Validate(t *testing.T) {
+	if err := DefaultPool.Validate(); err != nil {
+		t.Fatalf("DefaultPool.Validate() = %v", err)
+	}
+	if err := (Pool{MaxOpenConns: 2, MaxIdleConns: 5}).Validate(); err == nil {
+		t.Fatalf("expected error for max_idle_conns > max_open_conns")
+	}
+	if err := (Pool{ConnMaxLifetime: -time.Second}).Validate(); err == nil {
+		t.Fatalf("expected error for negative conn_max_lifetime")
+	}
+}
@@ -0,0 +1,225 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	mssqldb "github.com/microsoft/go-mssqldb"
+)
+
+// Retry configures how the client retries statements that fail with
+// transient errors.
+type Retry struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. It doubles with
+	// every further attempt, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetry is used when Config.Retry is nil.
+var DefaultRetry = Retry{
+	MaxAttempts:    4,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+}
+
+// Validate checks that the retry settings are usable.
+func (r Retry) Validate() error {
+	if r.MaxAttempts < 1 {
+		return fmt.Errorf("retry max_attempts must be at least 1; got %d", r.MaxAttempts)
+	}
+	if r.InitialBackoff < 0 || r.MaxBackoff < 0 {
+		return errors.New("retry backoff durations must not be negative")
+	}
+	if r.MaxBackoff < r.InitialBackoff {
+		return fmt.Errorf("retry max_backoff (%s) must not be less than initial_backoff (%s)", r.MaxBackoff, r.InitialBackoff)
+	}
+	return nil
+}
+
+// backoff returns the delay after the given failed attempt (starting at 1):
+// exponential growth capped at MaxBackoff, with "equal jitter" so that
+// concurrent resources retrying the same failover do not stampede.
+func (r Retry) backoff(attempt int) time.Duration {
+	d := r.InitialBackoff
+	for i := 1; i < attempt && d < r.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.MaxBackoff {
+		d = r.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// Statement idempotency, passed to client.exec. Non-idempotent statements
+// (CREATE, DROP, user scripts) are only retried when the server guarantees
+// they did not run; idempotent ones are also retried after a lost connection.
+const (
+	idempotent    = true
+	nonIdempotent = false
+)
+
+type retryClass int
+
+const (
+	notRetryable retryClass = iota
+	// retrySafe errors guarantee the statement was not executed (or was
+	// rolled back), so any statement may be retried.
+	retrySafe
+	// retryAmbiguous errors leave the outcome unknown, e.g. the connection
+	// dropped after the statement was sent.
+	retryAmbiguous
+)
+
+// Transient SQL Server and Azure SQL error numbers.
+var (
+	safeErrorNumbers = map[int32]bool{
+		1205:  true, // deadlock victim; the transaction was rolled back
+		1222:  true, // lock request timeout
+		10928: true, // Azure SQL resource limit reached
+		10929: true, // Azure SQL minimum guarantee not available
+		40501: true, // service is busy
+		40613: true, // database not currently available (failover in progress)
+		49918: true, // not enough resources to process request
+		49919: true, // too many create/update operations in progress
+		49920: true, // too many operations in progress
+	}
+	ambiguousErrorNumbers = map[int32]bool{
+		40143: true, // connection terminated while processing the request
+		40197: true, // service error processing the request (reconfiguration)
+	}
+)
+
+// classifyError decides whether err is transient.
+func classifyError(err error) retryClass {
+	if err == nil || errors.Is(err, sql.ErrNoRows) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return notRetryable
+	}
+
+	// go-mssqldb reports connections that broke before the statement was
+	// sent as driver.ErrBadConn.
+	if errors.Is(err, driver.ErrBadConn) {
+		return retrySafe
+	}
+
+	var sqlErr mssqldb.Error
+	if errors.As(err, &sqlErr) {
+		class := notRetryable
+		for _, e := range append([]mssqldb.Error{sqlErr}, sqlErr.All...) {
+			switch {
+			case safeErrorNumbers[e.Number]:
+				if class == notRetryable {
+					class = retrySafe
+				}
+			case ambiguousErrorNumbers[e.Number]:
+				class = retryAmbiguous
+			}
+		}
+		return class
+	}
+
+	var streamErr mssqldb.StreamError
+	if errors.As(err, &streamErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return retryAmbiguous
+	}
+
+	return notRetryable
+}
+
+// withRetry runs op until it succeeds, fails with a non-transient error, or
+// the attempts are exhausted.
+func (m *client) withRetry(ctx context.Context, idempotent bool, op func() error) error {
+	attempts := m.retry.MaxAttempts
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= attempts {
+			return err
+		}
+
+		class := classifyError(err)
+		if class == notRetryable || (class == retryAmbiguous && !idempotent) {
+			return err
+		}
+
+		delay := m.retry.backoff(attempt)
+		tflog.Debug(ctx, fmt.Sprintf("Transient error on attempt %d/%d, retrying in %s: %v", attempt, attempts, delay, err))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// exec runs a statement with retries. During a dry run the statement is
+// recorded instead.
+func (m *client) exec(ctx context.Context, conn *sql.DB, idempotent bool, query string, args ...any) (sql.Result, error) {
+	if m.recorder != nil {
+		return m.recordStatement(ctx, conn, query, args)
+	}
+
+	start := time.Now()
+	var result sql.Result
+	err := m.withRetry(ctx, idempotent, func() error {
+		var err error
+		result, err = conn.ExecContext(ctx, query, args...)
+		return err
+	})
+	if m.catalogCache != nil {
+		// Even a failed statement may have changed the catalog.
+		m.catalogCache.invalidate(m.databaseOf(conn))
+	}
+	m.logStatement(ctx, conn, query, args, start, result, err)
+	return result, err
+}
+
+// queryRow runs a single-row query with retries and scans it into dest.
+func (m *client) queryRow(ctx context.Context, conn *sql.DB, query string, args []any, dest ...any) error {
+	start := time.Now()
+	err := m.withRetry(ctx, idempotent, func() error {
+		return conn.QueryRowContext(ctx, query, args...).Scan(dest...)
+	})
+	m.logStatement(ctx, conn, query, args, start, nil, err)
+	return err
+}
+
+// query runs a multi-row query with retries. fn is called for the result
+// set and may run again if a later attempt is needed, so it must reset any
+// state it accumulates.
+func (m *client) query(ctx context.Context, conn *sql.DB, query string, args []any, fn func(*sql.Rows) error) error {
+	start := time.Now()
+	err := m.withRetry(ctx, idempotent, func() error {
+		rows, err := conn.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		if err := fn(rows); err != nil {
+			return err
+		}
+		return rows.Err()
+	})
+	m.logStatement(ctx, conn, query, args, start, nil, err)
+	return err
+}
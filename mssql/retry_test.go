@@ -0,0 +1,163 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	mssqldb "github.com/microsoft/go-mssqldb"
+)
+
+func Test_classifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want retryClass
+	}{
+		{name: "nil", err: nil, want: notRetryable},
+		{name: "no rows", err: sql.ErrNoRows, want: notRetryable},
+		{name: "context canceled", err: context.Canceled, want: notRetryable},
+		{name: "permission denied", err: mssqldb.Error{Number: 229}, want: notRetryable},
+		{name: "deadlock", err: mssqldb.Error{Number: 1205}, want: retrySafe},
+		{name: "wrapped deadlock", err: fmt.Errorf("failed to execute grant: %w", mssqldb.Error{Number: 1205}), want: retrySafe},
+		{name: "database unavailable", err: mssqldb.Error{Number: 40613}, want: retrySafe},
+		{name: "azure resources", err: mssqldb.Error{Number: 49918}, want: retrySafe},
+		{name: "azure reconfiguration", err: mssqldb.Error{Number: 40197}, want: retryAmbiguous},
+		{name: "earlier error in batch", err: mssqldb.Error{Number: 3621, All: []mssqldb.Error{{Number: 1205}, {Number: 3621}}}, want: retrySafe},
+		{name: "bad connection", err: driver.ErrBadConn, want: retrySafe},
+		{name: "connection reset", err: fmt.Errorf("read tcp: %w", syscall.ECONNRESET), want: retryAmbiguous},
+		{name: "unexpected eof", err: io.ErrUnexpectedEOF, want: retryAmbiguous},
+		{name: "stream error", err: mssqldb.StreamError{InnerError: errors.New("bad packet")}, want: retryAmbiguous},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Fatalf("classifyError(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Retry_backoff(t *testing.T) {
+	r := Retry{MaxAttempts: 10, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for attempt, ceiling := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		4: 800 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	} {
+		for i := 0; i < 20; i++ {
+			d := r.backoff(attempt)
+			if d < ceiling/2 || d > ceiling {
+				t.Fatalf("backoff(%d) = %s, want within [%s, %s]", attempt, d, ceiling/2, ceiling)
+			}
+		}
+	}
+}
+
+func Test_Retry_Validate(t *testing.T) {
+	if err := DefaultRetry.Validate(); err != nil {
+		t.Fatalf("DefaultRetry.Validate() = %v", err)
+	}
+	if err := (Retry{MaxAttempts: 0}).Validate(); err == nil {
+		t.Fatalf("expected error for max_attempts 0")
+	}
+	if err := (Retry{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: time.Millisecond}).Validate(); err == nil {
+		t.Fatalf("expected error for max_backoff < initial_backoff")
+	}
+}
+
+func newRetryTestClient(t *testing.T) (*client, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() err = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return &client{
+		conn:  db,
+		retry: Retry{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	}, mock
+}
+
+func Test_exec_RetriesDeadlock(t *testing.T) {
+	c, mock := newRetryTestClient(t)
+
+	mock.ExpectExec("CREATE DATABASE").WillReturnError(mssqldb.Error{Number: 1205, Message: "deadlock victim"})
+	mock.ExpectExec("CREATE DATABASE").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if _, err := c.exec(context.Background(), c.conn, nonIdempotent, "CREATE DATABASE [x]"); err != nil {
+		t.Fatalf("exec() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func Test_exec_AmbiguousErrorOnlyRetriesIdempotent(t *testing.T) {
+	lost := mssqldb.Error{Number: 40197, Message: "service error"}
+
+	t.Run("non-idempotent", func(t *testing.T) {
+		c, mock := newRetryTestClient(t)
+		mock.ExpectExec("CREATE LOGIN").WillReturnError(lost)
+
+		_, err := c.exec(context.Background(), c.conn, nonIdempotent, "CREATE LOGIN [x]")
+		if got := sqlErrorNumber(err); got != lost.Number {
+			t.Fatalf("exec() error = %v, want error %d", err, lost.Number)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	})
+
+	t.Run("idempotent", func(t *testing.T) {
+		c, mock := newRetryTestClient(t)
+		mock.ExpectExec("GRANT").WillReturnError(lost)
+		mock.ExpectExec("GRANT").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		if _, err := c.exec(context.Background(), c.conn, idempotent, "GRANT SELECT TO [x]"); err != nil {
+			t.Fatalf("exec() error = %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	})
+}
+
+func Test_queryRow_GivesUpAfterMaxAttempts(t *testing.T) {
+	c, mock := newRetryTestClient(t)
+
+	unavailable := mssqldb.Error{Number: 40613, Message: "database unavailable"}
+	for i := 0; i < 3; i++ {
+		mock.ExpectQuery("FROM sys.databases").WillReturnError(unavailable)
+	}
+
+	var name string
+	err := c.queryRow(context.Background(), c.conn, "SELECT [name] FROM sys.databases", nil, &name)
+	if got := sqlErrorNumber(err); got != unavailable.Number {
+		t.Fatalf("queryRow() error = %v, want error %d", err, unavailable.Number)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func sqlErrorNumber(err error) int32 {
+	var sqlErr mssqldb.Error
+	if errors.As(err, &sqlErr) {
+		return sqlErr.Number
+	}
+	return 0
+}
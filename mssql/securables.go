@@ -0,0 +1,190 @@
+package mssql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// securableClass is a class of database securables that permissions can be
+// granted on, e.g. GRANT ... ON CERTIFICATE::[name].
+type securableClass struct {
+	// name is the class in the ON clause.
+	name string
+	// objectTypes are the object_type values of grants on the class.
+	objectTypes []string
+	// class is sys.database_permissions.class.
+	class int
+	// schemaScoped securables are named schema.name.
+	schemaScoped bool
+	// view and idColumn are the catalog view of the securables and the
+	// column major_id refers to, and filter restricts the view to the
+	// class. OBJECT and SCHEMA use metadata functions instead.
+	view     string
+	idColumn string
+	filter   string
+}
+
+// securableClasses lists the securable classes of a database by class.
+// USER, ROLE and APPLICATION ROLE share the class of database principals.
+var securableClasses = []securableClass{
+	{name: "OBJECT", objectTypes: []string{"OBJECT", "TABLE", "VIEW", "PROCEDURE", "PROC", "FUNCTION", "SEQUENCE", "SYNONYM"}, class: 1, schemaScoped: true},
+	{name: "SCHEMA", objectTypes: []string{"SCHEMA"}, class: 3},
+	{name: "USER", objectTypes: []string{"USER"}, class: 4, view: "sys.database_principals", idColumn: "principal_id", filter: "[type] NOT IN ('A', 'R')"},
+	{name: "ROLE", objectTypes: []string{"ROLE"}, class: 4, view: "sys.database_principals", idColumn: "principal_id", filter: "[type] = 'R'"},
+	{name: "APPLICATION ROLE", objectTypes: []string{"APPLICATION ROLE"}, class: 4, view: "sys.database_principals", idColumn: "principal_id", filter: "[type] = 'A'"},
+	{name: "ASSEMBLY", objectTypes: []string{"ASSEMBLY"}, class: 5, view: "sys.assemblies", idColumn: "assembly_id"},
+	{name: "TYPE", objectTypes: []string{"TYPE"}, class: 6, schemaScoped: true, view: "sys.types", idColumn: "user_type_id"},
+	{name: "XML SCHEMA COLLECTION", objectTypes: []string{"XML SCHEMA COLLECTION"}, class: 10, schemaScoped: true, view: "sys.xml_schema_collections", idColumn: "xml_collection_id"},
+	{name: "SERVICE", objectTypes: []string{"SERVICE"}, class: 17, view: "sys.services", idColumn: "service_id"},
+	{name: "FULLTEXT CATALOG", objectTypes: []string{"FULLTEXT CATALOG"}, class: 23, view: "sys.fulltext_catalogs", idColumn: "fulltext_catalog_id"},
+	{name: "SYMMETRIC KEY", objectTypes: []string{"SYMMETRIC KEY"}, class: 24, view: "sys.symmetric_keys", idColumn: "symmetric_key_id"},
+	{name: "CERTIFICATE", objectTypes: []string{"CERTIFICATE"}, class: 25, view: "sys.certificates", idColumn: "certificate_id"},
+	{name: "ASYMMETRIC KEY", objectTypes: []string{"ASYMMETRIC KEY"}, class: 26, view: "sys.asymmetric_keys", idColumn: "asymmetric_key_id"},
+}
+
+// objectClass is the class of tables, views, procedures and the other
+// schema-scoped objects of sys.objects, the only class with columns.
+var objectClass = securableClasses[0]
+
+// ColumnObjectTypes are the object_type values of grants that may be
+// limited to columns.
+var ColumnObjectTypes = objectClass.objectTypes
+
+// ObjectTypes are the valid object_type values of a grant.
+var ObjectTypes = objectTypesOf(securableClasses)
+
+// objectTypesOf returns the object_type values of classes.
+func objectTypesOf(classes []securableClass) []string {
+	var types []string
+	for _, c := range classes {
+		types = append(types, c.objectTypes...)
+	}
+	return types
+}
+
+// lookupSecurableClass returns the class of an object_type.
+func lookupSecurableClass(objectType string) (securableClass, error) {
+	if c, ok := findSecurableClass(securableClasses, objectType); ok {
+		return c, nil
+	}
+	return securableClass{}, fmt.Errorf("object_type must be one of %s; got %q", strings.Join(ObjectTypes, ", "), objectType)
+}
+
+// findSecurableClass finds the class of an object_type among classes. Case
+// and runs of spaces do not matter.
+func findSecurableClass(classes []securableClass, objectType string) (securableClass, bool) {
+	normalized := strings.Join(strings.Fields(strings.ToUpper(objectType)), " ")
+	for _, c := range classes {
+		for _, t := range c.objectTypes {
+			if t == normalized {
+				return c, true
+			}
+		}
+	}
+	return securableClass{}, false
+}
+
+// splitName splits the name of a securable into its schema and name. The
+// schema is empty if the name is unqualified or the class is not
+// schema-scoped, in which case dots are part of the name.
+func (c securableClass) splitName(name string) (string, string) {
+	if !c.schemaScoped {
+		return "", name
+	}
+	return splitSchemaObject(name)
+}
+
+// Service Broker services are commonly named like URIs, such as
+// //Contoso/Orders/Service.
+var serviceNameRe = regexp.MustCompile(`^[A-Za-z0-9_.@#\\ /:-]+$`)
+
+// validateName validates the schema and name of a securable of the class.
+func (c securableClass) validateName(schema string, name string) error {
+	if schema != "" {
+		if err := validateIdentifier("object schema", schema); err != nil {
+			return err
+		}
+	}
+	if c.name != "SERVICE" {
+		return validateIdentifier("object name", name)
+	}
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("object name cannot be empty")
+	}
+	if len(name) > 128 {
+		return fmt.Errorf("object name must be 128 characters or fewer")
+	}
+	if !serviceNameRe.MatchString(name) {
+		return fmt.Errorf("object name contains invalid characters")
+	}
+	return nil
+}
+
+// idQuery returns the query of the id of a securable of the class named
+// @schema and @name. An empty @schema resolves like an unqualified name
+// does in GRANT, in the default schema of the user.
+func (c securableClass) idQuery() string {
+	switch c.name {
+	case "OBJECT":
+		return `SELECT OBJECT_ID(CASE WHEN @schema = '' THEN QUOTENAME(@name) ELSE QUOTENAME(@schema) + '.' + QUOTENAME(@name) END)`
+	case "SCHEMA":
+		return `SELECT SCHEMA_ID(@name)`
+	}
+	cmd := fmt.Sprintf("SELECT [%s] FROM %s WHERE [name] = @name", c.idColumn, c.view)
+	if c.schemaScoped {
+		cmd += " AND [schema_id] = COALESCE(SCHEMA_ID(NULLIF(@schema, '')), SCHEMA_ID())"
+	}
+	if c.filter != "" {
+		cmd += " AND " + c.filter
+	}
+	return cmd
+}
+
+// securableNameSQL and securableSchemaSQL resolve the name and schema of the
+// securable of a row of sys.database_permissions aliased sdp. They are NULL
+// for the database, and the schema is NULL for classes that are not
+// schema-scoped.
+var securableNameSQL, securableSchemaSQL = securableSQL()
+
+func securableSQL() (string, string) {
+	var name, schema strings.Builder
+	name.WriteString("CASE sdp.[class]")
+	schema.WriteString("CASE sdp.[class]")
+	seen := map[int]bool{}
+	for _, c := range securableClasses {
+		if seen[c.class] {
+			continue
+		}
+		seen[c.class] = true
+		switch c.name {
+		case "OBJECT":
+			name.WriteString(" WHEN 1 THEN OBJECT_NAME(sdp.[major_id])")
+			schema.WriteString(" WHEN 1 THEN OBJECT_SCHEMA_NAME(sdp.[major_id])")
+		case "SCHEMA":
+			name.WriteString(" WHEN 3 THEN SCHEMA_NAME(sdp.[major_id])")
+		default:
+			fmt.Fprintf(&name, " WHEN %d THEN (SELECT [name] FROM %s WHERE [%s] = sdp.[major_id])", c.class, c.view, c.idColumn)
+			if c.schemaScoped {
+				fmt.Fprintf(&schema, " WHEN %d THEN (SELECT SCHEMA_NAME([schema_id]) FROM %s WHERE [%s] = sdp.[major_id])", c.class, c.view, c.idColumn)
+			}
+		}
+	}
+	name.WriteString(" END")
+	schema.WriteString(" END")
+	return name.String(), schema.String()
+}
+
+// securableClassList is the list of classes of sys.database_permissions that
+// grants are read from, the database's included.
+var securableClassList = func() string {
+	classes := []string{"0"}
+	seen := map[int]bool{}
+	for _, c := range securableClasses {
+		if !seen[c.class] {
+			seen[c.class] = true
+			classes = append(classes, fmt.Sprint(c.class))
+		}
+	}
+	return strings.Join(classes, ", ")
+}()
@@ -0,0 +1,87 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// SERVERPROPERTY('EngineEdition') values.
+const (
+	EngineEditionPersonal                = 1
+	EngineEditionStandard                = 2
+	EngineEditionEnterprise              = 3
+	EngineEditionExpress                 = 4
+	EngineEditionAzureSQLDatabase        = 5
+	EngineEditionAzureSynapse            = 6
+	EngineEditionAzureSQLManagedInstance = 8
+	EngineEditionAzureSQLEdge            = 9
+	EngineEditionAzureSynapseServerless  = 11
+)
+
+// ServerInfo describes the connected server, as reported by SERVERPROPERTY.
+type ServerInfo struct {
+	// ProductMajorVersion is 13 for SQL Server 2016, 14 for 2017, 15 for
+	// 2019 and 16 for 2022. Azure SQL reports a fixed version (12) that
+	// does not reflect its features.
+	ProductMajorVersion int
+	EngineEdition       int
+	Edition             string
+	Collation           string
+}
+
+// IsAzure reports whether the server is a managed Azure SQL offering.
+func (s ServerInfo) IsAzure() bool {
+	switch s.EngineEdition {
+	case EngineEditionAzureSQLDatabase, EngineEditionAzureSynapse,
+		EngineEditionAzureSQLManagedInstance, EngineEditionAzureSynapseServerless:
+		return true
+	}
+	return false
+}
+
+// IsAzureSQLDatabase reports whether the server is Azure SQL Database, which
+// lacks most server-level options.
+func (s ServerInfo) IsAzureSQLDatabase() bool {
+	return s.EngineEdition == EngineEditionAzureSQLDatabase
+}
+
+// AtLeast reports whether a box SQL Server is at least the given major
+// version. Azure SQL is always considered current.
+func (s ServerInfo) AtLeast(major int) bool {
+	return s.IsAzure() || s.ProductMajorVersion >= major
+}
+
+// MaxCompatibilityLevel returns the highest database compatibility level the
+// server supports, or 0 when it is not known.
+func (s ServerInfo) MaxCompatibilityLevel() int64 {
+	if s.IsAzure() || s.ProductMajorVersion <= 0 {
+		return 0
+	}
+	return int64(s.ProductMajorVersion) * 10
+}
+
+func (s ServerInfo) String() string {
+	if s.IsAzure() {
+		return s.Edition
+	}
+	return fmt.Sprintf("%s, major version %d", s.Edition, s.ProductMajorVersion)
+}
+
+func (m *client) GetServerInfo(ctx context.Context) (ServerInfo, error) {
+	var info ServerInfo
+	// ProductMajorVersion is NULL before SQL Server 2012 SP1; fall back to
+	// the first part of ProductVersion.
+	cmd := `SELECT
+    CAST(COALESCE(SERVERPROPERTY('ProductMajorVersion'), PARSENAME(CAST(SERVERPROPERTY('ProductVersion') AS nvarchar(128)), 4)) AS int),
+    CAST(SERVERPROPERTY('EngineEdition') AS int),
+    CAST(SERVERPROPERTY('Edition') AS nvarchar(128)),
+    CAST(SERVERPROPERTY('Collation') AS nvarchar(128))`
+	tflog.Debug(ctx, fmt.Sprintf("Executing server properties query: command %s", cmd))
+	err := m.queryRow(ctx, m.conn, cmd, nil, &info.ProductMajorVersion, &info.EngineEdition, &info.Edition, &info.Collation)
+	if err != nil {
+		return info, fmt.Errorf("failed to read server properties: %v", err)
+	}
+	return info, nil
+}
@@ -0,0 +1,49 @@
+package mssql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func Test_GetServerInfo(t *testing.T) {
+	c, mock := newRetryTestClient(t)
+
+	mock.ExpectQuery("SERVERPROPERTY").WillReturnRows(
+		sqlmock.NewRows([]string{"major", "engine", "edition", "collation"}).
+			AddRow(15, EngineEditionEnterprise, "Enterprise Edition (64-bit)", "SQL_Latin1_General_CP1_CI_AS"),
+	)
+
+	info, err := c.GetServerInfo(context.Background())
+	if err != nil {
+		t.Fatalf("GetServerInfo() error = %v", err)
+	}
+	want := ServerInfo{ProductMajorVersion: 15, EngineEdition: EngineEditionEnterprise, Edition: "Enterprise Edition (64-bit)", Collation: "SQL_Latin1_General_CP1_CI_AS"}
+	if info != want {
+		t.Fatalf("GetServerInfo() = %+v, want %+v", info, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func Test_ServerInfo_Capabilities(t *testing.T) {
+	sql2016 := ServerInfo{ProductMajorVersion: 13, EngineEdition: EngineEditionStandard}
+	sql2022 := ServerInfo{ProductMajorVersion: 16, EngineEdition: EngineEditionExpress}
+	azure := ServerInfo{ProductMajorVersion: 12, EngineEdition: EngineEditionAzureSQLDatabase}
+	mi := ServerInfo{ProductMajorVersion: 12, EngineEdition: EngineEditionAzureSQLManagedInstance}
+
+	if sql2016.AtLeast(15) || !sql2022.AtLeast(15) || !azure.AtLeast(16) {
+		t.Fatalf("unexpected AtLeast results")
+	}
+	if got := sql2016.MaxCompatibilityLevel(); got != 130 {
+		t.Fatalf("MaxCompatibilityLevel() = %d, want 130", got)
+	}
+	if got := azure.MaxCompatibilityLevel(); got != 0 {
+		t.Fatalf("MaxCompatibilityLevel() = %d for Azure SQL, want 0 (unknown)", got)
+	}
+	if !azure.IsAzureSQLDatabase() || mi.IsAzureSQLDatabase() || !mi.IsAzure() || sql2022.IsAzure() {
+		t.Fatalf("unexpected edition classification")
+	}
+}
@@ -0,0 +1,163 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// serverClass is the class of permissions on the server itself, such as
+// VIEW SERVER STATE.
+var serverClass = securableClass{name: "SERVER", class: 100}
+
+// serverSecurableClasses lists the securable classes of the server, by
+// sys.server_permissions.class. Logins and server roles share the class of
+// server principals.
+var serverSecurableClasses = []securableClass{
+	{name: "LOGIN", objectTypes: []string{"LOGIN"}, class: 101},
+	{name: "SERVER ROLE", objectTypes: []string{"SERVER ROLE"}, class: 101},
+	{name: "ENDPOINT", objectTypes: []string{"ENDPOINT"}, class: 105},
+}
+
+// ServerObjectTypes are the valid object_type values of a server permission.
+var ServerObjectTypes = objectTypesOf(serverSecurableClasses)
+
+// serverSecurableNameSQL resolves the name of the securable of a row of
+// sys.server_permissions aliased sp. It is NULL for the server.
+const serverSecurableNameSQL = `CASE sp.[class]
+					WHEN 101 THEN (SELECT [name] FROM sys.server_principals WHERE [principal_id] = sp.[major_id])
+					WHEN 105 THEN (SELECT [name] FROM sys.endpoints WHERE [endpoint_id] = sp.[major_id])
+				END`
+
+// serverSecurable returns the class and name of the securable of a server
+// permission: serverClass and no name for a permission on the server.
+func serverSecurable(permission ServerPermission) (securableClass, string, error) {
+	hasObjectType := strings.TrimSpace(permission.ObjectType) != ""
+	hasObjectName := strings.TrimSpace(permission.ObjectName) != ""
+	if hasObjectType != hasObjectName {
+		return serverClass, "", fmt.Errorf("object_type and object_name must be set together")
+	}
+	if !hasObjectType {
+		return serverClass, "", nil
+	}
+	class, ok := findSecurableClass(serverSecurableClasses, permission.ObjectType)
+	if !ok {
+		return class, "", fmt.Errorf("object_type must be one of %s; got %q", strings.Join(ServerObjectTypes, ", "), permission.ObjectType)
+	}
+	if err := validateIdentifier("object name", permission.ObjectName); err != nil {
+		return class, "", err
+	}
+	return class, permission.ObjectName, nil
+}
+
+func (m *client) ReadServerPermission(ctx context.Context, permission ServerPermission) (ServerPermission, error) {
+	class, name, err := serverSecurable(permission)
+	if err != nil {
+		return permission, err
+	}
+
+	cmd := `
+			SELECT
+				pr.[name] AS [principal],
+				sp.[permission_name] AS [permission],
+				COALESCE(` + serverSecurableNameSQL + `, '') AS [object_name],
+				sp.[state]
+			FROM
+				sys.server_permissions AS sp
+			JOIN
+				sys.server_principals AS pr ON sp.grantee_principal_id = pr.principal_id
+			WHERE
+				sp.[class] = @class
+				AND sp.[state] IN ('G', 'W', 'D')
+				AND pr.[name] = @principal
+				AND sp.[permission_name] = @permission
+				AND (@class = 100 OR ` + serverSecurableNameSQL + ` = @object_name)`
+	args := []any{
+		sql.Named("class", class.class),
+		sql.Named("principal", permission.Principal),
+		sql.Named("permission", permission.Permission),
+		sql.Named("object_name", name),
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("Reading server permission: %s", cmd))
+
+	var objName, state string
+	if err := m.queryRow(ctx, m.conn, cmd, args, &permission.Principal, &permission.Permission, &objName, &state); err != nil {
+		return permission, err
+	}
+	if class.class != serverClass.class {
+		if permission.ObjectType == "" {
+			permission.ObjectType = class.name
+		}
+		permission.ObjectName = objName
+	}
+	permission.State = grantState(state)
+	permission.WithGrantOption = state == "W"
+	return permission, nil
+}
+
+// GrantServerPermission grants or denies a server permission. Server
+// permissions can only be granted in master.
+func (m *client) GrantServerPermission(ctx context.Context, permission ServerPermission) (ServerPermission, error) {
+	statement, suffix, err := grantStatement(permission.State, permission.WithGrantOption)
+	if err != nil {
+		return permission, err
+	}
+	permission.State = statement
+
+	err = m.execServerPermission(ctx, statement+" ", &permission, " + ' TO ' + QUOTENAME(@principal)"+suffix)
+	if err != nil {
+		return permission, fmt.Errorf("failed to execute grant: %v", err)
+	}
+	return permission, nil
+}
+
+func (m *client) RevokeServerPermission(ctx context.Context, permission ServerPermission) error {
+	return m.execServerPermission(ctx, "REVOKE ", &permission, " + ' FROM ' + QUOTENAME(@principal) + ' CASCADE'")
+}
+
+func (m *client) RevokeServerGrantOption(ctx context.Context, permission ServerPermission) error {
+	return m.execServerPermission(ctx, "REVOKE GRANT OPTION FOR ", &permission, " + ' FROM ' + QUOTENAME(@principal) + ' CASCADE'")
+}
+
+// execServerPermission runs statement for a server permission in master,
+// followed by grantee, the SQL expression of the clause naming the
+// principal. The permission and principal are normalized in place.
+func (m *client) execServerPermission(ctx context.Context, statement string, permission *ServerPermission, grantee string) error {
+	perm, err := normalizeDatabasePermission(permission.Permission)
+	if err != nil {
+		return err
+	}
+	principal, err := normalizePrincipalName(permission.Principal)
+	if err != nil {
+		return err
+	}
+	class, name, err := serverSecurable(*permission)
+	if err != nil {
+		return err
+	}
+	permission.Permission = perm
+	permission.Principal = principal
+
+	args := []any{sql.Named("permission", perm), sql.Named("principal", principal)}
+	on := ""
+	if class.class != serverClass.class {
+		on = " + ' ON ' + @class + '::' + QUOTENAME(@object_name)"
+		args = append(args, sql.Named("class", class.name), sql.Named("object_name", name))
+	}
+	query := "DECLARE @sql NVARCHAR(max);\nSET @sql = '" + statement + "' + @permission" + on + grantee + ";\nEXEC (@sql);"
+
+	conn, release, err := m.getConnForDatabase("master")
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	tflog.Debug(ctx, fmt.Sprintf("Running %s of server permission: %s", strings.TrimSpace(statement), query))
+
+	_, err = m.exec(ctx, conn, idempotent, query, args...)
+	return err
+}
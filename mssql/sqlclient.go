@@ -1,103 +1,250 @@
 package mssql
 
 import (
+	"container/list"
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/hashicorp/terraform-plugin-log/tflog"
-	_ "github.com/microsoft/go-mssqldb"
+	mssqldb "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/msdsn"
 )
 
 type client struct {
 	conn *sql.DB
 
-	host     string
-	port     int64
+	cfg      Config
 	database string
-	username string
-	password string
+	retry    Retry
+	pool     Pool
+
+	// adalTokenProvider is set when cfg.AzureAuth is configured and is shared by
+	// every per-database pool so tokens are cached once per provider.
+	adalTokenProvider func(ctx context.Context, serverSPN, stsURL string) (string, error)
+
+	// credentials is set when cfg.SqlAuth reads its credentials from a file
+	// or command; connectors then fetch them for every new connection.
+	credentials credentialSource
+
+	// statements is set when cfg.StatementLog is configured.
+	statements *statementLog
+	// recorder is set for dry runs; exec then records statements instead of
+	// running them.
+	recorder *dryRunRecorder
+
+	// catalogCache is set when cfg.CatalogCache is enabled.
+	catalogCache *catalogCache
+
+	// dialer is set when cfg.SSHTunnel is configured and is shared by every
+	// pool so that they all use one SSH session.
+	dialer *sshDialer
+
+	// open creates the pool for a database; nil uses openDatabase.
+	open func(database string) (*sql.DB, error)
 
 	connMu         sync.Mutex
-	connByDatabase map[string]*sql.DB
+	connByDatabase map[string]*dbPool
+	lru            *list.List
 }
 
-func buildConnString(host string, port int64, database string, username string, password string) string {
-	return fmt.Sprintf("server=%s;user id=%s;password=%s;port=%d;database=%s", host, username, password, port, database)
+// Config holds the settings used to connect to SQL Server.
+type Config struct {
+	Host     string
+	Port     int64
+	Database string
+
+	// Exactly one of SqlAuth or AzureAuth should be set.
+	SqlAuth   *SqlAuth
+	AzureAuth *AzureAuth
+
+	// TLS is optional; when nil the driver defaults apply (only the login
+	// packet is encrypted and the server certificate is not validated).
+	TLS *TLS
+
+	// Retry controls retries of transient errors. DefaultRetry applies when nil.
+	Retry *Retry
+
+	// Pool configures connection pooling. DefaultPool applies when nil.
+	Pool *Pool
+
+	// SSHTunnel, when set, dials every connection through an SSH bastion.
+	SSHTunnel *SSHTunnel
+
+	// ConnectionString is an optional go-mssqldb connection string. The
+	// fields above override any of its parameters.
+	ConnectionString string
+	// Parameters are extra go-mssqldb connection parameters such as
+	// "app name" or "dial timeout". They override ConnectionString.
+	Parameters map[string]string
+
+	// StatementLog is the path of a file to which every statement the client
+	// runs is appended as a JSON line. Password parameters are redacted.
+	StatementLog string
+
+	// DryRunFile, when set, makes NewClient return a client that records
+	// the statements that would change the server to this file instead of
+	// executing them. Reads still run against the server.
+	DryRunFile string
+
+	// CatalogCache makes the client read the permissions and role memberships
+	// of a database once and serve ReadPermission and ReadRoleMembership from
+	// that snapshot until it executes any statement.
+	CatalogCache bool
 }
 
-func NewClient(host string, port int64, database string, username string, password string) SqlClient {
-	if port <= 0 {
-		port = 1433
-	}
+// SqlAuth holds SQL authentication credentials. At most one of Password,
+// PasswordFile or CredentialCommand is set.
+type SqlAuth struct {
+	Username string
+	Password string
+
+	// PasswordFile is read for every new connection.
+	PasswordFile string
+	// CredentialCommand is run to obtain credentials, and run again shortly
+	// before they expire. It prints a JSON object with "username",
+	// "password" or "token", and an optional RFC 3339 "expiry".
+	CredentialCommand []string
+}
 
-	conn, err := sql.Open("sqlserver", buildConnString(host, port, database, username, password))
+// buildConnString renders the connection settings as a sqlserver:// URL with
+// every setting in the query, so that values containing ';' or '=' are
+// escaped. The first-class settings override the merged parameters.
+func buildConnString(cfg Config, database string) string {
+	// NewClient rejects parameters that do not merge.
+	params, _ := cfg.mergedParams()
+	query := url.Values{}
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	query.Set(msdsn.Server, cfg.Host)
+	query.Set(msdsn.Port, strconv.FormatInt(cfg.Port, 10))
+	query.Set(msdsn.Database, database)
+	if cfg.SqlAuth != nil {
+		query.Set(msdsn.UserID, cfg.SqlAuth.Username)
+		query.Set(msdsn.Password, cfg.SqlAuth.Password)
+	}
+	if cfg.TLS != nil {
+		cfg.TLS.connParams(query)
+	}
+	return "sqlserver://?" + query.Encode()
+}
 
+// newConnector builds a driver connector for the given database using the
+// configured authentication method.
+func (m *client) newConnector(database string) (driver.Connector, error) {
+	var connector *mssqldb.Connector
+	var err error
+	if m.cfg.AzureAuth == nil {
+		connector, err = mssqldb.NewConnector(buildConnString(m.cfg, database))
+	} else {
+		var params msdsn.Config
+		params, err = msdsn.Parse(buildConnString(m.cfg, database))
+		if err != nil {
+			return nil, err
+		}
+		connector, err = mssqldb.NewActiveDirectoryTokenConnector(params, m.cfg.AzureAuth.adalWorkflow(), m.adalTokenProvider)
+	}
 	if err != nil {
-		// TODO handle error
-		panic(err)
+		return nil, err
 	}
 
-	c := &client{
-		conn:           conn,
-		host:           host,
-		port:           port,
-		database:       database,
-		username:       username,
-		password:       password,
-		connByDatabase: map[string]*sql.DB{},
+	if m.credentials != nil {
+		return &credentialConnector{m: m, database: database, driver: connector.Driver()}, nil
 	}
-
-	// Seed the pool cache with the default database connection.
-	if database != "" {
-		c.connByDatabase[database] = conn
+	if m.dialer != nil {
+		connector.Dialer = m.dialer
 	}
-
-	return c
+	return connector, nil
 }
 
-// getConnForDatabase returns a pooled connection for a database.
-//
-// This is intentionally implemented as a cache of `*sql.DB` pools per database,
-// so callers don't need to manage close semantics for per-database connections.
-func (m *client) getConnForDatabase(database string) (*sql.DB, error) {
-	if database == "" || database == m.database {
-		return m.conn, nil
+// NewClient returns a client for the server described by cfg. It does not
+// connect; call Ping to verify the server is reachable.
+func NewClient(cfg Config) (SqlClient, error) {
+	if cfg.Port <= 0 {
+		cfg.Port = 1433
+	}
+
+	c := &client{
+		cfg:            cfg,
+		database:       cfg.Database,
+		retry:          DefaultRetry,
+		pool:           DefaultPool,
+		connByDatabase: map[string]*dbPool{},
+		lru:            list.New(),
+	}
+	if cfg.Retry != nil {
+		c.retry = *cfg.Retry
+	}
+	if cfg.Pool != nil {
+		c.pool = *cfg.Pool
+	}
+	if cfg.AzureAuth != nil {
+		c.adalTokenProvider = azureTokenProvider(*cfg.AzureAuth)
+	}
+	if cfg.SqlAuth != nil {
+		c.credentials = cfg.SqlAuth.credentialSource()
+	}
+	if cfg.SSHTunnel != nil {
+		if err := cfg.SSHTunnel.Validate(); err != nil {
+			return nil, err
+		}
+		c.dialer = newSSHDialer(*cfg.SSHTunnel)
 	}
 
-	m.connMu.Lock()
-	existing := m.connByDatabase[database]
-	m.connMu.Unlock()
-	if existing != nil {
-		return existing, nil
+	if _, err := cfg.mergedParams(); err != nil {
+		return nil, err
 	}
 
-	// Create outside the lock to avoid blocking concurrent callers.
-	newConn, err := sql.Open("sqlserver", buildConnString(m.host, m.port, database, m.username, m.password))
+	connector, err := c.newConnector(cfg.Database)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database %s: %v", database, err)
+		return nil, fmt.Errorf("invalid connection settings: %v", err)
 	}
 
-	if err := newConn.Ping(); err != nil {
-		newConn.Close()
-		return nil, fmt.Errorf("failed to ping database %s: %v", database, err)
+	if cfg.StatementLog != "" {
+		c.statements, err = openStatementLog(cfg.StatementLog)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open statement log: %v", err)
+		}
 	}
 
-	m.connMu.Lock()
-	// Double-check to avoid duplicating pools in races.
-	if existing = m.connByDatabase[database]; existing == nil {
-		m.connByDatabase[database] = newConn
-		m.connMu.Unlock()
-		return newConn, nil
+	if cfg.DryRunFile != "" {
+		c.recorder, err = openDryRunRecorder(cfg.DryRunFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open dry run file: %v", err)
+		}
 	}
-	m.connMu.Unlock()
 
-	newConn.Close()
-	return existing, nil
+	if cfg.CatalogCache {
+		c.catalogCache = newCatalogCache()
+	}
+
+	c.conn = sql.OpenDB(connector)
+	c.pool.apply(c.conn)
+
+	if c.recorder != nil {
+		return &recordingClient{client: c}, nil
+	}
+	return c, nil
+}
+
+// Ping connects to the server and logs in. Failures are returned as a
+// *ConnectError.
+func (m *client) Ping(ctx context.Context) error {
+	err := m.withRetry(ctx, idempotent, func() error {
+		return m.conn.PingContext(ctx)
+	})
+	if err != nil {
+		return newConnectError(m.cfg, err)
+	}
+	return nil
 }
 
 func (m *client) GetUser(ctx context.Context, database string, username string) (User, error) {
@@ -105,10 +252,11 @@ func (m *client) GetUser(ctx context.Context, database string, username string)
 		Id: username,
 	}
 
-	conn, err := m.getConnForDatabase(database)
+	conn, release, err := m.getConnForDatabase(database)
 	if err != nil {
 		return user, err
 	}
+	defer release()
 
 	cmd := `SELECT
     P.[name] AS id,
@@ -123,9 +271,8 @@ LEFT JOIN sys.server_principals SP ON P.[sid] = SP.[sid]
 WHERE P.[name] = @username`
 
 	tflog.Debug(ctx, fmt.Sprintf("Executing refresh query for username %s: command %s", username, cmd))
-	result := conn.QueryRowContext(ctx, cmd, sql.Named("username", username))
-
-	err = result.Scan(&user.Id, &user.Sid, &user.Username, &user.Type, &user.External, &user.DefaultSchema, &user.LoginName)
+	err = m.queryRow(ctx, conn, cmd, []any{sql.Named("username", username)},
+		&user.Id, &user.Sid, &user.Username, &user.Type, &user.External, &user.DefaultSchema, &user.LoginName)
 	return user, err
 }
 
@@ -136,12 +283,13 @@ func (m *client) CreateUser(ctx context.Context, database string, create CreateU
 		return user, err
 	}
 
-	conn, err := m.getConnForDatabase(database)
+	conn, release, err := m.getConnForDatabase(database)
 	if err != nil {
 		return user, err
 	}
+	defer release()
 
-	_, err = conn.ExecContext(ctx,
+	_, err = m.exec(ctx, conn, nonIdempotent,
 		cmd,
 		args...,
 	)
@@ -232,10 +380,11 @@ func (m *client) UpdateUser(ctx context.Context, database string, update UpdateU
 	addOption(&optionsBuilder, &args, "PASSWORD", update.Password, false)
 	addOption(&optionsBuilder, &args, "DEFAULT_SCHEMA", update.DefaultSchema, true)
 
-	conn, err := m.getConnForDatabase(database)
+	conn, release, err := m.getConnForDatabase(database)
 	if err != nil {
 		return User{}, err
 	}
+	defer release()
 
 	if optionsBuilder.Len() > 0 {
 
@@ -250,7 +399,7 @@ func (m *client) UpdateUser(ctx context.Context, database string, update UpdateU
 		cmd := cmdBuilder.String()
 		tflog.Debug(ctx, fmt.Sprintf("Updating User %s: cmd: %s", update.Id, cmd))
 
-		_, err := conn.ExecContext(ctx,
+		_, err := m.exec(ctx, conn, idempotent,
 			cmd,
 			args...,
 		)
@@ -271,12 +420,13 @@ func (m *client) DeleteUser(ctx context.Context, database string, username strin
 
 	tflog.Debug(ctx, fmt.Sprintf("Deleting User %s: cmd: %s", username, cmd))
 
-	conn, err := m.getConnForDatabase(database)
+	conn, release, err := m.getConnForDatabase(database)
 	if err != nil {
 		return err
 	}
+	defer release()
 
-	_, err = conn.ExecContext(ctx,
+	_, err = m.exec(ctx, conn, idempotent,
 		cmd,
 		username,
 		username,
@@ -316,10 +466,19 @@ func (m *client) ReadRoleMembership(ctx context.Context, database string, id str
 		return roleMembership, err
 	}
 
-	conn, err := m.getConnForDatabase(database)
+	if m.useCatalog(database) {
+		snapshot, err := m.catalog(ctx, database)
+		if err != nil {
+			return roleMembership, err
+		}
+		return snapshot.roleMembership(role, member)
+	}
+
+	conn, release, err := m.getConnForDatabase(database)
 	if err != nil {
 		return roleMembership, err
 	}
+	defer release()
 
 	cmd := `SELECT r.name role_principal_name,
 m.name AS member_principal_name
@@ -335,13 +494,7 @@ AND M.name = @p2
 
 	tflog.Debug(ctx, fmt.Sprintf("Reading Role Assignment role %s, member %s: cmd: %s", role, member, cmd))
 
-	result := conn.QueryRowContext(ctx,
-		cmd,
-		role,
-		member,
-	)
-
-	err = result.Scan(&roleMembership.Role, &roleMembership.Member)
+	err = m.queryRow(ctx, conn, cmd, []any{role, member}, &roleMembership.Role, &roleMembership.Member)
 	if err != nil {
 		tflog.Warn(ctx, err.Error())
 		return roleMembership, err
@@ -356,17 +509,18 @@ AND M.name = @p2
 func (m *client) AssignRole(ctx context.Context, database string, role string, member string) (RoleMembership, error) {
 	var roleMembership RoleMembership
 
-	conn, err := m.getConnForDatabase(database)
+	conn, release, err := m.getConnForDatabase(database)
 	if err != nil {
 		return roleMembership, err
 	}
+	defer release()
 
 	cmd := `DECLARE @sql NVARCHAR(max);
           SET @sql = 'ALTER ROLE ' + QUOTENAME(@p1) + ' ADD MEMBER ' + QUOTENAME(@p2);
           EXEC (@sql);`
 
 	tflog.Debug(ctx, fmt.Sprintf("Adding Principal %s to role %s: cmd: %s", member, role, cmd))
-	_, err = conn.ExecContext(ctx,
+	_, err = m.exec(ctx, conn, idempotent,
 		cmd,
 		role,
 		member,
@@ -379,17 +533,18 @@ func (m *client) AssignRole(ctx context.Context, database string, role string, m
 }
 
 func (m *client) UnassignRole(ctx context.Context, database string, role string, principal string) error {
-	conn, err := m.getConnForDatabase(database)
+	conn, release, err := m.getConnForDatabase(database)
 	if err != nil {
 		return err
 	}
+	defer release()
 
 	cmd := `DECLARE @sql NVARCHAR(max);
           SET @sql = 'ALTER ROLE ' + QUOTENAME(@p1) + ' DROP MEMBER ' + QUOTENAME(@p2);
           EXEC (@sql);`
 
 	tflog.Debug(ctx, fmt.Sprintf("Removing Principal %s from role %s: cmd: %s", principal, role, cmd))
-	_, err = conn.ExecContext(ctx,
+	_, err = m.exec(ctx, conn, idempotent,
 		cmd,
 		role,
 		principal,
@@ -417,12 +572,8 @@ WHERE r.name = @role AND m.name = @principal`
 
 	tflog.Debug(ctx, fmt.Sprintf("Reading Server Role Assignment role %s, member %s", role, principal))
 
-	result := m.conn.QueryRowContext(ctx, cmd,
-		sql.Named("role", role),
-		sql.Named("principal", principal),
-	)
-
-	err := result.Scan(&roleMembership.Role, &roleMembership.Member)
+	err := m.queryRow(ctx, m.conn, cmd, []any{sql.Named("role", role), sql.Named("principal", principal)},
+		&roleMembership.Role, &roleMembership.Member)
 	if err != nil {
 		return roleMembership, err
 	}
@@ -446,7 +597,7 @@ SET @sql = 'ALTER SERVER ROLE ' + QUOTENAME(@role) + ' ADD MEMBER ' + QUOTENAME(
 EXEC (@sql);`
 
 	tflog.Debug(ctx, fmt.Sprintf("Adding Principal %s to server role %s", principal, role))
-	_, err := m.conn.ExecContext(ctx, cmd,
+	_, err := m.exec(ctx, m.conn, idempotent, cmd,
 		sql.Named("role", role),
 		sql.Named("principal", principal),
 	)
@@ -470,7 +621,7 @@ SET @sql = 'ALTER SERVER ROLE ' + QUOTENAME(@role) + ' DROP MEMBER ' + QUOTENAME
 EXEC (@sql);`
 
 	tflog.Debug(ctx, fmt.Sprintf("Removing Principal %s from server role %s", principal, role))
-	_, err := m.conn.ExecContext(ctx, cmd,
+	_, err := m.exec(ctx, m.conn, idempotent, cmd,
 		sql.Named("role", role),
 		sql.Named("principal", principal),
 	)
@@ -482,10 +633,11 @@ func (m *client) ReadDatabasePermission(ctx context.Context, database string, id
 	principal := strings.Split(id, "/")[0]
 	permission := strings.Split(id, "/")[1]
 
-	conn, err := m.getConnForDatabase(database)
+	conn, release, err := m.getConnForDatabase(database)
 	if err != nil {
 		return DatabaseGrantPermission, err
 	}
+	defer release()
 
 	cmd := `
 		SELECT
@@ -503,9 +655,7 @@ func (m *client) ReadDatabasePermission(ctx context.Context, database string, id
 	`
 
 	tflog.Debug(ctx, fmt.Sprintf("Reading DB permission [principal: %s, permission: %s]", principal, permission))
-	result := conn.QueryRowContext(ctx, cmd, principal, permission)
-
-	err = result.Scan(&DatabaseGrantPermission.Principal, &DatabaseGrantPermission.Permission)
+	err = m.queryRow(ctx, conn, cmd, []any{principal, permission}, &DatabaseGrantPermission.Principal, &DatabaseGrantPermission.Permission)
 	if err != nil {
 		tflog.Warn(ctx, fmt.Sprintf("failed to scan result: %v", err))
 		return DatabaseGrantPermission, err
@@ -529,10 +679,11 @@ func (m *client) GrantDatabasePermission(ctx context.Context, database string, p
 		return DatabaseGrantPermission, err
 	}
 
-	conn, err := m.getConnForDatabase(database)
+	conn, release, err := m.getConnForDatabase(database)
 	if err != nil {
 		return DatabaseGrantPermission, err
 	}
+	defer release()
 
 	cmd := `DECLARE @sql NVARCHAR(max);
 SET @sql = N'GRANT ' + @p1 + N' TO ' + QUOTENAME(@p2) + N';';
@@ -540,7 +691,7 @@ EXEC (@sql);`
 
 	tflog.Debug(ctx, fmt.Sprintf("Granting permission %s to %s", perm, principal))
 
-	_, err = conn.ExecContext(ctx, cmd, perm, principal)
+	_, err = m.exec(ctx, conn, idempotent, cmd, perm, principal)
 	if err != nil {
 		return DatabaseGrantPermission, fmt.Errorf("failed to execute grant query: %v", err)
 	}
@@ -558,10 +709,11 @@ func (m *client) RevokeDatabasePermission(ctx context.Context, database string,
 		return err
 	}
 
-	conn, err := m.getConnForDatabase(database)
+	conn, release, err := m.getConnForDatabase(database)
 	if err != nil {
 		return err
 	}
+	defer release()
 
 	cmd := `DECLARE @sql NVARCHAR(max);
 SET @sql = N'REVOKE ' + @p1 + N' FROM ' + QUOTENAME(@p2) + N' CASCADE;';
@@ -569,7 +721,7 @@ EXEC (@sql);`
 
 	tflog.Debug(ctx, fmt.Sprintf("Revoking permission %s from user %s", perm, principal))
 
-	_, err = conn.ExecContext(ctx, cmd, perm, principal)
+	_, err = m.exec(ctx, conn, idempotent, cmd, perm, principal)
 	if err != nil {
 		return fmt.Errorf("failed to execute revoke query: %v", err)
 	}
@@ -579,108 +731,261 @@ EXEC (@sql);`
 
 // Permission operations.
 func (m *client) ReadPermission(ctx context.Context, grant GrantPermission) (GrantPermission, error) {
-	conn, err := m.getConnForDatabase(grant.Database)
-	if err != nil {
-		return grant, err
-	}
-
-	var cmd string
-	var result *sql.Row
-
 	hasObjectType := strings.TrimSpace(grant.ObjectType) != ""
 	hasObjectName := strings.TrimSpace(grant.ObjectName) != ""
 	if hasObjectType != hasObjectName {
 		return grant, fmt.Errorf("object_type and object_name must be set together")
 	}
 
+	var class securableClass
+	if hasObjectType {
+		var err error
+		if class, err = lookupSecurableClass(grant.ObjectType); err != nil {
+			return grant, err
+		}
+	}
+	if len(grant.Columns) > 0 && class.name != objectClass.name {
+		return grant, errColumnsWithoutObject
+	}
+
+	if m.useCatalog(grant.Database) {
+		return m.readPermissionFromCatalog(ctx, class, grant)
+	}
+
+	conn, release, err := m.getConnForDatabase(grant.Database)
+	if err != nil {
+		return grant, err
+	}
+	defer release()
+
+	if len(grant.Columns) > 0 {
+		return m.readColumnPermission(ctx, conn, grant)
+	}
+
+	var cmd string
+	var args []any
+
 	if hasObjectType {
-		objSchema, objName := splitSchemaObject(grant.ObjectName)
+		objSchema, objName := class.splitName(grant.ObjectName)
 		cmd = `
 			SELECT
 				dp.[name] AS [principal],
 				sdp.[permission_name] AS [permission],
-				CASE sdp.[class]
-					WHEN 3 THEN 'SCHEMA'
-					WHEN 1 THEN 'OBJECT'
-					ELSE sdp.[class_desc]
-				END AS [object_type],
-				COALESCE(OBJECT_SCHEMA_NAME(sdp.[major_id]), '') AS [object_schema],
-				CASE 
-					WHEN sdp.[class] = 3 THEN SCHEMA_NAME(sdp.[major_id])
-					ELSE OBJECT_NAME(sdp.[major_id])
-				END AS [object_name]
+				COALESCE(` + securableSchemaSQL + `, '') AS [object_schema],
+				` + securableNameSQL + ` AS [object_name],
+				sdp.[state]
 			FROM
 				sys.database_permissions AS sdp
 			JOIN
 				sys.database_principals AS dp ON sdp.grantee_principal_id = dp.principal_id
 			WHERE
-				sdp.[state] IN ('G', 'W')
+				sdp.[class] = @class
+				AND sdp.[minor_id] = 0
+				AND sdp.[state] IN ('G', 'W', 'D')
 				AND dp.[name] = @principal
 				AND sdp.[permission_name] = @permission
-				AND (
-					(sdp.[class] = 1 AND OBJECT_NAME(sdp.[major_id]) = @object_name AND (@object_schema = '' OR OBJECT_SCHEMA_NAME(sdp.[major_id]) = @object_schema))
-					OR (sdp.[class] = 3 AND SCHEMA_NAME(sdp.[major_id]) = @object_name)
-				)`
-		result = conn.QueryRowContext(ctx, cmd,
+				AND ` + securableNameSQL + ` = @object_name
+				AND (@object_schema = '' OR ` + securableSchemaSQL + ` = @object_schema)`
+		args = []any{
+			sql.Named("class", class.class),
 			sql.Named("principal", grant.Principal),
 			sql.Named("permission", grant.Permission),
 			sql.Named("object_name", objName),
 			sql.Named("object_schema", objSchema),
-		)
+		}
 	} else {
 		cmd = `
 			SELECT
 				dp.[name] AS [principal],
-				sdp.[permission_name] AS [permission]
+				sdp.[permission_name] AS [permission],
+				sdp.[state]
 			FROM
 				sys.database_permissions AS sdp
 			JOIN
 				sys.database_principals AS dp ON sdp.grantee_principal_id = dp.principal_id
 			WHERE
 				sdp.[class] = 0
-				AND sdp.[state] IN ('G', 'W')
+				AND sdp.[state] IN ('G', 'W', 'D')
 				AND dp.[name] = @principal
 				AND sdp.[permission_name] = @permission`
-		result = conn.QueryRowContext(ctx, cmd,
+		args = []any{
 			sql.Named("principal", grant.Principal),
 			sql.Named("permission", grant.Permission),
-		)
+		}
 	}
 
 	tflog.Debug(ctx, fmt.Sprintf("Reading permission: %s", cmd))
 
+	var state string
 	if hasObjectType {
-		var objType, objSchema, objName string
-		if err := result.Scan(&grant.Principal, &grant.Permission, &objType, &objSchema, &objName); err != nil {
+		var objSchema, objName string
+		if err := m.queryRow(ctx, conn, cmd, args, &grant.Principal, &grant.Permission, &objSchema, &objName, &state); err != nil {
 			return grant, err
 		}
-		// Preserve caller-specified type for OBJECT class (TABLE/VIEW/PROC/FUNCTION).
-		if strings.EqualFold(objType, "OBJECT") && grant.ObjectType != "" && !strings.EqualFold(grant.ObjectType, "OBJECT") {
-			// keep existing grant.ObjectType
-		} else {
-			grant.ObjectType = objType
-		}
-		if strings.EqualFold(grant.ObjectType, "SCHEMA") {
-			grant.ObjectName = objName
-		} else if objSchema != "" {
-			grant.ObjectName = fmt.Sprintf("%s.%s", objSchema, objName)
-		} else {
-			grant.ObjectName = objName
-		}
+		setGrantObject(&grant, class, objSchema, objName)
 	} else {
-		if err := result.Scan(&grant.Principal, &grant.Permission); err != nil {
+		if err := m.queryRow(ctx, conn, cmd, args, &grant.Principal, &grant.Permission, &state); err != nil {
 			return grant, err
 		}
 	}
+	grant.State = grantState(state)
+	grant.WithGrantOption = state == "W"
 
 	return grant, nil
 }
 
+var errColumnsWithoutObject = errors.New("columns require an object_type of TABLE, VIEW or OBJECT")
+
+// readColumnPermission reads the columns of an object a permission is
+// granted or denied on, from the rows of sys.database_permissions with a
+// minor_id. All columns the principal has the permission on are returned,
+// not only those of the grant.
+func (m *client) readColumnPermission(ctx context.Context, conn *sql.DB, grant GrantPermission) (GrantPermission, error) {
+	objSchema, objName := splitSchemaObject(grant.ObjectName)
+	cmd := `
+			SELECT
+				dp.[name] AS [principal],
+				sdp.[permission_name] AS [permission],
+				COALESCE(OBJECT_SCHEMA_NAME(sdp.[major_id]), '') AS [object_schema],
+				OBJECT_NAME(sdp.[major_id]) AS [object_name],
+				COL_NAME(sdp.[major_id], sdp.[minor_id]) AS [column],
+				sdp.[state]
+			FROM
+				sys.database_permissions AS sdp
+			JOIN
+				sys.database_principals AS dp ON sdp.grantee_principal_id = dp.principal_id
+			WHERE
+				sdp.[class] = 1
+				AND sdp.[minor_id] <> 0
+				AND sdp.[state] IN ('G', 'W', 'D')
+				AND dp.[name] = @principal
+				AND sdp.[permission_name] = @permission
+				AND OBJECT_NAME(sdp.[major_id]) = @object_name
+				AND (@object_schema = '' OR OBJECT_SCHEMA_NAME(sdp.[major_id]) = @object_schema)
+			ORDER BY
+				[column]`
+	args := []any{
+		sql.Named("principal", grant.Principal),
+		sql.Named("permission", grant.Permission),
+		sql.Named("object_name", objName),
+		sql.Named("object_schema", objSchema),
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("Reading column permission: %s", cmd))
+
+	var columns, states []string
+	var rowSchema, rowName string
+	err := m.query(ctx, conn, cmd, args, func(rows *sql.Rows) error {
+		columns, states = nil, nil
+		for rows.Next() {
+			var column, state string
+			if err := rows.Scan(&grant.Principal, &grant.Permission, &rowSchema, &rowName, &column, &state); err != nil {
+				return err
+			}
+			columns = append(columns, column)
+			states = append(states, state)
+		}
+		return nil
+	})
+	if err != nil {
+		return grant, err
+	}
+	if len(columns) == 0 {
+		return grant, sql.ErrNoRows
+	}
+	setGrantObject(&grant, objectClass, rowSchema, rowName)
+	setGrantColumns(&grant, columns, states)
+	return grant, nil
+}
+
+// setGrantColumns sets the columns of a grant and the state they share. A
+// column grant is denied if any of its columns is, and has the grant option
+// only if all of its columns do, so that applying the grant again makes
+// every column match.
+func setGrantColumns(grant *GrantPermission, columns []string, states []string) {
+	grant.Columns = columns
+	grant.State, grant.WithGrantOption = "GRANT", true
+	for _, state := range states {
+		if state == "D" {
+			grant.State = "DENY"
+		}
+		if state != "W" {
+			grant.WithGrantOption = false
+		}
+	}
+}
+
+// columnList returns the SQL expression of the column list of a grant, e.g.
+// ' (' + QUOTENAME(@column_0) + ')', appending the column names to args.
+func columnList(columns []string, args *[]any) (string, error) {
+	if len(columns) == 0 {
+		return "", nil
+	}
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		column = strings.TrimSpace(column)
+		if err := validateIdentifier("column", column); err != nil {
+			return "", err
+		}
+		name := fmt.Sprintf("column_%d", i)
+		quoted[i] = "QUOTENAME(@" + name + ")"
+		*args = append(*args, sql.Named(name, column))
+	}
+	return " + ' (' + " + strings.Join(quoted, " + ', ' + ") + " + ')'", nil
+}
+
+// grantState returns the State of a grant for sys.database_permissions.state:
+// D for DENY, G or W for GRANT.
+func grantState(state string) string {
+	if state == "D" {
+		return "DENY"
+	}
+	return "GRANT"
+}
+
+// grantStatement returns the statement that applies the State of a grant,
+// and the SQL expression of what follows the grantee. GRANT replaces a DENY
+// and DENY replaces a GRANT, so either changes the state of an existing
+// permission in place.
+func grantStatement(state string, withGrantOption bool) (string, string, error) {
+	switch strings.ToUpper(strings.TrimSpace(state)) {
+	case "", "GRANT":
+		if withGrantOption {
+			return "GRANT", " + ' WITH GRANT OPTION'", nil
+		}
+		return "GRANT", "", nil
+	case "DENY":
+		if withGrantOption {
+			return "", "", fmt.Errorf("with_grant_option only applies to GRANT")
+		}
+		// Denying a permission that was granted WITH GRANT OPTION requires
+		// CASCADE, like revoking it does.
+		return "DENY", " + ' CASCADE'", nil
+	default:
+		return "", "", fmt.Errorf("state must be GRANT or DENY; got %q", state)
+	}
+}
+
+// setGrantObject sets the securable of a grant of the class read from the
+// server. The object_type of the grant is kept, so that a TABLE stays a
+// TABLE rather than becoming an OBJECT.
+func setGrantObject(grant *GrantPermission, class securableClass, objSchema string, objName string) {
+	if grant.ObjectType == "" {
+		grant.ObjectType = class.name
+	}
+	if objSchema != "" {
+		grant.ObjectName = fmt.Sprintf("%s.%s", objSchema, objName)
+	} else {
+		grant.ObjectName = objName
+	}
+}
+
 func (m *client) GrantPermission(ctx context.Context, grant GrantPermission) (GrantPermission, error) {
-	conn, err := m.getConnForDatabase(grant.Database)
+	conn, release, err := m.getConnForDatabase(grant.Database)
 	if err != nil {
 		return grant, err
 	}
+	defer release()
 
 	perm, err := normalizeDatabasePermission(grant.Permission)
 	if err != nil {
@@ -693,6 +998,12 @@ func (m *client) GrantPermission(ctx context.Context, grant GrantPermission) (Gr
 	grant.Permission = perm
 	grant.Principal = principal
 
+	statement, suffix, err := grantStatement(grant.State, grant.WithGrantOption)
+	if err != nil {
+		return grant, err
+	}
+	grant.State = statement
+
 	hasObjectType := strings.TrimSpace(grant.ObjectType) != ""
 	hasObjectName := strings.TrimSpace(grant.ObjectName) != ""
 	if hasObjectType != hasObjectName {
@@ -702,49 +1013,47 @@ func (m *client) GrantPermission(ctx context.Context, grant GrantPermission) (Gr
 	var query string
 	var args []any
 	if hasObjectType {
-		securableClass, err := normalizeObjectType(grant.ObjectType)
+		class, objSchema, objName, err := grantSecurable(grant)
 		if err != nil {
 			return grant, err
 		}
-		objSchema, objName := splitSchemaObject(grant.ObjectName)
-		if objSchema != "" {
-			if err := validateIdentifier("object schema", objSchema); err != nil {
-				return grant, err
-			}
-		}
-		if err := validateIdentifier("object name", objName); err != nil {
+		columns, err := columnList(grant.Columns, &args)
+		if err != nil {
 			return grant, err
 		}
 
 		var cmdBuilder strings.Builder
 		cmdBuilder.WriteString("DECLARE @sql NVARCHAR(max);\n")
-		cmdBuilder.WriteString("SET @sql = 'GRANT ' + @permission + ' ON ' + @class + '::' + ")
+		cmdBuilder.WriteString("SET @sql = '" + statement + " ' + @permission" + columns + " + ' ON ' + @class + '::' + ")
 		if objSchema != "" {
 			cmdBuilder.WriteString("QUOTENAME(@object_schema) + '.' + QUOTENAME(@object_name)")
 			args = append(args, sql.Named("object_schema", objSchema))
 		} else {
 			cmdBuilder.WriteString("QUOTENAME(@object_name)")
 		}
-		cmdBuilder.WriteString(" + ' TO ' + QUOTENAME(@principal);")
+		cmdBuilder.WriteString(" + ' TO ' + QUOTENAME(@principal)" + suffix + ";")
 		cmdBuilder.WriteString("\nEXEC (@sql);")
 		args = append(args,
 			sql.Named("permission", grant.Permission),
-			sql.Named("class", securableClass),
+			sql.Named("class", class.name),
 			sql.Named("object_name", objName),
 			sql.Named("principal", grant.Principal),
 		)
 		query = cmdBuilder.String()
 	} else {
-		query = "DECLARE @sql NVARCHAR(max);\nSET @sql = 'GRANT ' + @permission + ' TO ' + QUOTENAME(@principal);\nEXEC (@sql);"
+		if len(grant.Columns) > 0 {
+			return grant, errColumnsWithoutObject
+		}
+		query = "DECLARE @sql NVARCHAR(max);\nSET @sql = '" + statement + " ' + @permission + ' TO ' + QUOTENAME(@principal)" + suffix + ";\nEXEC (@sql);"
 		args = append(args,
 			sql.Named("permission", grant.Permission),
 			sql.Named("principal", grant.Principal),
 		)
 	}
 
-	tflog.Debug(ctx, fmt.Sprintf("Granting permission: %s", query))
+	tflog.Debug(ctx, fmt.Sprintf("Applying %s of permission: %s", statement, query))
 
-	if _, err := conn.ExecContext(ctx, query, args...); err != nil {
+	if _, err := m.exec(ctx, conn, idempotent, query, args...); err != nil {
 		return grant, fmt.Errorf("failed to execute grant: %v", err)
 	}
 
@@ -752,10 +1061,21 @@ func (m *client) GrantPermission(ctx context.Context, grant GrantPermission) (Gr
 }
 
 func (m *client) RevokePermission(ctx context.Context, grant GrantPermission) error {
-	conn, err := m.getConnForDatabase(grant.Database)
+	return m.revokePermission(ctx, "REVOKE ", grant)
+}
+
+func (m *client) RevokeGrantOption(ctx context.Context, grant GrantPermission) error {
+	return m.revokePermission(ctx, "REVOKE GRANT OPTION FOR ", grant)
+}
+
+// revokePermission runs statement, REVOKE or REVOKE GRANT OPTION FOR, for
+// a grant. CASCADE also revokes what the principal granted to others.
+func (m *client) revokePermission(ctx context.Context, statement string, grant GrantPermission) error {
+	conn, release, err := m.getConnForDatabase(grant.Database)
 	if err != nil {
 		return err
 	}
+	defer release()
 
 	perm, err := normalizeDatabasePermission(grant.Permission)
 	if err != nil {
@@ -777,23 +1097,18 @@ func (m *client) RevokePermission(ctx context.Context, grant GrantPermission) er
 	var query string
 	var args []any
 	if hasObjectType {
-		securableClass, err := normalizeObjectType(grant.ObjectType)
+		class, objSchema, objName, err := grantSecurable(grant)
 		if err != nil {
 			return err
 		}
-		objSchema, objName := splitSchemaObject(grant.ObjectName)
-		if objSchema != "" {
-			if err := validateIdentifier("object schema", objSchema); err != nil {
-				return err
-			}
-		}
-		if err := validateIdentifier("object name", objName); err != nil {
+		columns, err := columnList(grant.Columns, &args)
+		if err != nil {
 			return err
 		}
 
 		var cmdBuilder strings.Builder
 		cmdBuilder.WriteString("DECLARE @sql NVARCHAR(max);\n")
-		cmdBuilder.WriteString("SET @sql = 'REVOKE ' + @permission + ' ON ' + @class + '::' + ")
+		cmdBuilder.WriteString("SET @sql = '" + statement + "' + @permission" + columns + " + ' ON ' + @class + '::' + ")
 		if objSchema != "" {
 			cmdBuilder.WriteString("QUOTENAME(@object_schema) + '.' + QUOTENAME(@object_name)")
 			args = append(args, sql.Named("object_schema", objSchema))
@@ -804,22 +1119,25 @@ func (m *client) RevokePermission(ctx context.Context, grant GrantPermission) er
 		cmdBuilder.WriteString("\nEXEC (@sql);")
 		args = append(args,
 			sql.Named("permission", grant.Permission),
-			sql.Named("class", securableClass),
+			sql.Named("class", class.name),
 			sql.Named("object_name", objName),
 			sql.Named("principal", grant.Principal),
 		)
 		query = cmdBuilder.String()
 	} else {
-		query = "DECLARE @sql NVARCHAR(max);\nSET @sql = 'REVOKE ' + @permission + ' FROM ' + QUOTENAME(@principal) + ' CASCADE';\nEXEC (@sql);"
+		if len(grant.Columns) > 0 {
+			return errColumnsWithoutObject
+		}
+		query = "DECLARE @sql NVARCHAR(max);\nSET @sql = '" + statement + "' + @permission + ' FROM ' + QUOTENAME(@principal) + ' CASCADE';\nEXEC (@sql);"
 		args = append(args,
 			sql.Named("permission", grant.Permission),
 			sql.Named("principal", grant.Principal),
 		)
 	}
 
-	tflog.Debug(ctx, fmt.Sprintf("Revoking permission: %s", query))
+	tflog.Debug(ctx, fmt.Sprintf("Running %s: %s", strings.TrimSpace(statement), query))
 
-	_, err = conn.ExecContext(ctx, query, args...)
+	_, err = m.exec(ctx, conn, idempotent, query, args...)
 	return err
 }
 
@@ -877,18 +1195,21 @@ func validateLoginSid(sid string) error {
 	return nil
 }
 
-// normalizeObjectType converts user-friendly object types to SQL Server securable class names.
-// Valid inputs: SCHEMA, OBJECT, TABLE, VIEW, PROCEDURE, FUNCTION.
-// SQL Server only recognizes SCHEMA and OBJECT as securable classes for ON clause.
-func normalizeObjectType(objectType string) (string, error) {
-	switch strings.ToUpper(strings.TrimSpace(objectType)) {
-	case "SCHEMA":
-		return "SCHEMA", nil
-	case "OBJECT", "TABLE", "VIEW", "PROCEDURE", "FUNCTION", "PROC":
-		return "OBJECT", nil
-	default:
-		return "", fmt.Errorf("object_type must be one of SCHEMA, OBJECT, TABLE, VIEW, PROCEDURE, FUNCTION (or PROC); got %q", objectType)
+// grantSecurable returns the class, schema and name of the securable of a
+// grant on a securable, validating that columns are only set on objects.
+func grantSecurable(grant GrantPermission) (securableClass, string, string, error) {
+	class, err := lookupSecurableClass(grant.ObjectType)
+	if err != nil {
+		return class, "", "", err
 	}
+	if len(grant.Columns) > 0 && class.name != objectClass.name {
+		return class, "", "", errColumnsWithoutObject
+	}
+	objSchema, objName := class.splitName(grant.ObjectName)
+	if err := class.validateName(objSchema, objName); err != nil {
+		return class, "", "", err
+	}
+	return class, objSchema, objName, nil
 }
 
 // splitSchemaObject splits "schema.object" into schema + object.
@@ -915,27 +1236,28 @@ func (m *client) GetRole(ctx context.Context, database string, name string) (Rol
 		Name: name,
 	}
 
-	conn, err := m.getConnForDatabase(database)
+	conn, release, err := m.getConnForDatabase(database)
 	if err != nil {
 		return role, err
 	}
+	defer release()
 
 	cmd := `SELECT [name] FROM sys.database_principals WHERE [type] = 'R' AND [name] = @name`
 	tflog.Debug(ctx, fmt.Sprintf("Executing refresh query for role %s: command %s", name, cmd))
-	result := conn.QueryRowContext(ctx, cmd, sql.Named("name", name))
-	err = result.Scan(&role.Id)
+	err = m.queryRow(ctx, conn, cmd, []any{sql.Named("name", name)}, &role.Id)
 	return role, err
 }
 
 func (m *client) CreateRole(ctx context.Context, database string, name string) (Role, error) {
 	var role Role
-	conn, err := m.getConnForDatabase(database)
+	conn, release, err := m.getConnForDatabase(database)
 	if err != nil {
 		return role, err
 	}
+	defer release()
 
 	query := fmt.Sprintf("CREATE ROLE [%s]", name)
-	_, _ = conn.ExecContext(ctx, query)
+	_, _ = m.exec(ctx, conn, nonIdempotent, query)
 
 	role, err = m.GetRole(ctx, database, name)
 	return role, err
@@ -949,24 +1271,120 @@ func (m *client) UpdateRole(ctx context.Context, database string, role Role) (Ro
 }
 
 func (m *client) DeleteRole(ctx context.Context, database string, name string) error {
-	conn, err := m.getConnForDatabase(database)
+	conn, release, err := m.getConnForDatabase(database)
 	if err != nil {
 		return err
 	}
+	defer release()
 
 	query := fmt.Sprintf("DROP ROLE %s", name)
 	tflog.Debug(ctx, fmt.Sprintf("Deleting Role %s: cmd: %s", name, query))
-	_, err = conn.ExecContext(ctx, query)
+	_, err = m.exec(ctx, conn, nonIdempotent, query)
 
 	return err
 }
 
+// GetDatabasePrincipalName returns the name of the database principal with
+// the given principal_id.
+func (m *client) GetDatabasePrincipalName(ctx context.Context, database string, id int64) (string, error) {
+	conn, release, err := m.getConnForDatabase(database)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	var name string
+	cmd := `SELECT [name] FROM sys.database_principals WHERE [principal_id] = @id`
+	tflog.Debug(ctx, fmt.Sprintf("Executing lookup query for database principal %d: command %s", id, cmd))
+	err = m.queryRow(ctx, conn, cmd, []any{sql.Named("id", id)}, &name)
+	return name, err
+}
+
+// GetSchemaName returns the name of the schema with the given schema_id.
+func (m *client) GetSchemaName(ctx context.Context, database string, id int64) (string, error) {
+	conn, release, err := m.getConnForDatabase(database)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	var name string
+	cmd := `SELECT [name] FROM sys.schemas WHERE [schema_id] = @id`
+	tflog.Debug(ctx, fmt.Sprintf("Executing lookup query for schema %d: command %s", id, cmd))
+	err = m.queryRow(ctx, conn, cmd, []any{sql.Named("id", id)}, &name)
+	return name, err
+}
+
+// GetServerPrincipalName returns the name of the login or server role with
+// the given principal_id.
+func (m *client) GetServerPrincipalName(ctx context.Context, id int64) (string, error) {
+	var name string
+	cmd := `SELECT [name] FROM sys.server_principals WHERE [principal_id] = @id`
+	tflog.Debug(ctx, fmt.Sprintf("Executing lookup query for server principal %d: command %s", id, cmd))
+	err := m.queryRow(ctx, m.conn, cmd, []any{sql.Named("id", id)}, &name)
+	return name, err
+}
+
+// GetDatabasePrincipalId returns the principal_id of the user or role with
+// the given name.
+func (m *client) GetDatabasePrincipalId(ctx context.Context, database string, name string) (int64, error) {
+	conn, release, err := m.getConnForDatabase(database)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	var id int64
+	cmd := `SELECT [principal_id] FROM sys.database_principals WHERE [name] = @name`
+	tflog.Debug(ctx, fmt.Sprintf("Executing lookup query for database principal %s: command %s", name, cmd))
+	err = m.queryRow(ctx, conn, cmd, []any{sql.Named("name", name)}, &id)
+	return id, err
+}
+
+// GetServerPrincipalId returns the principal_id of the login or server role
+// with the given name.
+func (m *client) GetServerPrincipalId(ctx context.Context, name string) (int64, error) {
+	var id int64
+	cmd := `SELECT [principal_id] FROM sys.server_principals WHERE [name] = @name`
+	tflog.Debug(ctx, fmt.Sprintf("Executing lookup query for server principal %s: command %s", name, cmd))
+	err := m.queryRow(ctx, m.conn, cmd, []any{sql.Named("name", name)}, &id)
+	return id, err
+}
+
+// GetSecurableId returns the id of the securable of the object_type and
+// object_name of a grant, such as the schema_id of a schema or the object_id
+// of a table. Unqualified names resolve like they do in GRANT.
+func (m *client) GetSecurableId(ctx context.Context, database string, objectType string, objectName string) (int64, error) {
+	class, err := lookupSecurableClass(objectType)
+	if err != nil {
+		return 0, err
+	}
+	conn, release, err := m.getConnForDatabase(database)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	objSchema, objName := class.splitName(objectName)
+	cmd := class.idQuery()
+	args := []any{sql.Named("schema", objSchema), sql.Named("name", objName)}
+
+	var id sql.NullInt64
+	tflog.Debug(ctx, fmt.Sprintf("Executing lookup query for %s %s: command %s", class.name, objectName, cmd))
+	if err := m.queryRow(ctx, conn, cmd, args, &id); err != nil {
+		return 0, err
+	}
+	if !id.Valid {
+		return 0, sql.ErrNoRows
+	}
+	return id.Int64, nil
+}
+
 func (m *client) GetDatabase(ctx context.Context, name string) (Database, error) {
 	var db Database
 	cmd := `SELECT [name], [database_id] FROM sys.databases WHERE [name] = @name`
 	tflog.Debug(ctx, fmt.Sprintf("Executing refresh query for database %s: command %s", name, cmd))
-	result := m.conn.QueryRowContext(ctx, cmd, sql.Named("name", name))
-	err := result.Scan(&db.Name, &db.Id)
+	err := m.queryRow(ctx, m.conn, cmd, []any{sql.Named("name", name)}, &db.Name, &db.Id)
 	return db, err
 }
 
@@ -974,15 +1392,14 @@ func (m *client) GetDatabaseById(ctx context.Context, id int64) (Database, error
 	var db Database
 	cmd := `SELECT [name], [database_id] FROM sys.databases WHERE [database_id] = @id`
 	tflog.Debug(ctx, fmt.Sprintf("Executing refresh query for database %d: command %s", id, cmd))
-	result := m.conn.QueryRowContext(ctx, cmd, sql.Named("id", id))
-	err := result.Scan(&db.Name, &db.Id)
+	err := m.queryRow(ctx, m.conn, cmd, []any{sql.Named("id", id)}, &db.Name, &db.Id)
 	return db, err
 }
 
 func (m *client) CreateDatabase(ctx context.Context, name string) (Database, error) {
 	var db Database
 	query := fmt.Sprintf("CREATE DATABASE [%s]", name)
-	_, err := m.conn.ExecContext(ctx, query)
+	_, err := m.exec(ctx, m.conn, nonIdempotent, query)
 	if err != nil {
 		return db, fmt.Errorf("failed to create database: %v", err)
 	}
@@ -991,10 +1408,14 @@ func (m *client) CreateDatabase(ctx context.Context, name string) (Database, err
 }
 
 func (m *client) ExecScript(ctx context.Context, database string, script string) error {
-	conn, err := m.getConnForDatabase(database)
+	conn, release, err := m.getConnForDatabase(database)
 	if err != nil {
 		return err
 	}
+	defer release()
+
+	// A script may change the catalog of any database.
+	defer m.catalogCache.invalidate("")
 
 	batches := splitBatches(script)
 	tflog.Debug(ctx, fmt.Sprintf("Executing script in database %s (%d batches, total %d chars)", database, len(batches), len(script)))
@@ -1005,7 +1426,7 @@ func (m *client) ExecScript(ctx context.Context, database string, script string)
 			continue
 		}
 		tflog.Debug(ctx, fmt.Sprintf("Executing batch %d/%d", i+1, len(batches)))
-		if _, err := conn.ExecContext(ctx, batch); err != nil {
+		if _, err := m.exec(ctx, conn, nonIdempotent, batch); err != nil {
 			return fmt.Errorf("failed to execute batch %d: %v", i+1, err)
 		}
 	}
@@ -1057,9 +1478,8 @@ func (m *client) GetLogin(ctx context.Context, name string) (Login, error) {
 	WHERE p.[name] = @name AND p.[type] IN ('S', 'U', 'G')`
 
 	tflog.Debug(ctx, fmt.Sprintf("Executing query for login %s: %s", name, cmd))
-	result := m.conn.QueryRowContext(ctx, cmd, sql.Named("name", name))
-
-	err := result.Scan(&login.Name, &login.DefaultDatabase, &login.DefaultLanguage, &login.IsDisabled, &login.Sid)
+	err := m.queryRow(ctx, m.conn, cmd, []any{sql.Named("name", name)},
+		&login.Name, &login.DefaultDatabase, &login.DefaultLanguage, &login.IsDisabled, &login.Sid)
 	if err != nil {
 		return login, err
 	}
@@ -1123,7 +1543,7 @@ func (m *client) CreateLogin(ctx context.Context, create CreateLogin) (Login, er
 	cmd := cmdBuilder.String()
 	tflog.Debug(ctx, fmt.Sprintf("Creating login %s: %s", create.Name, cmd))
 
-	_, err := m.conn.ExecContext(ctx, cmd, args...)
+	_, err := m.exec(ctx, m.conn, nonIdempotent, cmd, args...)
 	if err != nil {
 		return login, fmt.Errorf("failed to create login: %v", err)
 	}
@@ -1188,7 +1608,7 @@ func (m *client) UpdateLogin(ctx context.Context, update UpdateLogin) (Login, er
 		cmd := cmdBuilder.String()
 		tflog.Debug(ctx, fmt.Sprintf("Updating login %s: %s", update.Name, cmd))
 
-		if _, err := m.conn.ExecContext(ctx, cmd, args...); err != nil {
+		if _, err := m.exec(ctx, m.conn, idempotent, cmd, args...); err != nil {
 			return Login{}, fmt.Errorf("failed to update login: %v", err)
 		}
 	}
@@ -1206,7 +1626,7 @@ SET @sql = 'IF EXISTS (SELECT 1 FROM sys.server_principals WHERE [name] = ' + QU
 EXEC (@sql);`
 
 	tflog.Debug(ctx, fmt.Sprintf("Deleting login %s: %s", name, cmd))
-	_, err := m.conn.ExecContext(ctx, cmd, sql.Named("name", name))
+	_, err := m.exec(ctx, m.conn, idempotent, cmd, sql.Named("name", name))
 	return err
 }
 
@@ -1231,8 +1651,6 @@ func (m *client) GetDatabaseOptions(ctx context.Context, name string) (DatabaseO
 	WHERE d.[name] = @name`
 
 	tflog.Debug(ctx, fmt.Sprintf("Getting database options for %s", name))
-	result := m.conn.QueryRowContext(ctx, cmd, sql.Named("name", name))
-
 	var (
 		compLevel            int
 		recModel             string
@@ -1246,7 +1664,7 @@ func (m *client) GetDatabaseOptions(ctx context.Context, name string) (DatabaseO
 		autoUpdateStatsAsync bool
 	)
 
-	err := result.Scan(
+	err := m.queryRow(ctx, m.conn, cmd, []any{sql.Named("name", name)},
 		&opts.Collation,
 		&compLevel,
 		&recModel,
@@ -1309,7 +1727,7 @@ func (m *client) execAlterDatabase(ctx context.Context, database string, clause
 SET @sql = N'ALTER DATABASE ' + QUOTENAME(@db) + N' ` + clause + `';
 EXEC (@sql);`
 
-	_, err := m.conn.ExecContext(ctx, cmd, sql.Named("db", database))
+	_, err := m.exec(ctx, m.conn, idempotent, cmd, sql.Named("db", database))
 	return err
 }
 
@@ -1428,10 +1846,11 @@ func (m *client) SetDatabaseOptions(ctx context.Context, name string, opts Datab
 func (m *client) GetDatabaseScopedConfigurations(ctx context.Context, name string) ([]DatabaseScopedConfiguration, error) {
 	var configs []DatabaseScopedConfiguration
 
-	conn, err := m.getConnForDatabase(name)
+	conn, release, err := m.getConnForDatabase(name)
 	if err != nil {
 		return configs, err
 	}
+	defer release()
 
 	cmd := `SELECT
 		[name],
@@ -1447,25 +1866,23 @@ func (m *client) GetDatabaseScopedConfigurations(ctx context.Context, name strin
 	WHERE [value] IS NOT NULL`
 
 	tflog.Debug(ctx, fmt.Sprintf("Getting database scoped configurations for %s", name))
-	rows, err := conn.QueryContext(ctx, cmd)
-	if err != nil {
-		return configs, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var cfg DatabaseScopedConfiguration
-		var valueForSecondary sql.NullString
-		if err := rows.Scan(&cfg.Name, &cfg.Value, &valueForSecondary); err != nil {
-			return configs, err
-		}
-		if valueForSecondary.Valid {
-			cfg.ValueForSecondary = valueForSecondary.String
+	err = m.query(ctx, conn, cmd, nil, func(rows *sql.Rows) error {
+		configs = nil
+		for rows.Next() {
+			var cfg DatabaseScopedConfiguration
+			var valueForSecondary sql.NullString
+			if err := rows.Scan(&cfg.Name, &cfg.Value, &valueForSecondary); err != nil {
+				return err
+			}
+			if valueForSecondary.Valid {
+				cfg.ValueForSecondary = valueForSecondary.String
+			}
+			configs = append(configs, cfg)
 		}
-		configs = append(configs, cfg)
-	}
+		return nil
+	})
 
-	return configs, rows.Err()
+	return configs, err
 }
 
 func (m *client) SetDatabaseScopedConfiguration(ctx context.Context, name string, cfg DatabaseScopedConfiguration) error {
@@ -1484,21 +1901,22 @@ func (m *client) SetDatabaseScopedConfiguration(ctx context.Context, name string
 		}
 	}
 
-	conn, err := m.getConnForDatabase(name)
+	conn, release, err := m.getConnForDatabase(name)
 	if err != nil {
 		return err
 	}
+	defer release()
 
 	cmd := fmt.Sprintf("ALTER DATABASE SCOPED CONFIGURATION SET %s = %s", cfg.Name, cfg.Value)
 	tflog.Debug(ctx, fmt.Sprintf("Setting database scoped configuration: %s", cmd))
-	if _, err = conn.ExecContext(ctx, cmd); err != nil {
+	if _, err = m.exec(ctx, conn, idempotent, cmd); err != nil {
 		return err
 	}
 
 	if cfg.ValueForSecondary != "" {
 		cmd = fmt.Sprintf("ALTER DATABASE SCOPED CONFIGURATION FOR SECONDARY SET %s = %s", cfg.Name, cfg.ValueForSecondary)
 		tflog.Debug(ctx, fmt.Sprintf("Setting database scoped configuration for secondary: %s", cmd))
-		if _, err = conn.ExecContext(ctx, cmd); err != nil {
+		if _, err = m.exec(ctx, conn, idempotent, cmd); err != nil {
 			return err
 		}
 	}
@@ -1514,13 +1932,14 @@ func (m *client) ClearDatabaseScopedConfiguration(ctx context.Context, name stri
 		return err
 	}
 
-	conn, err := m.getConnForDatabase(name)
+	conn, release, err := m.getConnForDatabase(name)
 	if err != nil {
 		return err
 	}
+	defer release()
 
 	cmd := fmt.Sprintf("ALTER DATABASE SCOPED CONFIGURATION CLEAR %s", configName)
 	tflog.Debug(ctx, fmt.Sprintf("Clearing database scoped configuration: %s", cmd))
-	_, err = conn.ExecContext(ctx, cmd)
+	_, err = m.exec(ctx, conn, idempotent, cmd)
 	return err
 }
@@ -134,7 +134,16 @@ func Test_CreateDatabase(t *testing.T) {
 	}
 
 	// Use 127.0.0.1 instead of localhost to avoid IPv6 ::1 resolution issues on some systems.
-	c, ok := NewClient("127.0.0.1", 1433, "master", "sa", password).(*client)
+	sqlClient, err := NewClient(Config{
+		Host:     "127.0.0.1",
+		Port:     1433,
+		Database: "master",
+		SqlAuth:  &SqlAuth{Username: "sa", Password: password},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	c, ok := sqlClient.(*client)
 	if !ok {
 		t.Fatalf("expected *client from NewClient")
 	}
@@ -269,6 +278,190 @@ func Test_validatePermission(t *testing.T) {
 	}
 }
 
+func Test_columnList(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		want    string
+		wantErr bool
+	}{
+		{name: "none", columns: nil, want: ""},
+		{name: "one", columns: []string{"id"}, want: " + ' (' + QUOTENAME(@column_0) + ')'"},
+		{name: "two", columns: []string{"id", " region "}, want: " + ' (' + QUOTENAME(@column_0) + ', ' + QUOTENAME(@column_1) + ')'"},
+		{name: "invalid char", columns: []string{"id]; DROP TABLE t; --"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []any
+			got, err := columnList(tt.columns, &args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("columnList() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("columnList() = %q, want %q", got, tt.want)
+			}
+			if !tt.wantErr && len(args) != len(tt.columns) {
+				t.Fatalf("columnList() args = %v, want one per column", args)
+			}
+		})
+	}
+}
+
+func Test_grantSecurable(t *testing.T) {
+	tests := []struct {
+		name       string
+		grant      GrantPermission
+		wantClass  string
+		wantSchema string
+		wantName   string
+		wantErr    bool
+	}{
+		{name: "table", grant: GrantPermission{ObjectType: "table", ObjectName: "sales.orders"}, wantClass: "OBJECT", wantSchema: "sales", wantName: "orders"},
+		{name: "unqualified sequence", grant: GrantPermission{ObjectType: "SEQUENCE", ObjectName: "order_numbers"}, wantClass: "OBJECT", wantName: "order_numbers"},
+		{name: "type", grant: GrantPermission{ObjectType: "TYPE", ObjectName: "dbo.Money"}, wantClass: "TYPE", wantSchema: "dbo", wantName: "Money"},
+		{name: "xml schema collection", grant: GrantPermission{ObjectType: "xml  schema collection", ObjectName: "sales.OrderSchema"}, wantClass: "XML SCHEMA COLLECTION", wantSchema: "sales", wantName: "OrderSchema"},
+		{name: "certificate with a dot", grant: GrantPermission{ObjectType: "CERTIFICATE", ObjectName: "CodeSigning.v2"}, wantClass: "CERTIFICATE", wantName: "CodeSigning.v2"},
+		{name: "application role", grant: GrantPermission{ObjectType: "APPLICATION ROLE", ObjectName: "reporting"}, wantClass: "APPLICATION ROLE", wantName: "reporting"},
+		{name: "service", grant: GrantPermission{ObjectType: "SERVICE", ObjectName: "//Contoso/Orders/Service"}, wantClass: "SERVICE", wantName: "//Contoso/Orders/Service"},
+		{name: "slash outside a service", grant: GrantPermission{ObjectType: "ASSEMBLY", ObjectName: "a/b"}, wantErr: true},
+		{name: "columns on a type", grant: GrantPermission{ObjectType: "TYPE", ObjectName: "dbo.Money", Columns: []string{"id"}}, wantErr: true},
+		{name: "unknown type", grant: GrantPermission{ObjectType: "QUEUE", ObjectName: "orders"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class, schema, name, err := grantSecurable(tt.grant)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("grantSecurable() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if class.name != tt.wantClass || schema != tt.wantSchema || name != tt.wantName {
+				t.Fatalf("grantSecurable() = %s, %q, %q, want %s, %q, %q", class.name, schema, name, tt.wantClass, tt.wantSchema, tt.wantName)
+			}
+		})
+	}
+}
+
+func Test_serverSecurable(t *testing.T) {
+	tests := []struct {
+		name       string
+		permission ServerPermission
+		wantClass  int
+		wantName   string
+		wantErr    bool
+	}{
+		{name: "server", permission: ServerPermission{Permission: "VIEW SERVER STATE"}, wantClass: 100},
+		{name: "login", permission: ServerPermission{ObjectType: "login", ObjectName: "etl"}, wantClass: 101, wantName: "etl"},
+		{name: "server role", permission: ServerPermission{ObjectType: "SERVER  ROLE", ObjectName: "monitoring"}, wantClass: 101, wantName: "monitoring"},
+		{name: "endpoint", permission: ServerPermission{ObjectType: "ENDPOINT", ObjectName: "Hadr_endpoint"}, wantClass: 105, wantName: "Hadr_endpoint"},
+		{name: "name without type", permission: ServerPermission{ObjectName: "etl"}, wantErr: true},
+		{name: "database securable", permission: ServerPermission{ObjectType: "SCHEMA", ObjectName: "dbo"}, wantErr: true},
+		{name: "invalid name", permission: ServerPermission{ObjectType: "LOGIN", ObjectName: "etl]; DROP LOGIN sa; --"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class, name, err := serverSecurable(tt.permission)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("serverSecurable() err=%v wantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if class.class != tt.wantClass || name != tt.wantName {
+				t.Fatalf("serverSecurable() = %d, %q, want %d, %q", class.class, name, tt.wantClass, tt.wantName)
+			}
+		})
+	}
+}
+
+func Test_ReadServerPermission(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	c := &client{conn: db}
+	mock.ExpectQuery("FROM\\s+sys.server_permissions").
+		WithArgs(
+			sql.Named("class", 101),
+			sql.Named("principal", "dba"),
+			sql.Named("permission", "IMPERSONATE"),
+			sql.Named("object_name", "etl"),
+		).
+		WillReturnRows(sqlmock.NewRows([]string{"principal", "permission", "object_name", "state"}).
+			AddRow("dba", "IMPERSONATE", "ETL", "W"))
+
+	got, err := c.ReadServerPermission(context.Background(), ServerPermission{Principal: "dba", Permission: "IMPERSONATE", ObjectType: "LOGIN", ObjectName: "etl"})
+	if err != nil {
+		t.Fatalf("ReadServerPermission() error = %v", err)
+	}
+	if got.State != "GRANT" || !got.WithGrantOption || got.ObjectType != "LOGIN" || got.ObjectName != "ETL" {
+		t.Fatalf("ReadServerPermission() = %+v, want IMPERSONATE on LOGIN ETL with the grant option", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+// Test_GrantServerPermission_RunsInMaster checks that server permissions
+// are granted and revoked through master when the provider's database is
+// another one.
+func Test_GrantServerPermission_RunsInMaster(t *testing.T) {
+	app, appMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer app.Close()
+	master, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer master.Close()
+
+	var opened []string
+	c := &client{conn: app, database: "app"}
+	c.open = func(database string) (*sql.DB, error) {
+		opened = append(opened, database)
+		return master, nil
+	}
+
+	mock.ExpectExec("' ON ' \\+ @class").
+		WithArgs(
+			sql.Named("permission", "CONNECT"),
+			sql.Named("principal", "etl"),
+			sql.Named("class", "ENDPOINT"),
+			sql.Named("object_name", "TSQL Default TCP"),
+		).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("REVOKE").
+		WithArgs(sql.Named("permission", "VIEW SERVER STATE"), sql.Named("principal", "etl")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ctx := context.Background()
+	if _, err := c.GrantServerPermission(ctx, ServerPermission{Principal: "etl", Permission: "connect", ObjectType: "endpoint", ObjectName: "TSQL Default TCP", State: "GRANT"}); err != nil {
+		t.Fatalf("GrantServerPermission() error = %v", err)
+	}
+	if err := c.RevokeServerPermission(ctx, ServerPermission{Principal: "etl", Permission: "VIEW SERVER STATE"}); err != nil {
+		t.Fatalf("RevokeServerPermission() error = %v", err)
+	}
+
+	if len(opened) != 1 || opened[0] != "master" {
+		t.Fatalf("opened databases = %v, want master", opened)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet sqlmock expectations in master: %v", err)
+	}
+	if err := appMock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet sqlmock expectations in app: %v", err)
+	}
+}
+
 func Test_validateLoginSid(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -341,3 +534,32 @@ func Test_CreateLogin_WithSid(t *testing.T) {
 		t.Fatalf("unmet sqlmock expectations: %v", err)
 	}
 }
+
+func Test_NameLookups(t *testing.T) {
+	c, mock := newRetryTestClient(t)
+
+	mock.ExpectQuery("FROM sys.database_principals WHERE \\[principal_id\\] = @id").
+		WithArgs(sql.Named("id", int64(5))).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("app_user"))
+	mock.ExpectQuery("FROM sys.schemas WHERE \\[schema_id\\] = @id").
+		WithArgs(sql.Named("id", int64(6))).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("sales"))
+	mock.ExpectQuery("FROM sys.server_principals WHERE \\[principal_id\\] = @id").
+		WithArgs(sql.Named("id", int64(7))).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+	ctx := context.Background()
+	if name, err := c.GetDatabasePrincipalName(ctx, "", 5); err != nil || name != "app_user" {
+		t.Fatalf("GetDatabasePrincipalName() = %q, %v, want app_user", name, err)
+	}
+	if name, err := c.GetSchemaName(ctx, "", 6); err != nil || name != "sales" {
+		t.Fatalf("GetSchemaName() = %q, %v, want sales", name, err)
+	}
+	if _, err := c.GetServerPrincipalName(ctx, 7); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetServerPrincipalName() of a missing id error = %v, want sql.ErrNoRows", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet sqlmock expectations: %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHTunnel configures an SSH bastion through which every connection to SQL
+// Server is dialed. Host names are resolved by the bastion, so servers only
+// resolvable inside the private network can be reached.
+type SSHTunnel struct {
+	Host string
+	// Port defaults to 22.
+	Port int64
+	User string
+
+	// PrivateKey is a PEM encoded private key, optionally encrypted with
+	// PrivateKeyPassphrase. UseAgent authenticates with the keys of the agent
+	// listening on SSH_AUTH_SOCK. At least one of them is required.
+	PrivateKey           string
+	PrivateKeyPassphrase string
+	UseAgent             bool
+
+	// HostKey pins the bastion's public key, in authorized_keys format.
+	// Otherwise the key is verified against KnownHostsFile, which defaults to
+	// ~/.ssh/known_hosts.
+	HostKey        string
+	KnownHostsFile string
+}
+
+func (t SSHTunnel) address() string {
+	port := t.Port
+	if port <= 0 {
+		port = 22
+	}
+	return net.JoinHostPort(t.Host, strconv.FormatInt(port, 10))
+}
+
+// Validate checks that the tunnel settings are complete.
+func (t SSHTunnel) Validate() error {
+	if t.Host == "" || t.User == "" {
+		return errors.New("ssh_tunnel host and user are required")
+	}
+	if t.PrivateKey == "" && !t.UseAgent {
+		return errors.New("ssh_tunnel requires private_key or use_agent")
+	}
+	if t.HostKey != "" && t.KnownHostsFile != "" {
+		return errors.New("ssh_tunnel host_key and known_hosts_file are mutually exclusive")
+	}
+	return nil
+}
+
+// clientConfig builds the SSH client configuration. The returned cleanup
+// releases the agent connection, if any, once the SSH client is closed.
+func (t SSHTunnel) clientConfig() (*ssh.ClientConfig, func(), error) {
+	hostKeyCallback, err := t.hostKeyCallback()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cleanup := func() {}
+	var methods []ssh.AuthMethod
+	if t.PrivateKey != "" {
+		var signer ssh.Signer
+		if t.PrivateKeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(t.PrivateKey), []byte(t.PrivateKeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(t.PrivateKey))
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid ssh_tunnel private_key: %v", err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	if t.UseAgent {
+		socket := os.Getenv("SSH_AUTH_SOCK")
+		if socket == "" {
+			return nil, nil, errors.New("ssh_tunnel use_agent is set but SSH_AUTH_SOCK is not")
+		}
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to the SSH agent: %v", err)
+		}
+		cleanup = func() { conn.Close() }
+		methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+	}
+
+	return &ssh.ClientConfig{
+		User:            t.User,
+		Auth:            methods,
+		HostKeyCallback: hostKeyCallback,
+	}, cleanup, nil
+}
+
+func (t SSHTunnel) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if t.HostKey != "" {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(t.HostKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ssh_tunnel host_key: %v", err)
+		}
+		return ssh.FixedHostKey(key), nil
+	}
+
+	path := t.KnownHostsFile
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("ssh_tunnel known_hosts_file is not set and the home directory is unknown: %v", err)
+		}
+		path = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	callback, err := knownhosts.New(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ssh_tunnel known hosts: %v", err)
+	}
+	return callback, nil
+}
+
+// sshTunnelError reports a failure to establish the SSH session itself, as
+// opposed to the bastion failing to reach SQL Server.
+type sshTunnelError struct {
+	addr string
+	err  error
+}
+
+func (e *sshTunnelError) Error() string {
+	return fmt.Sprintf("ssh tunnel via %s: %v", e.addr, e.err)
+}
+
+func (e *sshTunnelError) Unwrap() error {
+	return e.err
+}
+
+// sshDialer dials SQL Server connections through a shared SSH client. The
+// client is connected on first use and reconnected if the bastion drops it.
+type sshDialer struct {
+	tunnel SSHTunnel
+
+	mu      sync.Mutex
+	client  *ssh.Client
+	cleanup func()
+}
+
+func newSSHDialer(tunnel SSHTunnel) *sshDialer {
+	return &sshDialer{tunnel: tunnel}
+}
+
+// HostName implements mssqldb.HostDialer, so that the driver leaves name
+// resolution to the bastion.
+func (d *sshDialer) HostName() string {
+	return d.tunnel.Host
+}
+
+func (d *sshDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := d.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.DialContext(ctx, network, addr)
+	var channelErr *ssh.OpenChannelError
+	if err == nil || errors.As(err, &channelErr) || ctx.Err() != nil {
+		// A rejected channel means the bastion could not reach addr; the
+		// session itself is fine.
+		return conn, err
+	}
+
+	// The bastion may have closed an idle session; reconnect once. If that
+	// fails too, the tunnel is down and its error is the one to report.
+	d.reset(client)
+	client, err = d.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.DialContext(ctx, network, addr)
+}
+
+func (d *sshDialer) connect(ctx context.Context) (*ssh.Client, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.client != nil {
+		return d.client, nil
+	}
+
+	addr := d.tunnel.address()
+	config, cleanup, err := d.tunnel.clientConfig()
+	if err != nil {
+		return nil, &sshTunnelError{addr: addr, err: err}
+	}
+	var nd net.Dialer
+	conn, err := nd.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		cleanup()
+		return nil, &sshTunnelError{addr: addr, err: err}
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		cleanup()
+		return nil, &sshTunnelError{addr: addr, err: err}
+	}
+
+	d.client = ssh.NewClient(c, chans, reqs)
+	d.cleanup = cleanup
+	return d.client, nil
+}
+
+// reset drops client if it is still the current one.
+func (d *sshDialer) reset(client *ssh.Client) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.client == client {
+		d.closeLocked()
+	}
+}
+
+func (d *sshDialer) closeLocked() error {
+	if d.client == nil {
+		return nil
+	}
+	err := d.client.Close()
+	d.cleanup()
+	d.client, d.cleanup = nil, nil
+	return err
+}
+
+// Close closes the SSH session. Tunneled connections still open are closed
+// with it.
+func (d *sshDialer) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.closeLocked()
+}
@@ -0,0 +1,374 @@
+package mssql
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testSSHServer is an in-process SSH server that only supports direct-tcpip
+// channels, like a locked-down bastion.
+type testSSHServer struct {
+	addr     string
+	hostKey  ssh.PublicKey
+	sessions atomic.Int32
+
+	listener net.Listener
+	mu       sync.Mutex
+	conns    []net.Conn
+}
+
+func newTestSSHServer(t *testing.T, clientKey ssh.PublicKey) *testSSHServer {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate host key: %v", err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatalf("host signer: %v", err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &testSSHServer{addr: l.Addr().String(), hostKey: hostSigner.PublicKey(), listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn, config)
+		}
+	}()
+	return s
+}
+
+// stop takes the bastion down: it drops every session and refuses new ones.
+func (s *testSSHServer) stop() {
+	s.listener.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	s.sessions.Add(1)
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "only direct-tcpip is supported")
+			continue
+		}
+		var target struct {
+			Host     string
+			Port     uint32
+			OrigHost string
+			OrigPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+			newChannel.Reject(ssh.Prohibited, "invalid payload")
+			continue
+		}
+		upstream, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, channelReqs, err := newChannel.Accept()
+		if err != nil {
+			upstream.Close()
+			continue
+		}
+		go ssh.DiscardRequests(channelReqs)
+		go func() {
+			io.Copy(channel, upstream)
+			channel.Close()
+		}()
+		go func() {
+			io.Copy(upstream, channel)
+			upstream.Close()
+		}()
+	}
+}
+
+// newEchoServer stands in for SQL Server behind the bastion.
+func newEchoServer(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func newClientKey(t *testing.T) (string, ssh.PublicKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate client key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatalf("marshal client key: %v", err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("client public key: %v", err)
+	}
+	return string(pem.EncodeToMemory(block)), sshPub
+}
+
+func testTunnel(server *testSSHServer, privateKey string) SSHTunnel {
+	host, port, _ := net.SplitHostPort(server.addr)
+	p, _ := strconv.ParseInt(port, 10, 64)
+	return SSHTunnel{
+		Host:       host,
+		Port:       p,
+		User:       "terraform",
+		PrivateKey: privateKey,
+		HostKey:    string(ssh.MarshalAuthorizedKey(server.hostKey)),
+	}
+}
+
+func assertEcho(t *testing.T, conn net.Conn) {
+	t.Helper()
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(buf) != "ping" {
+		t.Fatalf("read %q, want ping", buf)
+	}
+}
+
+func Test_sshDialer_Tunnels(t *testing.T) {
+	privateKey, publicKey := newClientKey(t)
+	server := newTestSSHServer(t, publicKey)
+	target := newEchoServer(t)
+
+	d := newSSHDialer(testTunnel(server, privateKey))
+	t.Cleanup(func() { d.Close() })
+
+	for i := 0; i < 2; i++ {
+		conn, err := d.DialContext(context.Background(), "tcp", target)
+		if err != nil {
+			t.Fatalf("DialContext() error = %v", err)
+		}
+		assertEcho(t, conn)
+	}
+	if n := server.sessions.Load(); n != 1 {
+		t.Fatalf("sessions = %d, want connections to share one SSH session", n)
+	}
+}
+
+func Test_sshDialer_KnownHostsFile(t *testing.T) {
+	privateKey, publicKey := newClientKey(t)
+	server := newTestSSHServer(t, publicKey)
+	target := newEchoServer(t)
+
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(server.addr)}, server.hostKey)
+	if err := os.WriteFile(knownHosts, []byte(line+"\n"), 0o600); err != nil {
+		t.Fatalf("write known_hosts: %v", err)
+	}
+
+	tunnel := testTunnel(server, privateKey)
+	tunnel.HostKey = ""
+	tunnel.KnownHostsFile = knownHosts
+	d := newSSHDialer(tunnel)
+	t.Cleanup(func() { d.Close() })
+
+	conn, err := d.DialContext(context.Background(), "tcp", target)
+	if err != nil {
+		t.Fatalf("DialContext() error = %v", err)
+	}
+	assertEcho(t, conn)
+}
+
+func Test_sshDialer_RejectsUnknownHostKey(t *testing.T) {
+	privateKey, publicKey := newClientKey(t)
+	server := newTestSSHServer(t, publicKey)
+	_, otherKey := newClientKey(t)
+
+	tunnel := testTunnel(server, privateKey)
+	tunnel.HostKey = string(ssh.MarshalAuthorizedKey(otherKey))
+	d := newSSHDialer(tunnel)
+
+	_, err := d.DialContext(context.Background(), "tcp", newEchoServer(t))
+	if err == nil {
+		t.Fatalf("expected host key mismatch")
+	}
+	if got := newConnectError(Config{Host: "db.internal", Port: 1433}, err); got.Summary != "SSH Tunnel Failed" {
+		t.Fatalf("Summary = %q, want SSH Tunnel Failed", got.Summary)
+	}
+}
+
+func Test_sshDialer_UnreachableTarget(t *testing.T) {
+	privateKey, publicKey := newClientKey(t)
+	server := newTestSSHServer(t, publicKey)
+
+	// Grab a free port, then close it so nothing listens there.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	target := l.Addr().String()
+	l.Close()
+
+	d := newSSHDialer(testTunnel(server, privateKey))
+	t.Cleanup(func() { d.Close() })
+
+	_, err = d.DialContext(context.Background(), "tcp", target)
+	if err == nil {
+		t.Fatalf("expected error dialing a closed port")
+	}
+	if got := newConnectError(Config{Host: "db.internal", Port: 1433}, err); got.Summary != "Sql Server Unreachable" {
+		t.Fatalf("Summary = %q, want Sql Server Unreachable", got.Summary)
+	}
+	if n := server.sessions.Load(); n != 1 {
+		t.Fatalf("sessions = %d, a rejected channel should not reconnect", n)
+	}
+}
+
+func Test_sshDialer_BastionDown(t *testing.T) {
+	privateKey, publicKey := newClientKey(t)
+	server := newTestSSHServer(t, publicKey)
+	target := newEchoServer(t)
+
+	d := newSSHDialer(testTunnel(server, privateKey))
+	t.Cleanup(func() { d.Close() })
+
+	conn, err := d.DialContext(context.Background(), "tcp", target)
+	if err != nil {
+		t.Fatalf("DialContext() error = %v", err)
+	}
+	assertEcho(t, conn)
+
+	// The session drops and reconnecting fails.
+	server.stop()
+	_, err = d.DialContext(context.Background(), "tcp", target)
+	if err == nil {
+		t.Fatalf("expected error dialing through a stopped bastion")
+	}
+	if got := newConnectError(Config{Host: "db.internal", Port: 1433}, err); got.Summary != "SSH Tunnel Failed" {
+		t.Fatalf("Summary = %q, want SSH Tunnel Failed (err: %v)", got.Summary, err)
+	}
+}
+
+func Test_SSHTunnel_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tunnel  SSHTunnel
+		wantErr bool
+	}{
+		{name: "private key", tunnel: SSHTunnel{Host: "bastion", User: "u", PrivateKey: "key"}},
+		{name: "agent", tunnel: SSHTunnel{Host: "bastion", User: "u", UseAgent: true}},
+		{name: "missing host", tunnel: SSHTunnel{User: "u", UseAgent: true}, wantErr: true},
+		{name: "missing credentials", tunnel: SSHTunnel{Host: "bastion", User: "u"}, wantErr: true},
+		{name: "host key and known hosts", tunnel: SSHTunnel{Host: "bastion", User: "u", UseAgent: true, HostKey: "k", KnownHostsFile: "/f"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.tunnel.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() err=%v wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_Ping_DialsThroughTunnel(t *testing.T) {
+	privateKey, publicKey := newClientKey(t)
+	server := newTestSSHServer(t, publicKey)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	l.Close()
+	p, _ := strconv.ParseInt(port, 10, 64)
+
+	tunnel := testTunnel(server, privateKey)
+	c, err := NewClient(Config{
+		Host:      "127.0.0.1",
+		Port:      p,
+		Database:  "master",
+		SqlAuth:   &SqlAuth{Username: "sa", Password: "pw"},
+		SSHTunnel: &tunnel,
+		Retry:     &Retry{MaxAttempts: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	var connErr *ConnectError
+	if err := c.Ping(context.Background()); !errors.As(err, &connErr) || connErr.Summary != "Sql Server Unreachable" {
+		t.Fatalf("Ping() error = %v, want Sql Server Unreachable", err)
+	}
+	if n := server.sessions.Load(); n != 1 {
+		t.Fatalf("sessions = %d, want the driver to dial through the tunnel", n)
+	}
+}
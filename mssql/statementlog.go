@@ -0,0 +1,163 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	mssqldb "github.com/microsoft/go-mssqldb"
+)
+
+// redacted replaces the value of secret parameters in the statement log.
+const redacted = "[REDACTED]"
+
+// secretParameters are the bind parameters whose values are never written to
+// the statement log.
+var secretParameters = map[string]bool{
+	"password": true,
+}
+
+// statementLog appends a JSON Lines record of every statement the client
+// runs. Each record is written with a single call, so several clients may
+// share the file.
+type statementLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func openStatementLog(path string) (*statementLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &statementLog{file: f}, nil
+}
+
+// statementRecord is one line of the statement log.
+type statementRecord struct {
+	Time       time.Time      `json:"time"`
+	Resource   string         `json:"resource,omitempty"`
+	ResourceID string         `json:"resource_id,omitempty"`
+	Database   string         `json:"database"`
+	Statement  string         `json:"statement"`
+	Parameters map[string]any `json:"parameters,omitempty"`
+	DurationMs float64        `json:"duration_ms"`
+	// RowsAffected is only reported for statements that are not queries.
+	RowsAffected *int64 `json:"rows_affected,omitempty"`
+	ErrorNumber  int32  `json:"error_number,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+func (l *statementLog) write(rec statementRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.file.Write(b)
+	return err
+}
+
+func (l *statementLog) Close() error {
+	return l.file.Close()
+}
+
+type resourceKey struct{}
+
+type resourceLabel struct {
+	typeName string
+	id       string
+}
+
+// WithResource labels the statements run with ctx with the Terraform
+// resource they are run for. Terraform does not share resource addresses
+// with providers, so the resource type and ID identify it in the statement
+// log instead. The ID of a resource being created is unknown in its plan, so
+// Create passes the ID the resource will have. Statements recorded by a dry
+// run with the returned context are available from RecordedStatements.
+func WithResource(ctx context.Context, typeName string, id string) context.Context {
+	ctx, _ = withCapture(ctx)
+	return context.WithValue(ctx, resourceKey{}, resourceLabel{typeName: typeName, id: id})
+}
+
+// logParameters renders bind parameters for the statement log, redacting
+// secrets. Positional parameters are named p1, p2 and so on.
+func logParameters(args []any) map[string]any {
+	if len(args) == 0 {
+		return nil
+	}
+	params := make(map[string]any, len(args))
+	for i, arg := range args {
+		name, value := fmt.Sprintf("p%d", i+1), arg
+		if named, ok := arg.(sql.NamedArg); ok {
+			name, value = named.Name, named.Value
+		}
+		if secretParameters[strings.ToLower(name)] {
+			value = redacted
+		}
+		params[name] = value
+	}
+	return params
+}
+
+// logStatement records a statement that ran against conn. result is nil for
+// queries.
+func (m *client) logStatement(ctx context.Context, conn *sql.DB, query string, args []any, start time.Time, result sql.Result, err error) {
+	if m.statements == nil {
+		return
+	}
+
+	rec := statementRecord{
+		Time:       start.UTC(),
+		Database:   m.databaseOf(conn),
+		Statement:  query,
+		Parameters: logParameters(args),
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if label, ok := ctx.Value(resourceKey{}).(resourceLabel); ok {
+		rec.Resource, rec.ResourceID = label.typeName, label.id
+	}
+	if result != nil && err == nil {
+		if n, rowsErr := result.RowsAffected(); rowsErr == nil {
+			rec.RowsAffected = &n
+		}
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		rec.Error = err.Error()
+		var sqlErr mssqldb.Error
+		if errors.As(err, &sqlErr) {
+			rec.ErrorNumber = sqlErr.Number
+		}
+	}
+
+	// The statement has already run, so a write failure must not fail it.
+	if err := m.statements.write(rec); err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("Failed to write the statement log: %v", err))
+	}
+}
+
+// databaseOf returns the name of the database a pool connects to.
+func (m *client) databaseOf(conn *sql.DB) string {
+	if conn == m.conn {
+		return m.database
+	}
+
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+	for name, p := range m.connByDatabase {
+		if p.db == conn {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,126 @@
+package mssql
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	mssqldb "github.com/microsoft/go-mssqldb"
+)
+
+func readStatementLog(t *testing.T, path string) []statementRecord {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open statement log: %v", err)
+	}
+	defer f.Close()
+
+	var records []statementRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec statementRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("line %q is not JSON: %v", scanner.Text(), err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func Test_statementLog_RecordsStatements(t *testing.T) {
+	c, mock := newRetryTestClient(t)
+	c.database = "master"
+	path := filepath.Join(t.TempDir(), "statements.jsonl")
+	log, err := openStatementLog(path)
+	if err != nil {
+		t.Fatalf("openStatementLog() error = %v", err)
+	}
+	c.statements = log
+
+	mock.ExpectExec("CREATE LOGIN").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DROP LOGIN").WillReturnError(mssqldb.Error{Number: 15151, Message: "Cannot drop the login 'app'"})
+
+	ctx := WithResource(context.Background(), "mssql_login", "sql:1433/app")
+	if _, err := c.exec(ctx, c.conn, nonIdempotent, "CREATE LOGIN", sql.Named("name", "app"), sql.Named("password", "s3cret")); err != nil {
+		t.Fatalf("exec() error = %v", err)
+	}
+	if _, err := c.exec(context.Background(), c.conn, idempotent, "DROP LOGIN", sql.Named("name", "app")); err == nil {
+		t.Fatalf("expected exec() to fail")
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read statement log: %v", err)
+	}
+	if strings.Contains(string(b), "s3cret") {
+		t.Fatalf("statement log contains the password:\n%s", b)
+	}
+
+	records := readStatementLog(t, path)
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+
+	create := records[0]
+	if create.Resource != "mssql_login" || create.ResourceID != "sql:1433/app" || create.Database != "master" {
+		t.Fatalf("record = %+v, want the resource and database", create)
+	}
+	if create.Parameters["name"] != "app" || create.Parameters["password"] != redacted {
+		t.Fatalf("parameters = %v, want name and a redacted password", create.Parameters)
+	}
+	if create.RowsAffected == nil || *create.RowsAffected != 0 || create.ErrorNumber != 0 {
+		t.Fatalf("record = %+v, want rows_affected 0 and no error", create)
+	}
+
+	drop := records[1]
+	if drop.Resource != "" || drop.ErrorNumber != 15151 || drop.RowsAffected != nil || drop.Error == "" {
+		t.Fatalf("record = %+v, want error 15151 without a resource", drop)
+	}
+}
+
+func Test_statementLog_DatabaseOfPool(t *testing.T) {
+	c, _ := newRetryTestClient(t)
+	c.database = "master"
+	path := filepath.Join(t.TempDir(), "statements.jsonl")
+	log, err := openStatementLog(path)
+	if err != nil {
+		t.Fatalf("openStatementLog() error = %v", err)
+	}
+	c.statements = log
+	t.Cleanup(func() { log.Close() })
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New() err = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	c.open = func(string) (*sql.DB, error) { return db, nil }
+
+	conn, release, err := c.getConnForDatabase("app")
+	if err != nil {
+		t.Fatalf("getConnForDatabase() error = %v", err)
+	}
+	defer release()
+
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(1))
+	var n int
+	if err := c.queryRow(context.Background(), conn, "SELECT 1", nil, &n); err != nil {
+		t.Fatalf("queryRow() error = %v", err)
+	}
+
+	records := readStatementLog(t, path)
+	if len(records) != 1 || records[0].Database != "app" || records[0].RowsAffected != nil {
+		t.Fatalf("records = %+v, want one query in database app", records)
+	}
+}
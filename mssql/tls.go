@@ -0,0 +1,112 @@
+package mssql
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// Encryption modes, as understood by go-mssqldb's `encrypt` parameter.
+const (
+	// EncryptDisable sends everything, including the login packet, in clear text.
+	EncryptDisable = "disable"
+	// EncryptOptional only encrypts the login packet.
+	EncryptOptional = "optional"
+	// EncryptMandatory encrypts the whole session after a TDS pre-login.
+	EncryptMandatory = "mandatory"
+	// EncryptStrict uses TDS 8.0, where TLS is negotiated before any TDS traffic.
+	EncryptStrict = "strict"
+)
+
+// EncryptModes lists the supported values for TLS.Encrypt.
+var EncryptModes = []string{
+	EncryptDisable,
+	EncryptOptional,
+	EncryptMandatory,
+	EncryptStrict,
+}
+
+// TLS configures transport encryption and server certificate validation.
+type TLS struct {
+	// Encrypt is one of EncryptModes. Defaults to EncryptMandatory.
+	Encrypt string
+	// TrustServerCertificate skips validation of the server certificate.
+	TrustServerCertificate bool
+	// Certificate is the path to a PEM file with the CA (or self-signed server
+	// certificate) used to validate the server, instead of the system roots.
+	Certificate string
+	// HostNameInCertificate overrides the host name expected in the server
+	// certificate. Defaults to the server host.
+	HostNameInCertificate string
+	// AllowInsecure permits settings that disable encryption or certificate
+	// validation.
+	AllowInsecure bool
+}
+
+func (t TLS) encrypt() string {
+	if t.Encrypt == "" {
+		return EncryptMandatory
+	}
+	return t.Encrypt
+}
+
+// Insecure reports whether the settings disable session encryption or
+// server certificate validation.
+func (t TLS) Insecure() bool {
+	switch t.encrypt() {
+	case EncryptDisable, EncryptOptional:
+		return true
+	}
+	return t.TrustServerCertificate
+}
+
+// Validate rejects unknown modes, contradictory settings and, unless
+// AllowInsecure is set, settings that weaken transport security.
+func (t TLS) Validate() error {
+	encrypt := t.encrypt()
+
+	valid := false
+	for _, mode := range EncryptModes {
+		if encrypt == mode {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("tls encrypt must be one of %s; got %q", strings.Join(EncryptModes, ", "), t.Encrypt)
+	}
+
+	if encrypt == EncryptDisable && (t.TrustServerCertificate || t.Certificate != "" || t.HostNameInCertificate != "") {
+		return errors.New("tls trust_server_certificate, certificate and host_name_in_certificate have no effect when encrypt is \"disable\"")
+	}
+	if t.TrustServerCertificate && (t.Certificate != "" || t.HostNameInCertificate != "") {
+		return errors.New("tls trust_server_certificate skips certificate validation and cannot be combined with certificate or host_name_in_certificate")
+	}
+	if encrypt == EncryptStrict && t.TrustServerCertificate {
+		return errors.New("tls encrypt \"strict\" always validates the server certificate and cannot be combined with trust_server_certificate")
+	}
+
+	if t.Insecure() && !t.AllowInsecure {
+		return fmt.Errorf("tls settings (encrypt = %q, trust_server_certificate = %t) disable encryption or certificate validation; set allow_insecure = true to use them anyway", encrypt, t.TrustServerCertificate)
+	}
+	return nil
+}
+
+// connParams sets the settings as go-mssqldb connection string parameters.
+func (t TLS) connParams(query url.Values) {
+	query.Set(msdsn.Encrypt, t.encrypt())
+	if t.encrypt() == EncryptDisable {
+		return
+	}
+	query.Set(msdsn.TrustServerCertificate, strconv.FormatBool(t.TrustServerCertificate))
+	if t.Certificate != "" {
+		query.Set(msdsn.Certificate, t.Certificate)
+	}
+	if t.HostNameInCertificate != "" {
+		query.Set(msdsn.HostNameInCertificate, t.HostNameInCertificate)
+	}
+}
@@ -0,0 +1,152 @@
+package mssql
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// writeSelfSignedCert writes a throwaway self-signed certificate for localhost
+// and returns its path.
+func writeSelfSignedCert(t *testing.T) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "server.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+	return path
+}
+
+func Test_TLS_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tls     TLS
+		wantErr bool
+	}{
+		{name: "default is mandatory", tls: TLS{}},
+		{name: "strict", tls: TLS{Encrypt: EncryptStrict}},
+		{name: "pinned certificate", tls: TLS{Certificate: "/etc/ssl/ca.pem", HostNameInCertificate: "sql.internal"}},
+		{name: "unknown mode", tls: TLS{Encrypt: "true"}, wantErr: true},
+		{name: "trust without allow_insecure", tls: TLS{TrustServerCertificate: true}, wantErr: true},
+		{name: "trust with allow_insecure", tls: TLS{TrustServerCertificate: true, AllowInsecure: true}},
+		{name: "disable without allow_insecure", tls: TLS{Encrypt: EncryptDisable}, wantErr: true},
+		{name: "optional with allow_insecure", tls: TLS{Encrypt: EncryptOptional, AllowInsecure: true}},
+		{name: "trust with certificate", tls: TLS{TrustServerCertificate: true, Certificate: "/ca.pem", AllowInsecure: true}, wantErr: true},
+		{name: "disable with certificate", tls: TLS{Encrypt: EncryptDisable, Certificate: "/ca.pem", AllowInsecure: true}, wantErr: true},
+		{name: "strict with trust", tls: TLS{Encrypt: EncryptStrict, TrustServerCertificate: true, AllowInsecure: true}, wantErr: true},
+		{name: "separator in path", tls: TLS{Certificate: "/ca.pem;encrypt=disable"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.tls.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() err=%v wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_buildConnString_TLS(t *testing.T) {
+	cert := writeSelfSignedCert(t)
+
+	cfg := Config{
+		Host:    "127.0.0.1",
+		Port:    1433,
+		SqlAuth: &SqlAuth{Username: "sa", Password: "pw"},
+		TLS:     &TLS{Certificate: cert, HostNameInCertificate: "localhost"},
+	}
+	params, err := msdsn.Parse(buildConnString(cfg, "master"))
+	if err != nil {
+		t.Fatalf("msdsn.Parse() error = %v", err)
+	}
+	if params.Encryption != msdsn.EncryptionRequired {
+		t.Fatalf("Encryption = %v, want required", params.Encryption)
+	}
+	if params.TLSConfig == nil || params.TLSConfig.InsecureSkipVerify {
+		t.Fatalf("expected certificate validation, got %+v", params.TLSConfig)
+	}
+	if params.TLSConfig.ServerName != "localhost" {
+		t.Fatalf("ServerName = %q, want localhost", params.TLSConfig.ServerName)
+	}
+	if params.TLSConfig.RootCAs == nil {
+		t.Fatalf("expected pinned RootCAs")
+	}
+
+	cfg.TLS = &TLS{Encrypt: EncryptStrict}
+	params, err = msdsn.Parse(buildConnString(cfg, "master"))
+	if err != nil {
+		t.Fatalf("msdsn.Parse() error = %v", err)
+	}
+	if params.Encryption != msdsn.EncryptionStrict || params.TLSConfig.InsecureSkipVerify {
+		t.Fatalf("unexpected strict settings: encryption=%v tls=%+v", params.Encryption, params.TLSConfig)
+	}
+
+	cfg.TLS = &TLS{Encrypt: EncryptDisable, AllowInsecure: true}
+	params, err = msdsn.Parse(buildConnString(cfg, "master"))
+	if err != nil {
+		t.Fatalf("msdsn.Parse() error = %v", err)
+	}
+	if params.Encryption != msdsn.EncryptionDisabled || params.TLSConfig != nil {
+		t.Fatalf("unexpected disabled settings: encryption=%v tls=%+v", params.Encryption, params.TLSConfig)
+	}
+}
+
+func Test_buildConnString_TLSWithSeparators(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "certs;encrypt=disable")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	cert := filepath.Join(dir, "server.pem")
+	if err := os.Rename(writeSelfSignedCert(t), cert); err != nil {
+		t.Fatalf("move certificate: %v", err)
+	}
+
+	cfg := Config{
+		Host: "127.0.0.1",
+		Port: 1433,
+		TLS:  &TLS{Certificate: cert, HostNameInCertificate: "localhost;trustservercertificate=true"},
+	}
+	if err := cfg.TLS.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	params, err := msdsn.Parse(buildConnString(cfg, "master"))
+	if err != nil {
+		t.Fatalf("msdsn.Parse() error = %v", err)
+	}
+	if params.Encryption != msdsn.EncryptionRequired || params.TLSConfig == nil || params.TLSConfig.InsecureSkipVerify {
+		t.Fatalf("unexpected settings: encryption=%v tls=%+v", params.Encryption, params.TLSConfig)
+	}
+	if params.TLSConfig.ServerName != "localhost;trustservercertificate=true" || params.TLSConfig.RootCAs == nil {
+		t.Fatalf("ServerName = %q, RootCAs = %v, want the configured values", params.TLSConfig.ServerName, params.TLSConfig.RootCAs)
+	}
+}